@@ -0,0 +1,730 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/voxels"
+)
+
+// A subcommand of the program
+type command struct {
+
+	// name the command is run by
+	name string
+
+	// one line description of the command
+	summary string
+
+	// description of the command for its help
+	description string
+
+	// defines the flags of the command, returning a function that validates them into a configuration once parsed
+	define func(flags *flag.FlagSet) func(config *executionArgs) error
+
+	// runs the command on its inputs
+	run func(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error
+
+	// whether inputs are handled one at a time rather than as one dataset, so they need not share a CRS
+	separateInputs bool
+
+	// whether to report when the command completes, for commands that write their output to a file
+	reportCompletion bool
+
+}
+
+// the commands of the program, in the order they are listed in the usage
+var commands = []*command{
+	{
+		name: "info",
+		summary: "describe the header, VLRs and CRS of LAS files",
+		description: "Prints the version, point format, point counts by return, scale and offset, bounds, estimated density, VLRs and CRS of each input, as text or JSON.",
+		define: defineInfo,
+		run: printInfo,
+		separateInputs: true,
+	},
+	{
+		name: "voxelize",
+		summary: "voxelize points into a CSV of voxels",
+		description: "Voxelizes points and writes the filled voxels as CSV, optionally normalized to the height above the ground.",
+		define: defineVoxelize,
+		run: processVoxels,
+		reportCompletion: true,
+	},
+	{
+		name: "metrics",
+		summary: "measure canopy and understory heights of each column",
+		description: "Voxelizes points and writes the canopy height, canopy base height, understory height and fuel strata gap of each column, as CSV or as a GeoTIFF if the output is .tif.",
+		define: defineMetrics,
+		run: processVoxels,
+		reportCompletion: true,
+	},
+	{
+		name: "profile",
+		summary: "count the voxels at each height",
+		description: "Voxelizes points and writes the number of filled voxels at each height as CSV.",
+		define: defineProfile,
+		run: processVoxels,
+		reportCompletion: true,
+	},
+	{
+		name: "run",
+		summary: "voxelize points through a pipeline from a configuration file",
+		description: "Voxelizes points and post processes them through the stages of a JSON pipeline configuration, which default their parameters to the flags.",
+		define: defineRun,
+		run: processVoxels,
+		reportCompletion: true,
+	},
+	{
+		name: "convert",
+		summary: "convert points to CSV",
+		description: "Writes the points that pass the filters as CSV, with their attributes if requested.",
+		define: defineConvert,
+		run: convertPoints,
+		reportCompletion: true,
+	},
+}
+
+// describes the inputs of every command
+const inputsHelp = "Inputs are LAS or LAZ files, globs or directories of them, processed as one dataset. LAZ files of point\n" +
+	"formats 6 - 10 use layered compression, which is not supported, and must be decompressed to LAS first."
+
+// prints the usage of the program
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: voxelize <command> [flags] <inputs...>")
+	fmt.Fprintln(os.Stderr, "\n" + inputsHelp + "\n\nCommands:")
+
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", command.name, command.summary)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nRun voxelize <command> -h for the flags of a command.")
+}
+
+// exits after an invalid command line, describing the problem
+func exitUsage(message string) {
+	fmt.Fprintln(os.Stderr, message)
+	os.Exit(2)
+}
+
+// parses the command and its arguments, exiting if they are invalid
+func parseCommand(args []string) (*command, executionArgs) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		usage()
+		os.Exit(2)
+	}
+
+	var selected *command
+
+	for _, command := range commands {
+		if command.name == args[0] {
+			selected = command
+		}
+	}
+
+	if selected == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(selected.name, flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: voxelize %s [flags] <inputs...>\n\n%s\n\n%s\n\nFlags:\n", selected.name, selected.description, inputsHelp)
+		flags.PrintDefaults()
+	}
+
+	validate := selected.define(flags)
+
+	flags.Parse(args[1:])
+
+	fileNames, err := expandInputs(flags.Args())
+
+	if err != nil {
+		exitUsage(err.Error())
+	}
+
+	if len(fileNames) == 0 {
+		exitUsage("must define an input file")
+	}
+
+	config := executionArgs{fileNames: fileNames}
+
+	if err = validate(&config); err != nil {
+		exitUsage(err.Error())
+	}
+
+	return selected, config
+}
+
+// flag that can be given several times, collecting its values in order
+type listFlag []string
+
+// Gets the values of the flag
+func(list *listFlag) String() string {
+	return strings.Join(*list, " ")
+}
+
+// Adds a value of the flag
+func(list *listFlag) Set(value string) error {
+	*list = append(*list, value)
+
+	return nil
+}
+
+// defines a flag that can be given several times
+func addListFlag(flags *flag.FlagSet, name string, usage string) *listFlag {
+	list := &listFlag{}
+
+	flags.Var(list, name, usage)
+
+	return list
+}
+
+// flags for reading points, shared by every command that processes them
+type readFlags struct {
+
+	// file to write the output to
+	destName *string
+
+	// how many concurrent reads and processes to use
+	concurrency *int
+
+	// how many chunks to split the inputs into
+	chunkNumber *int
+
+	// how long to run for before cancelling
+	timeout *time.Duration
+
+	// how to report progress
+	progressMode *string
+
+	// time between plain and json progress reports
+	progressInterval *time.Duration
+
+	// classifications to keep
+	classes *string
+
+	// classifications to drop
+	dropClasses *string
+
+	// returns to keep
+	returns *string
+
+	// point flags to drop
+	dropFlags *string
+
+	// largest absolute scan angle to keep
+	maxScanAngle *float64
+
+	// intensity range to keep
+	intensity *string
+
+	// GPS time range to keep
+	gpsTime *string
+
+	// elevation range to keep
+	zRange *string
+
+	// ranges of extra bytes attributes to keep, as name=min,max
+	extraRanges *listFlag
+
+	// box to clip to
+	clipBox *string
+
+	// polygons to clip to
+	clipFile *string
+
+}
+
+// defines the flags for reading points, writing to the specified output by default
+func addReadFlags(flags *flag.FlagSet, defaultOutput string) *readFlags {
+	return &readFlags{
+		destName: flags.String("output", defaultOutput, "the file to output results to"),
+		concurrency: flags.Int("concurrency", 32, "how many concurrent reads and processes to use"),
+		chunkNumber: flags.Int("chunks", 256, "how many chunks to split the file into"),
+		timeout: flags.Duration("timeout", 0, "how long to run for before cancelling (e.g. 90s, 10m), 0 for no limit"),
+		progressMode: flags.String("progress", "auto", "how to report progress: ansi, plain (log lines), json (newline delimited events), none, or auto (ansi when stdout is a terminal, otherwise plain)"),
+		progressInterval: flags.Duration("progress-interval", 5 * time.Second, "time between plain and json progress reports"),
+		classes: flags.String("classes", "", "comma separated classifications to keep, all others are dropped"),
+		dropClasses: flags.String("drop-classes", "", "comma separated classifications to drop (e.g. 7,18 for noise)"),
+		returns: flags.String("returns", "", "comma separated returns to keep: return numbers, first, last, single or multiple"),
+		dropFlags: flags.String("drop-flags", "", "comma separated point flags to drop: withheld, synthetic, keypoint or overlap"),
+		maxScanAngle: flags.Float64("max-scan-angle", -1, "largest absolute scan angle to keep in degrees, negative to keep all"),
+		intensity: flags.String("intensity", "", "min,max intensity to keep, either may be empty for no limit"),
+		gpsTime: flags.String("gps-time", "", "min,max GPS time to keep, either may be empty for no limit"),
+		zRange: flags.String("z", "", "min,max elevation to keep in the units of the input, either may be empty for no limit"),
+		clipBox: flags.String("clip-box", "", "minx,miny,maxx,maxy box in the CRS of the input to clip points to"),
+		clipFile: flags.String("clip", "", "GeoJSON (.json, .geojson) or WKT file of polygons in the CRS of the input to clip points to"),
+		extraRanges: addListFlag(flags, "extra", "name=min,max range of an extra bytes attribute to keep, either limit may be empty, can be given several times")}
+}
+
+// validates the flags for reading points into a configuration, clipping to the columns of its grid if it has one
+func(read *readFlags) apply(config *executionArgs, clipEach bool) error {
+	config.destName, config.concurrency, config.chunkNumber, config.timeout = *read.destName, *read.concurrency, *read.chunkNumber, *read.timeout
+
+	if config.concurrency < 1 {
+		return errors.New("-concurrency must be at least 1")
+	}
+
+	progress, err := parseProgress(*read.progressMode, *read.progressInterval)
+
+	if err != nil {
+		return err
+	}
+
+	config.progress = progress
+
+	pointFilters, err := parsePointFilters(*read.classes, *read.dropClasses, *read.returns, *read.dropFlags, *read.maxScanAngle,
+		*read.intensity, *read.gpsTime, *read.zRange, *read.extraRanges)
+
+	if err != nil {
+		return err
+	}
+
+	config.pointFilters = pointFilters
+
+	if *read.clipBox != "" {
+		box, err := parseBox(*read.clipBox)
+
+		if err != nil {
+			return err
+		}
+
+		*config = clipTo(*config, box, box.MinX, box.MinY, box.MaxX, box.MaxY)
+	}
+
+	if clipEach && *read.clipFile == "" {
+		return errors.New("-clip-each needs polygons from -clip")
+	}
+
+	if *read.clipFile == "" {
+		return nil
+	}
+
+	features, err := lasProcessing.ReadClipFeatures(*read.clipFile)
+
+	if err != nil {
+		return err
+	}
+
+	if clipEach {
+		config.clipFeatures = features
+		return nil
+	}
+
+	polygons := make([]lasProcessing.Polygon, 0)
+
+	for _, feature := range features {
+		polygons = append(polygons, feature.Polygons...)
+	}
+
+	filter := lasProcessing.NewPolygonFilter(polygons)
+
+	minX, minY, maxX, maxY := filter.Bounds()
+
+	*config = clipTo(*config, filter, minX, minY, maxX, maxY)
+
+	return nil
+}
+
+// flags for voxelizing points, shared by the voxelize, metrics and profile commands
+type voxelFlags struct {
+
+	// flags for reading the points
+	read *readFlags
+
+	// point density in a voxel to be filled
+	density *int
+
+	// side length of a voxel
+	voxelSize *float64
+
+	// how to index voxels
+	indexing *string
+
+	// whether to use heights above the ground instead of elevations
+	normalize *bool
+
+	// corner of voxel 0,0,0
+	anchor *string
+
+	// whether to filter ground instead of using the lowest voxel in each column
+	ground *bool
+
+	// whether to use ground classified points for the ground when present
+	groundClass *bool
+
+	// largest window for the ground filter
+	groundWindow *float64
+
+	// expected terrain slope for the ground filter
+	groundSlope *float64
+
+	// initial height above the ground filter surface to be non ground
+	groundThreshold *float64
+
+	// largest height above the ground filter surface to be non ground
+	groundMaxThreshold *float64
+
+	// where to output minimum heights
+	minimumImagePath *string
+
+	// whether to output positions in the CRS of the input
+	world *bool
+
+	// whether to write a JSON sidecar
+	sidecar *bool
+
+	// side length of tiles to process separately
+	tileSize *float64
+
+	// buffer processed around each tile
+	tileBuffer *float64
+
+	// whether to split the input by point source
+	splitSources *bool
+
+	// key to split the input by
+	splitBy *string
+
+	// most groups to post process at the same time
+	splitConcurrency *int
+
+	// file to write the manifest of the outputs of each group to
+	manifest *string
+
+	// attribute to name the output of each clip feature by
+	clipEach *string
+
+	// file to also write voxels to
+	voxelsOutput *string
+
+	// file to also write metrics to
+	metricsOutput *string
+
+	// file to also write the profile to
+	profileOutput *string
+
+	// whether to write the outputs at the same time
+	concurrentOutputs *bool
+
+}
+
+// defines the flags for voxelizing points, writing to the specified output by default
+func addVoxelFlags(flags *flag.FlagSet, defaultOutput string) *voxelFlags {
+	return &voxelFlags{
+		read: addReadFlags(flags, defaultOutput),
+		density: flags.Int("density", 20, "point density in a voxel to be filled"),
+		voxelSize: flags.Float64("voxel", 0.1, "side length for a voxel"),
+		indexing: flags.String("indexing", "floor", "how to index voxels, floor or truncate (legacy)"),
+		normalize: flags.Bool("normalize", false, "whether to use heights above the ground instead of elevations"),
+		anchor: flags.String("anchor", "0,0,0", "x,y,z corner of voxel 0,0,0 to align the grid to"),
+		ground: flags.Bool("ground", false, "whether to filter ground for normalization and measurements instead of using the lowest voxel in each column"),
+		groundClass: flags.Bool("ground-class", false, "whether to use points classified as ground (class 2) for the ground when present, implies -ground"),
+		groundWindow: flags.Float64("ground-window", 20, "largest window for the ground filter, in the units of the voxel size"),
+		groundSlope: flags.Float64("ground-slope", 0.3, "expected terrain slope for the ground filter (rise over run)"),
+		groundThreshold: flags.Float64("ground-threshold", 0.5, "initial height above the ground filter surface to be non ground"),
+		groundMaxThreshold: flags.Float64("ground-max-threshold", 3, "largest height above the ground filter surface to be non ground"),
+		minimumImagePath: flags.String("minimum-output", "", "file path to output the PNG minimums image"),
+		world: flags.Bool("world", false, "whether to output voxel centres and heights in the CRS of the input file instead of voxel indices"),
+		sidecar: flags.Bool("sidecar", false, "whether to write a JSON sidecar (output name + .json) describing the voxel grid, extent and CRS"),
+		tileSize: flags.Float64("tile", 0, "side length of tiles to process one at a time to limit memory use, in the units of the voxel size, 0 to process everything at once"),
+		tileBuffer: flags.Float64("tile-buffer", 10, "width of the buffer processed around each tile and cropped from its output, at least half of -ground-window when filtering ground"),
+		splitSources: flags.Bool("split-sources", false, "whether to write a separate output for each point source, the same as -split-by source"),
+		splitBy: flags.String("split-by", "", "key to write a separate output for each group of points by: source, class, return, user-data, channel or gps-time:<seconds>"),
+		splitConcurrency: flags.Int("split-concurrency", 4, "most groups to post process at the same time when splitting"),
+		manifest: flags.String("manifest", "", "file to write a JSON manifest of the outputs of each group to when splitting, defaults to the output name + -split.json"),
+		clipEach: flags.String("clip-each", "", "feature attribute to name outputs by, to write one output per -clip feature instead of clipping to all of them"),
+		voxelsOutput: flags.String("voxels-output", "", "file to also write voxels to, from the same voxels as -output"),
+		metricsOutput: flags.String("metrics-output", "", "file to also write metrics to (CSV, or GeoTIFF if .tif), from the same voxels as -output"),
+		profileOutput: flags.String("profile-output", "", "file to also write the profile to, from the same voxels as -output"),
+		concurrentOutputs: flags.Bool("concurrent-outputs", false, "whether to post process and write the outputs at the same time, using more memory")}
+}
+
+// validates the flags for voxelizing points into a configuration
+func(voxel *voxelFlags) apply(config *executionArgs) error {
+	grid, err := parseGrid(*voxel.voxelSize, *voxel.indexing, *voxel.anchor)
+
+	if err != nil {
+		return err
+	}
+
+	if *voxel.density < 1 {
+		return errors.New("-density must be at least 1")
+	}
+
+	splitBy := *voxel.splitBy
+
+	if *voxel.splitSources {
+		if splitBy != "" && splitBy != "source" {
+			return errors.New("-split-sources can not be used with -split-by")
+		}
+
+		splitBy = "source"
+	}
+
+	if splitBy != "" {
+		if config.splitKey, err = parseSplitKey(splitBy); err != nil {
+			return err
+		}
+	}
+
+	if *voxel.tileSize > 0 && splitBy != "" {
+		return errors.New("-tile can not be used with -split-by or -split-sources")
+	}
+
+	if *voxel.clipEach != "" && splitBy != "" {
+		return errors.New("-clip-each can not be used with -split-by or -split-sources")
+	}
+
+	if *voxel.splitConcurrency < 1 {
+		return errors.New("-split-concurrency must be at least 1")
+	}
+
+	if *voxel.manifest != "" && splitBy == "" {
+		return errors.New("-manifest can only be used with -split-by or -split-sources")
+	}
+
+	config.grid, config.voxelSize, config.density, config.normalize = grid, *voxel.voxelSize, *voxel.density, *voxel.normalize
+
+	config.ground, config.groundClass = *voxel.ground || *voxel.groundClass, *voxel.groundClass
+
+	config.groundFilter = voxels.GroundFilter{MaxWindow: *voxel.groundWindow, Slope: *voxel.groundSlope,
+		InitialThreshold: *voxel.groundThreshold, MaxThreshold: *voxel.groundMaxThreshold, UseGroundClass: *voxel.groundClass}
+
+	config.minimumImagePath = *voxel.minimumImagePath
+
+	config.georeferencing = voxels.Georeferencing{WorldCoordinates: *voxel.world, Sidecar: *voxel.sidecar}
+
+	config.tileSize, config.tileBuffer = *voxel.tileSize, *voxel.tileBuffer
+
+	config.splitConcurrency, config.manifestPath = *voxel.splitConcurrency, *voxel.manifest
+
+	config.clipEach = *voxel.clipEach
+
+	config.alsoOutputs, config.concurrentOutputs = nil, *voxel.concurrentOutputs
+
+	for _, output := range []outputFile{{kind: voxelOutput, fileName: *voxel.voxelsOutput},
+		{kind: measurementOutput, fileName: *voxel.metricsOutput}, {kind: gradientOutput, fileName: *voxel.profileOutput}} {
+		if output.fileName != "" {
+			config.alsoOutputs = append(config.alsoOutputs, output)
+		}
+	}
+
+	// clipping restricts the columns of the grid, so it is applied once the grid is known
+	return voxel.read.apply(config, config.clipEach != "")
+}
+
+// defines the flags of the voxelize command
+func defineVoxelize(flags *flag.FlagSet) func(config *executionArgs) error {
+	voxel := addVoxelFlags(flags, "voxels.csv")
+
+	attributes := flags.Bool("attributes", false, "whether to output intensity, colour, classification and return attributes for each voxel")
+
+	extraAttributes := flags.String("extra-attributes", "", "comma separated extra bytes attributes to output the mean of for each voxel, implies -attributes")
+
+	return func(config *executionArgs) error {
+		config.output, config.attributes = voxelOutput, *attributes
+
+		if *extraAttributes != "" {
+			for _, name := range strings.Split(*extraAttributes, ",") {
+				config.extraAttributes = append(config.extraAttributes, strings.TrimSpace(name))
+			}
+
+			config.attributes = true
+		}
+
+		return voxel.apply(config)
+	}
+}
+
+// defines the flags of the metrics command
+func defineMetrics(flags *flag.FlagSet) func(config *executionArgs) error {
+	voxel := addVoxelFlags(flags, "metrics.csv")
+
+	return func(config *executionArgs) error {
+		config.output = measurementOutput
+
+		return voxel.apply(config)
+	}
+}
+
+// defines the flags of the profile command
+func defineProfile(flags *flag.FlagSet) func(config *executionArgs) error {
+	voxel := addVoxelFlags(flags, "profile.csv")
+
+	return func(config *executionArgs) error {
+		config.output = gradientOutput
+
+		return voxel.apply(config)
+	}
+}
+
+// defines the flags of the run command
+func defineRun(flags *flag.FlagSet) func(config *executionArgs) error {
+	voxel := addVoxelFlags(flags, "")
+
+	pipelineFile := flags.String("pipeline", "", "JSON file describing the stages of the pipeline and their parameters")
+
+	return func(config *executionArgs) error {
+		if *pipelineFile == "" {
+			return errors.New("must define a pipeline with -pipeline")
+		}
+
+		if *voxel.tileSize > 0 {
+			return errors.New("-tile can not be used with -pipeline")
+		}
+
+		if *voxel.voxelsOutput != "" || *voxel.metricsOutput != "" || *voxel.profileOutput != "" {
+			return errors.New("-voxels-output, -metrics-output and -profile-output can not be used with -pipeline")
+		}
+
+		if err := voxel.apply(config); err != nil {
+			return err
+		}
+
+		pipeline, err := lasProcessing.ReadPipelineConfig(*pipelineFile)
+
+		if err != nil {
+			return err
+		}
+
+		config.pipeline = pipeline
+
+		if config.destName == "" {
+			config.destName = pipeline.Output
+		}
+
+		if config.destName == "" {
+			return errors.New("must define an output with -output or in the pipeline")
+		}
+
+		// built once to check the stages and find the attributes they need before reading any points
+		_, tracking, err := buildConfiguredPipeline(*config)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", *pipelineFile, err)
+		}
+
+		tracking.apply(config)
+
+		return nil
+	}
+}
+
+// defines the flags of the convert command
+func defineConvert(flags *flag.FlagSet) func(config *executionArgs) error {
+	read := addReadFlags(flags, "points.csv")
+
+	attributes := flags.Bool("attributes", false, "whether to output the intensity, returns, classification, point source and colour of each point")
+
+	return func(config *executionArgs) error {
+		config.attributes = *attributes
+
+		return read.apply(config, false)
+	}
+}
+
+// defines the flags of the info command
+func defineInfo(flags *flag.FlagSet) func(config *executionArgs) error {
+	format := flags.String("format", "text", "how to describe the inputs, text or json")
+
+	return func(config *executionArgs) error {
+		if *format != "text" && *format != "json" {
+			return fmt.Errorf("unknown format %q, must be text or json", *format)
+		}
+
+		config.infoFormat = *format
+
+		return nil
+	}
+}
+
+// prints a description of each input
+func printInfo(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error {
+	infos := make([]voxels.FileInfo, len(files))
+
+	for i, file := range files {
+		infos[i] = voxels.InspectFile(file)
+	}
+
+	if config.infoFormat == "json" {
+		contents, err := json.MarshalIndent(infos, "", "  ")
+
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.Write(append(contents, '\n'))
+
+		return err
+	}
+
+	for i := range infos {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		if err := infos[i].WriteText(os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writes the points that pass the filters as CSV
+func convertPoints(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error {
+	output, err := os.Create(config.destName)
+
+	if err != nil {
+		return err
+	}
+
+	defer output.Close()
+
+	file := files[0]
+
+	chunks := lasProcessing.ChunkFile(file, config.chunkNumber)
+
+	if len(files) > 1 {
+		file = lasProcessing.MosaicFile(files)
+		chunks = lasProcessing.ChunkFiles(files, config.chunkNumber)
+	}
+
+	buffered := bufio.NewWriter(output)
+
+	writer := lasProcessing.NewPointCSVWriter(buffered, file, config.pointFilters, config.attributes)
+
+	if err = writer.WriteHeader(); err != nil {
+		return err
+	}
+
+	status := lasProcessing.NewConcurrentStatus()
+
+	uiDone := make(chan bool)
+
+	go config.progress.Processing(status, uiDone)
+
+	// rows are written as chunks are processed, so chunks are processed in order
+	_, err = lasProcessing.SequentialProcess[int](ctx, file, chunks, writer, status)
+
+	<- uiDone
+
+	if err != nil {
+		return fmt.Errorf("converting %v: %w", inputsName(config), err)
+	}
+
+	return buffered.Flush()
+}
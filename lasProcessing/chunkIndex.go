@@ -0,0 +1,167 @@
+package lasProcessing
+
+import (
+	"context"
+	"math"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Number of points in each chunk of an index, the usual number of points in a LAZ chunk
+const indexChunkPoints = 50000
+
+// Chunk of a file with the XY bounds of its points
+type ChunkBounds struct {
+
+	// Chunk of the file, with its file set
+	Chunk *LASChunk
+
+	// Smallest X of a point in the chunk
+	MinX float64
+
+	// Smallest Y of a point in the chunk
+	MinY float64
+
+	// Largest X of a point in the chunk
+	MaxX float64
+
+	// Largest Y of a point in the chunk
+	MaxY float64
+
+}
+
+// Small chunks of some files with the bounds of their points, for reading only the chunks with points in an area.
+// Chunks start with the bounds of their file, and are narrowed to the bounds of their own points once measured.
+type ChunkIndex struct {
+
+	// Chunks of each file in order, by file in the order of the files
+	Chunks []*ChunkBounds
+
+	// Chunks by the chunk they bound
+	byChunk map[*LASChunk]*ChunkBounds
+
+}
+
+// Divides files into chunks of about the size of a LAZ chunk, or whole LAZ chunks for LAZ files,
+// bounded by their file
+func NewChunkIndex(files []*lidarioMod.LasFile) *ChunkIndex {
+	index := &ChunkIndex{Chunks: make([]*ChunkBounds, 0), byChunk: make(map[*LASChunk]*ChunkBounds)}
+
+	for _, file := range files {
+		header := file.Header
+
+		for _, chunk := range ChunkFile(file, (header.NumberPoints + indexChunkPoints - 1) / indexChunkPoints) {
+			// files without points have nothing to read
+			if chunk.End <= chunk.Start {
+				continue
+			}
+
+			chunk.File = file
+
+			bounds := &ChunkBounds{Chunk: chunk, MinX: header.MinX, MinY: header.MinY, MaxX: header.MaxX, MaxY: header.MaxY}
+
+			index.Chunks = append(index.Chunks, bounds)
+			index.byChunk[chunk] = bounds
+		}
+	}
+
+	return index
+}
+
+// Narrows the bounds of chunks of the index to measured bounds, from a ChunkBoundsProcessor
+func(index *ChunkIndex) Narrow(measured []ChunkBounds) {
+	for _, bounds := range measured {
+		if indexed, contains := index.byChunk[bounds.Chunk]; contains {
+			*indexed = bounds
+		}
+	}
+}
+
+// Gets the chunks that keep selects, joining neighbouring chunks of the same file so that there are
+// about the specified number of chunks
+func(index *ChunkIndex) Select(keep func(bounds *ChunkBounds) bool, numChunks int) []*LASChunk {
+	selected := make([]*LASChunk, 0)
+
+	totalPoints := 0
+
+	for _, bounds := range index.Chunks {
+		if keep(bounds) {
+			selected = append(selected, bounds.Chunk)
+			totalPoints += bounds.Chunk.End - bounds.Chunk.Start
+		}
+	}
+
+	targetSize := totalPoints / maxChunks(numChunks)
+
+	chunks := make([]*LASChunk, 0)
+
+	for _, chunk := range selected {
+		if len(chunks) > 0 {
+			last := chunks[len(chunks) - 1]
+
+			if last.File == chunk.File && last.End == chunk.Start && last.End - last.Start < targetSize {
+				last.End = chunk.End
+				continue
+			}
+		}
+
+		chunks = append(chunks, &LASChunk{Start: chunk.Start, End: chunk.End, File: chunk.File})
+	}
+
+	return chunks
+}
+
+// Gets a number of chunks of at least 1
+func maxChunks(numChunks int) int {
+	if numChunks < 1 {
+		return 1
+	}
+	return numChunks
+}
+
+// Finds the XY bounds of the points of chunks
+type ChunkBoundsProcessor struct {
+
+}
+
+// Finds the bounds of a chunk
+func(processor *ChunkBoundsProcessor) Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *LASChunk, status *ChunkProgress) (*[]ChunkBounds, error) {
+	status.Set(0.0)
+
+	rawBytes, err := chunk.ReadOnFile(inputFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := ChunkBounds{Chunk: chunk, MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+
+	for i := chunk.Start; i < chunk.End; i++ {
+		if err := CheckCancelled(ctx, i - chunk.Start); err != nil {
+			return nil, err
+		}
+
+		x, y, _ := ReadPointData(inputFile, chunk, rawBytes, i)
+
+		bounds.MinX, bounds.MinY = math.Min(bounds.MinX, x), math.Min(bounds.MinY, y)
+		bounds.MaxX, bounds.MaxY = math.Max(bounds.MaxX, x), math.Max(bounds.MaxY, y)
+
+		status.Set(float64(i - chunk.Start) / float64(chunk.End - chunk.Start))
+	}
+
+	status.Set(1.0)
+
+	return &[]ChunkBounds{bounds}, nil
+}
+
+// Gets bounds of no chunks
+func(processor *ChunkBoundsProcessor) EmptyOutput(inputFile *lidarioMod.LasFile) *[]ChunkBounds {
+	return &[]ChunkBounds{}
+}
+
+// Combines the bounds of chunks
+func(processor *ChunkBoundsProcessor) CombineOutput(base *[]ChunkBounds, incoming *[]ChunkBounds) *[]ChunkBounds {
+	combined := append(*base, *incoming...)
+
+	return &combined
+}
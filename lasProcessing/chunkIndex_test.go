@@ -0,0 +1,64 @@
+package lasProcessing
+
+import (
+	"testing"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Creates an uncompressed file of a number of points over an area, without any points to read
+func headerFile(numberPoints int, minX float64, maxX float64) *lidarioMod.LasFile {
+	return &lidarioMod.LasFile{Header: lidarioMod.LasHeader{NumberPoints: numberPoints, MinX: minX, MaxX: maxX, MaxY: 10}}
+}
+
+func TestChunkIndexSelect(t *testing.T) {
+	west, east := headerFile(4 * indexChunkPoints, 0, 100), headerFile(indexChunkPoints / 2, 100, 200)
+
+	index := NewChunkIndex([]*lidarioMod.LasFile{west, east, headerFile(0, 0, 200)})
+
+	if len(index.Chunks) != 5 {
+		t.Fatalf("indexed %d chunks, expected 5", len(index.Chunks))
+	}
+
+	// the chunks of the west file each cover a quarter of it
+	measured := make([]ChunkBounds, 0)
+
+	for i, bounds := range index.Chunks[:4] {
+		measured = append(measured, ChunkBounds{Chunk: bounds.Chunk, MinX: float64(i * 25), MaxX: float64(i * 25 + 25), MaxY: 10})
+	}
+
+	index.Narrow(measured)
+
+	// a line at x = 60 crosses the third chunk of the west file, and the unmeasured east file does not reach it
+	crossing := func(bounds *ChunkBounds) bool {
+		return bounds.MinX <= 60 && bounds.MaxX >= 60
+	}
+
+	chunks := index.Select(crossing, 8)
+
+	if len(chunks) != 1 || chunks[0].File != west || chunks[0].Start != 2 * indexChunkPoints || chunks[0].End != 3 * indexChunkPoints {
+		t.Fatalf("selected %+v, expected only the third chunk of the west file", chunks)
+	}
+
+	everything := func(bounds *ChunkBounds) bool {
+		return true
+	}
+
+	// neighbouring chunks of a file are joined, but never chunks of different files
+	chunks = index.Select(everything, 1)
+
+	if len(chunks) != 2 || chunks[0].File != west || chunks[0].End != 4 * indexChunkPoints || chunks[1].File != east {
+		t.Fatalf("selected %+v, expected one chunk of each file", chunks)
+	}
+
+	chunks = index.Select(everything, 8)
+
+	if len(chunks) != 5 {
+		t.Fatalf("selected %d chunks, expected every chunk to be kept", len(chunks))
+	}
+
+	// selecting never changes the chunks of the index
+	if index.Chunks[0].Chunk.End != indexChunkPoints {
+		t.Fatalf("first chunk of the index was changed to end at %d", index.Chunks[0].Chunk.End)
+	}
+}
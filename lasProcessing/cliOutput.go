@@ -9,6 +9,12 @@ import (
 
 // Displays a progress bar at the specified progress
 func progressBarRaw(width int, progress float64) string {
+	// nothing is known before processing starts
+	if math.IsNaN(progress) || progress < 0 {
+		progress = 0
+	} else if progress > 1 {
+		progress = 1
+	}
 	active := int(float64(width) * progress)
 	inactive := width - active
 	return "|" + strings.Repeat("=", active) + strings.Repeat("-", inactive) + "|"
@@ -24,38 +30,53 @@ func ProgressBarFloat(name string, progress float64) string {
 	return name + ":\t" + progressBarRaw(80, progress) + fmt.Sprintf("(%f", math.Round(progress * 10000) / 100) + "%)"
 }
 
-// Prints the CLI status to the console
-func cliStatusPanel(status *ConcurrentStatus) {
-	println(ProgressBarInt("CKS", *(status.CurrentChunk), *(status.TotalChunks)));
-	for i, progress := range status.ChunkProgress {
-		if *progress != 1.0 {
-			println("\033[2K\rP" + ProgressBarFloat("P" + fmt.Sprint(i), *progress))
+// Prints the CLI status to the console, outputting the number of lines printed
+func cliStatusPanel(event ProgressEvent) int {
+	println(ProgressBarInt("CKS", event.CurrentChunk, event.TotalChunks));
+	println("\033[2K\r" + progressSummary(event))
+	for i, progress := range event.ChunkProgress {
+		if progress != 1.0 {
+			println("\033[2K\rP" + ProgressBarFloat("P" + fmt.Sprint(i), progress))
 		} else {
 			println("\033[2K\rP" + fmt.Sprint(i) + ":\tMerging")
 		}
 	}
+	return len(event.ChunkProgress) + 2
 }
 
-// Displays the status of a ConcurrentStatus in the console
-func CLIStatus(status *ConcurrentStatus, quit *bool, uiDone chan<- bool) {
-	cliStatusPanel(status)
-	for !*quit {
-		time.Sleep(200 * time.Millisecond)
-		print(strings.Repeat("\033[A", *(status.Concurrency) + 1) + "\r")
-		cliStatusPanel(status)
+// Summarizes the points processed, throughput, merges and ETA of concurrent processing
+func progressSummary(event ProgressEvent) string {
+	summary := fmt.Sprintf("%d / %d points, %.0f points/s, %d merges", event.Points, event.TotalPoints, event.PointsPerSecond, event.Merges)
+
+	if event.ETA > 0 {
+		summary += ", ETA " + event.ETA.Round(time.Second).String()
 	}
-	print(strings.Repeat("\033[A\033[2K\r", *(status.Concurrency) + 1))
+
+	return summary
+}
+
+// Displays the status of a ConcurrentStatus in the console until processing finishes
+func CLIStatus(status *ConcurrentStatus, uiDone chan<- bool) {
+	lines := 0
+	for event := range status.Subscribe(200 * time.Millisecond) {
+		if event.Done {
+			continue
+		}
+		print(strings.Repeat("\033[A", lines) + "\r")
+		lines = cliStatusPanel(event)
+	}
+	print(strings.Repeat("\033[A\033[2K\r", lines))
 	println("Finished processing")
 	uiDone <- true
 }
 
 // Writes pipeline status to the screen
-func pipelineStatus(status *PipelineStatus, prevStep string) bool {
-	if status.Step != prevStep && prevStep != "" {
+func pipelineStatus(event PipelineEvent, prevStep string) bool {
+	if event.Step != prevStep && prevStep != "" {
 		println("Finished " + strings.ToLower(prevStep))
 	}
 	
-	if status.Step == "" {
+	if event.Step == "" {
 		if prevStep != "" {
 			// finished
 			println("Finished post processing")	
@@ -63,22 +84,71 @@ func pipelineStatus(status *PipelineStatus, prevStep string) bool {
 		return false
 	}
 
-	println(ProgressBarFloat(status.Step, status.Progress))
+	bar := ProgressBarFloat(event.Step, event.Progress)
+
+	if event.ETA > 0 {
+		bar += " ETA " + event.ETA.Round(time.Second).String()
+	}
+
+	println(bar)
 	return true
 }
 
-// Displays the status of a PostProcessingStatus in the console
-func PostProcessingStatus(status *PipelineStatus, quit *bool, uiDone chan<- bool) {
-	prevStep := status.Step
-	prevWrite := pipelineStatus(status, prevStep)
-	for !*quit {
-		time.Sleep(200 * time.Millisecond)
+// Displays the status of a PipelineStatus in the console until the pipeline finishes
+func PostProcessingStatus(status *PipelineStatus, uiDone chan<- bool) {
+	prevStep := ""
+	prevWrite := false
+	for event := range status.Subscribe(200 * time.Millisecond) {
 		if prevWrite {
 			print("\033[A\033[2K\r")
 		}
-		prevWrite = pipelineStatus(status, prevStep)
-		prevStep = status.Step
+		prevWrite = pipelineStatus(event, prevStep)
+		prevStep = event.Step
+	}
+	uiDone <- true
+}
+
+// Reports the progress of processing and post processing
+type ProgressReporter interface {
+
+	// Reports the progress of concurrent processing until it finishes, then signals uiDone
+	Processing(status *ConcurrentStatus, uiDone chan<- bool)
+
+	// Reports the progress of a post processing pipeline until it finishes, then signals uiDone
+	PostProcessing(status *PipelineStatus, uiDone chan<- bool)
+
+}
+
+// Reports progress with bars redrawn in place, for interactive terminals
+type ANSIReporter struct {
+
+}
+
+// Reports the progress of concurrent processing with bars
+func(reporter *ANSIReporter) Processing(status *ConcurrentStatus, uiDone chan<- bool) {
+	CLIStatus(status, uiDone)
+}
+
+// Reports the progress of a post processing pipeline with bars
+func(reporter *ANSIReporter) PostProcessing(status *PipelineStatus, uiDone chan<- bool) {
+	PostProcessingStatus(status, uiDone)
+}
+
+// Reports no progress
+type SilentReporter struct {
+
+}
+
+// Waits for concurrent processing to finish
+func(reporter *SilentReporter) Processing(status *ConcurrentStatus, uiDone chan<- bool) {
+	for range status.Subscribe(time.Second) {
+	}
+	uiDone <- true
+}
+
+// Waits for a post processing pipeline to finish
+func(reporter *SilentReporter) PostProcessing(status *PipelineStatus, uiDone chan<- bool) {
+	for range status.Subscribe(time.Second) {
 	}
-	pipelineStatus(status, prevStep)
 	uiDone <- true
-}
\ No newline at end of file
+}
@@ -0,0 +1,157 @@
+package lasProcessing
+
+import (
+	"math"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Vertex of a polygon ring
+type Vertex struct {
+
+	// X position
+	X float64
+
+	// Y position
+	Y float64
+
+}
+
+// Ring of vertices, which may or may not repeat the first vertex at the end
+type Ring []Vertex
+
+// Whether a position is inside the ring, by the even-odd rule. A position exactly on an edge is inside when
+// the ring is to its right, or above it on a horizontal edge, so rings sharing an edge never both contain it.
+func(ring Ring) Contains(x float64, y float64) bool {
+	inside := false
+
+	for i, j := 0, len(ring) - 1; i < len(ring); j, i = i, i + 1 {
+		a, b := ring[i], ring[j]
+
+		if (a.Y > y) != (b.Y > y) && x < (b.X - a.X) * (y - a.Y) / (b.Y - a.Y) + a.X {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// Polygon with an outer ring and any number of holes
+type Polygon struct {
+
+	// Outer boundary
+	Outer Ring
+
+	// Holes in the polygon
+	Holes []Ring
+
+}
+
+// Whether a position is inside the outer ring and outside every hole
+func(polygon *Polygon) Contains(x float64, y float64) bool {
+	if !polygon.Outer.Contains(x, y) {
+		return false
+	}
+
+	for _, hole := range polygon.Holes {
+		if hole.Contains(x, y) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Gets the bounds of the polygon as min x, min y, max x, max y
+func(polygon *Polygon) Bounds() (float64, float64, float64, float64) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+
+	for _, vertex := range polygon.Outer {
+		minX, minY = math.Min(minX, vertex.X), math.Min(minY, vertex.Y)
+		maxX, maxY = math.Max(maxX, vertex.X), math.Max(maxY, vertex.Y)
+	}
+
+	return minX, minY, maxX, maxY
+}
+
+// Keeps points inside an axis-aligned box over the XY plane
+type BoxFilter struct {
+
+	// Lowest X position to keep
+	MinX float64
+
+	// Lowest Y position to keep
+	MinY float64
+
+	// Highest X position to keep
+	MaxX float64
+
+	// Highest Y position to keep
+	MaxY float64
+
+}
+
+// Whether a position is inside the box
+func(filter *BoxFilter) Contains(x float64, y float64) bool {
+	return x >= filter.MinX && x <= filter.MaxX && y >= filter.MinY && y <= filter.MaxY
+}
+
+// Whether a point is inside the box
+func(filter *BoxFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	x, y, _ := ReadPointData(inputFile, chunk, rawBytes, point)
+
+	return filter.Contains(x, y)
+}
+
+// Keeps points inside any of a set of polygons
+type PolygonFilter struct {
+
+	// Polygons to keep the points inside of
+	polygons []Polygon
+
+	// Bounds of each polygon, to skip testing polygons far from a point
+	bounds []BoxFilter
+
+}
+
+// Creates a filter keeping points inside any of the specified polygons
+func NewPolygonFilter(polygons []Polygon) *PolygonFilter {
+	filter := &PolygonFilter{polygons: polygons, bounds: make([]BoxFilter, len(polygons))}
+
+	for i := range polygons {
+		bounds := &filter.bounds[i]
+		bounds.MinX, bounds.MinY, bounds.MaxX, bounds.MaxY = polygons[i].Bounds()
+	}
+
+	return filter
+}
+
+// Gets the bounds of all the polygons as min x, min y, max x, max y
+func(filter *PolygonFilter) Bounds() (float64, float64, float64, float64) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+
+	for _, bounds := range filter.bounds {
+		minX, minY = math.Min(minX, bounds.MinX), math.Min(minY, bounds.MinY)
+		maxX, maxY = math.Max(maxX, bounds.MaxX), math.Max(maxY, bounds.MaxY)
+	}
+
+	return minX, minY, maxX, maxY
+}
+
+// Whether a position is inside any of the polygons
+func(filter *PolygonFilter) Contains(x float64, y float64) bool {
+	for i := range filter.polygons {
+		if filter.bounds[i].Contains(x, y) && filter.polygons[i].Contains(x, y) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether a point is inside any of the polygons
+func(filter *PolygonFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	x, y, _ := ReadPointData(inputFile, chunk, rawBytes, point)
+
+	return filter.Contains(x, y)
+}
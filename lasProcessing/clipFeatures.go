@@ -0,0 +1,337 @@
+package lasProcessing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Polygon feature to clip points to, read from GeoJSON or WKT
+type ClipFeature struct {
+
+	// Attributes of the feature, nil for WKT
+	Properties map[string]any
+
+	// Polygons making up the feature
+	Polygons []Polygon
+
+}
+
+// Gets the value of an attribute of the feature as text, empty if the feature does not have it
+func(feature *ClipFeature) Attribute(name string) string {
+	value, contains := feature.Properties[name]
+
+	if !contains || value == nil {
+		return ""
+	}
+
+	// whole numbers are written without a decimal point
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// Reads polygon features from a file, as GeoJSON if the file is .json or .geojson and otherwise as WKT
+// with one geometry per line. Coordinates have to be in the CRS of the points being clipped.
+func ReadClipFeatures(fileName string) ([]ClipFeature, error) {
+	contents, err := os.ReadFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	extension := strings.ToLower(filepath.Ext(fileName))
+
+	var features []ClipFeature
+
+	if extension == ".json" || extension == ".geojson" {
+		features, err = parseGeoJSON(contents)
+	} else {
+		features, err = parseWKTLines(string(contents))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", fileName, err)
+	}
+
+	if len(features) == 0 {
+		return nil, fmt.Errorf("reading %s: no polygons", fileName)
+	}
+
+	return features, nil
+}
+
+// GeoJSON object, any of a feature collection, feature or geometry
+type geoJSONObject struct {
+
+	// Type of the object
+	Type string `json:"type"`
+
+	// Features of a feature collection
+	Features []geoJSONObject `json:"features"`
+
+	// Attributes of a feature
+	Properties map[string]any `json:"properties"`
+
+	// Geometry of a feature
+	Geometry *geoJSONObject `json:"geometry"`
+
+	// Coordinates of a geometry
+	Coordinates json.RawMessage `json:"coordinates"`
+
+}
+
+// Parses the polygon features of GeoJSON
+func parseGeoJSON(contents []byte) ([]ClipFeature, error) {
+	var object geoJSONObject
+
+	if err := json.Unmarshal(contents, &object); err != nil {
+		return nil, err
+	}
+
+	switch object.Type {
+	case "FeatureCollection":
+		features := make([]ClipFeature, 0, len(object.Features))
+
+		for i, feature := range object.Features {
+			if feature.Geometry == nil {
+				return nil, fmt.Errorf("feature %d has no geometry", i)
+			}
+
+			polygons, err := parseGeoJSONGeometry(feature.Geometry)
+
+			if err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+
+			features = append(features, ClipFeature{Properties: feature.Properties, Polygons: polygons})
+		}
+
+		return features, nil
+	case "Feature":
+		if object.Geometry == nil {
+			return nil, fmt.Errorf("feature has no geometry")
+		}
+
+		polygons, err := parseGeoJSONGeometry(object.Geometry)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []ClipFeature{{Properties: object.Properties, Polygons: polygons}}, nil
+	default:
+		polygons, err := parseGeoJSONGeometry(&object)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []ClipFeature{{Polygons: polygons}}, nil
+	}
+}
+
+// Parses a GeoJSON Polygon or MultiPolygon
+func parseGeoJSONGeometry(geometry *geoJSONObject) ([]Polygon, error) {
+	switch geometry.Type {
+	case "Polygon":
+		var rings [][][]float64
+
+		if err := json.Unmarshal(geometry.Coordinates, &rings); err != nil {
+			return nil, err
+		}
+
+		polygon, err := createPolygon(rings)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []Polygon{polygon}, nil
+	case "MultiPolygon":
+		var polygonRings [][][][]float64
+
+		if err := json.Unmarshal(geometry.Coordinates, &polygonRings); err != nil {
+			return nil, err
+		}
+
+		polygons := make([]Polygon, 0, len(polygonRings))
+
+		for _, rings := range polygonRings {
+			polygon, err := createPolygon(rings)
+
+			if err != nil {
+				return nil, err
+			}
+
+			polygons = append(polygons, polygon)
+		}
+
+		return polygons, nil
+	default:
+		return nil, fmt.Errorf("geometry %q is not a Polygon or MultiPolygon", geometry.Type)
+	}
+}
+
+// Creates a polygon from rings of positions, the first being the outer ring
+func createPolygon(rings [][][]float64) (Polygon, error) {
+	polygon := Polygon{}
+
+	if len(rings) == 0 {
+		return polygon, fmt.Errorf("polygon has no rings")
+	}
+
+	for i, positions := range rings {
+		if len(positions) < 3 {
+			return polygon, fmt.Errorf("polygon ring has %d positions, at least 3 are needed", len(positions))
+		}
+
+		ring := make(Ring, 0, len(positions))
+
+		for _, position := range positions {
+			if len(position) < 2 {
+				return polygon, fmt.Errorf("position %v has no x and y", position)
+			}
+
+			ring = append(ring, Vertex{X: position[0], Y: position[1]})
+		}
+
+		if i == 0 {
+			polygon.Outer = ring
+		} else {
+			polygon.Holes = append(polygon.Holes, ring)
+		}
+	}
+
+	return polygon, nil
+}
+
+// Parses WKT polygons, one geometry per line, each as its own feature
+func parseWKTLines(contents string) ([]ClipFeature, error) {
+	features := make([]ClipFeature, 0)
+
+	for i, line := range strings.Split(contents, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		polygons, err := parseWKT(line)
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i + 1, err)
+		}
+
+		features = append(features, ClipFeature{Polygons: polygons})
+	}
+
+	return features, nil
+}
+
+// Parses a WKT POLYGON or MULTIPOLYGON, optionally prefixed with an EWKT SRID
+func parseWKT(text string) ([]Polygon, error) {
+	text = strings.TrimSpace(text)
+
+	if separator := strings.Index(text, ";"); separator >= 0 && strings.HasPrefix(strings.ToUpper(text), "SRID=") {
+		text = text[separator + 1:]
+	}
+
+	open := strings.Index(text, "(")
+
+	if open < 0 {
+		return nil, fmt.Errorf("%q is not a WKT polygon", text)
+	}
+
+	// the kind may be followed by Z, M or ZM
+	kind := strings.Fields(strings.ToUpper(text[:open]))
+
+	ringDepth := 0
+
+	if len(kind) > 0 && kind[0] == "POLYGON" {
+		ringDepth = 2
+	} else if len(kind) > 0 && kind[0] == "MULTIPOLYGON" {
+		ringDepth = 3
+	} else {
+		return nil, fmt.Errorf("%q is not a POLYGON or MULTIPOLYGON", strings.Join(kind, " "))
+	}
+
+	polygons := make([]Polygon, 0)
+
+	var rings [][][]float64
+
+	depth, start := 0, 0
+
+	for i := open; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth += 1
+
+			if depth == ringDepth {
+				start = i + 1
+			}
+		case ')':
+			if depth == ringDepth {
+				ring, err := parseWKTRing(text[start:i])
+
+				if err != nil {
+					return nil, err
+				}
+
+				rings = append(rings, ring)
+			}
+
+			// the rings of a polygon are complete
+			if depth == ringDepth - 1 {
+				polygon, err := createPolygon(rings)
+
+				if err != nil {
+					return nil, err
+				}
+
+				polygons = append(polygons, polygon)
+				rings = nil
+			}
+
+			depth -= 1
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+
+	return polygons, nil
+}
+
+// Parses the comma separated positions of a WKT ring
+func parseWKTRing(text string) ([][]float64, error) {
+	positions := make([][]float64, 0)
+
+	for _, position := range strings.Split(text, ",") {
+		fields := strings.Fields(position)
+
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("position %q has no x and y", position)
+		}
+
+		x, err := strconv.ParseFloat(fields[0], 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := strconv.ParseFloat(fields[1], 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		positions = append(positions, []float64{x, y})
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,162 @@
+package lasProcessing
+
+import (
+	"testing"
+)
+
+// A position and whether it is expected inside
+type containsCase struct {
+
+	// X position
+	x float64
+
+	// Y position
+	y float64
+
+	// Whether the position is inside
+	inside bool
+
+}
+
+// Parses WKT polygons, failing the test if they are invalid
+func mustParseWKT(t *testing.T, text string) []Polygon {
+	polygons, err := parseWKT(text)
+
+	if err != nil {
+		t.Fatalf("%s: %v", text, err)
+	}
+
+	return polygons
+}
+
+func TestPolygonWithHoles(t *testing.T) {
+	// a 10 x 10 square with two square holes, written without repeating the first vertex of the second hole
+	polygons := mustParseWKT(t, "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 4 2, 4 4, 2 4, 2 2), (6 6, 8 6, 8 8, 6 8))")
+
+	if len(polygons) != 1 || len(polygons[0].Holes) != 2 {
+		t.Fatalf("parsed %d polygons, expected 1 with 2 holes", len(polygons))
+	}
+
+	for _, c := range []containsCase{
+		{1, 1, true},
+		{5, 5, true},
+		{9, 5, true},
+		// inside each hole
+		{3, 3, false},
+		{7, 7, false},
+		// outside the outer ring
+		{-1, 5, false},
+		{11, 5, false},
+		{5, -0.001, false},
+		// between the holes, in line with both
+		{5, 3, true},
+		{3, 7, true},
+	} {
+		if inside := polygons[0].Contains(c.x, c.y); inside != c.inside {
+			t.Fatalf("%v, %v is inside %v, expected %v", c.x, c.y, inside, c.inside)
+		}
+	}
+}
+
+// Positions exactly on an edge are inside when the polygon is to their right, or above them on a horizontal edge
+func TestPolygonEdges(t *testing.T) {
+	square := mustParseWKT(t, "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (4 4, 6 4, 6 6, 4 6, 4 4))")[0]
+
+	triangle := mustParseWKT(t, "POLYGON ((0 0, 10 0, 0 10))")[0]
+
+	for _, c := range []struct{ polygon Polygon; containsCase }{
+		// left and bottom edges of the outer ring, with the corner between them
+		{square, containsCase{0, 5, true}},
+		{square, containsCase{5, 0, true}},
+		{square, containsCase{0, 0, true}},
+		// right and top edges, with the other corners
+		{square, containsCase{10, 5, false}},
+		{square, containsCase{5, 10, false}},
+		{square, containsCase{10, 0, false}},
+		{square, containsCase{0, 10, false}},
+		{square, containsCase{10, 10, false}},
+		// the edges of a hole, which has the polygon to the right of its right edge and above its top edge
+		{square, containsCase{4, 5, false}},
+		{square, containsCase{5, 4, false}},
+		{square, containsCase{6, 5, true}},
+		{square, containsCase{5, 6, true}},
+		// a sloping edge, with the triangle to its left
+		{triangle, containsCase{5, 5, false}},
+		{triangle, containsCase{4.99, 5, true}},
+		{triangle, containsCase{0, 5, true}},
+	} {
+		if inside := c.polygon.Contains(c.x, c.y); inside != c.inside {
+			t.Fatalf("%v, %v is inside %v, expected %v", c.x, c.y, inside, c.inside)
+		}
+	}
+}
+
+func TestPolygonFilterMultipolygon(t *testing.T) {
+	// two squares sharing the edge x = 10, and a separate square with a hole
+	features, err := parseWKTLines("MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0)), ((10 0, 20 0, 20 10, 10 10, 10 0)), " +
+		"((30 0, 40 0, 40 10, 30 10, 30 0), (32 2, 38 2, 38 8, 32 8, 32 2)))")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(features) != 1 || len(features[0].Polygons) != 3 {
+		t.Fatalf("parsed %d features, expected 1 of 3 polygons", len(features))
+	}
+
+	filter := NewPolygonFilter(features[0].Polygons)
+
+	if minX, minY, maxX, maxY := filter.Bounds(); minX != 0 || minY != 0 || maxX != 40 || maxY != 10 {
+		t.Fatalf("bounds are %v, %v to %v, %v, expected 0, 0 to 40, 10", minX, minY, maxX, maxY)
+	}
+
+	for _, c := range []containsCase{
+		{5, 5, true},
+		{15, 5, true},
+		// the shared edge is kept once, by the square to its right
+		{10, 5, true},
+		// the gap between the squares and the far square, inside the bounds of the filter
+		{25, 5, false},
+		{31, 5, true},
+		{35, 5, false},
+		{40, 5, false},
+		{-0.001, 5, false},
+	} {
+		if inside := filter.Contains(c.x, c.y); inside != c.inside {
+			t.Fatalf("%v, %v is inside %v, expected %v", c.x, c.y, inside, c.inside)
+		}
+	}
+
+	// each position on the shared edge is in exactly one of the squares
+	for y := 0.0; y < 10; y += 0.5 {
+		if left, right := features[0].Polygons[0].Contains(10, y), features[0].Polygons[1].Contains(10, y); left == right {
+			t.Fatalf("10, %v is in the left square %v and the right square %v, expected exactly one", y, left, right)
+		}
+	}
+}
+
+func TestGeoJSONMultiPolygonWithHole(t *testing.T) {
+	features, err := parseGeoJSON([]byte(`{"type": "Feature", "properties": {"plot": 7}, "geometry": {"type": "MultiPolygon",
+		"coordinates": [[[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]], [[2, 2], [8, 2], [8, 8], [2, 8], [2, 2]]],
+		[[[20, 0], [30, 0], [30, 10], [20, 10], [20, 0]]]]}}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(features) != 1 || len(features[0].Polygons) != 2 || len(features[0].Polygons[0].Holes) != 1 {
+		t.Fatalf("parsed %+v, expected 1 feature of 2 polygons, the first with a hole", features)
+	}
+
+	if plot := features[0].Attribute("plot"); plot != "7" {
+		t.Fatalf("plot attribute is %q, expected 7", plot)
+	}
+
+	filter := NewPolygonFilter(features[0].Polygons)
+
+	for _, c := range []containsCase{{1, 1, true}, {5, 5, false}, {25, 5, true}, {15, 5, false}} {
+		if inside := filter.Contains(c.x, c.y); inside != c.inside {
+			t.Fatalf("%v, %v is inside %v, expected %v", c.x, c.y, inside, c.inside)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package lasProcessing
 
 import (
+	"context"
 	"encoding/binary"
-	"io"
+	"fmt"
+	"math"
+	"sync"
 
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
 )
@@ -15,35 +18,88 @@ type LASChunk struct {
 
 	// End of the LAS chunk
 	End int
+
+	// File the chunk is in, nil for the file being processed
+	File *lidarioMod.LasFile
+}
+
+// Gets the bytes for a LAS chunk, decompressing them if the file is a LAZ file
+func(chunk *LASChunk) ReadOnFile(file *lidarioMod.LasFile) ([]byte, error) {
+	return file.ReadPointRecords(chunk.Start, chunk.End)
+}
+
+// Error from processing a chunk
+type ChunkError struct {
+
+	// Start of the chunk that failed
+	Start int
+
+	// End of the chunk that failed
+	End int
+
+	// Name of the file the chunk is in, empty for the file being processed
+	File string
+
+	// Error from processing the chunk
+	Err error
+
 }
 
-// Gets the bytes for a LAS chunk
-func(chunk *LASChunk) ReadOnFile(file *lidarioMod.LasFile) []byte {
-	recordLength := file.Header.PointRecordLength
+// Gets the error message
+func(err *ChunkError) Error() string {
+	message := "chunk [" + fmt.Sprint(err.Start) + ", " + fmt.Sprint(err.End) + ")"
+
+	if err.File != "" {
+		message += " of " + err.File
+	}
 
-	offset := int64(file.Header.OffsetToPoints)
+	return message + ": " + err.Err.Error()
+}
 
-	pointOffset := int64(recordLength) * int64(chunk.Start)
+// Gets the error from processing the chunk
+func(err *ChunkError) Unwrap() error {
+	return err.Err
+}
 
-	delta := int64(chunk.End) - int64(chunk.Start)
+// Divides a LAZ file into chunks made up of whole compressed chunks, so that no compressed
+// chunk has to be decoded more than once
+func chunkCompressedFile(file *lidarioMod.LasFile, numChunks int) []*LASChunk {
+	chunks := make([]*LASChunk, 0)
 
-	chunkLength := delta * int64(file.Header.PointRecordLength)
+	lazChunks := file.LazChunks()
 
-	rawBytes := make([]byte, chunkLength)
+	targetSize := file.Header.NumberPoints / numChunks
 
-	_, err := file.RawFile.ReadAt(rawBytes, offset + pointOffset)
+	start := 0
 
-	if err != nil && err != io.EOF {
-		panic(err)
+	for i, lazChunk := range lazChunks {
+		end := lazChunk.PointStart + lazChunk.PointCount
+
+		if end - start >= targetSize || i == len(lazChunks) - 1 {
+			chunks = append(chunks, &LASChunk{Start: start, End: end})
+			start = end
+		}
 	}
 
-	return rawBytes
+	return chunks
 }
 
 // Divides a LAS file into chunks for processing
 func ChunkFile(file *lidarioMod.LasFile, numChunks int) []*LASChunk {
 	chunks := make([]*LASChunk, 0)
 
+	if numChunks > file.Header.NumberPoints {
+		numChunks = file.Header.NumberPoints
+	}
+
+	if numChunks < 1 {
+		numChunks = 1
+	}
+
+	if file.IsCompressed() {
+		return chunkCompressedFile(file, numChunks)
+	}
+
 	chunkSize := file.Header.NumberPoints / numChunks
 
 	for i := 1; i < numChunks; i++ { // iterate for one less than numChunks
@@ -57,31 +113,90 @@ func ChunkFile(file *lidarioMod.LasFile, numChunks int) []*LASChunk {
 	return chunks
 }
 
+// Divides several LAS files into chunks for processing as one dataset, splitting the number of chunks
+// between the files by their number of points
+func ChunkFiles(files []*lidarioMod.LasFile, numChunks int) []*LASChunk {
+	totalPoints := 0
+
+	for _, file := range files {
+		totalPoints += file.Header.NumberPoints
+	}
+
+	chunks := make([]*LASChunk, 0)
+
+	for _, file := range files {
+		fileChunks := numChunks
+
+		if totalPoints > 0 {
+			fileChunks = numChunks * file.Header.NumberPoints / totalPoints
+		}
+
+		for _, chunk := range ChunkFile(file, fileChunks) {
+			// files without points have nothing to process
+			if chunk.End > chunk.Start {
+				chunk.File = file
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+
+	return chunks
+}
+
+// Gets a file whose header covers the bounds and points of all the specified files, for creating
+// outputs that hold all of them. Only the header of the file is set, its points are read through the
+// chunks from ChunkFiles.
+func MosaicFile(files []*lidarioMod.LasFile) *lidarioMod.LasFile {
+	header := files[0].Header
+
+	for _, file := range files[1:] {
+		header.NumberPoints += file.Header.NumberPoints
+
+		header.MinX = math.Min(header.MinX, file.Header.MinX)
+		header.MinY = math.Min(header.MinY, file.Header.MinY)
+		header.MinZ = math.Min(header.MinZ, file.Header.MinZ)
+
+		header.MaxX = math.Max(header.MaxX, file.Header.MaxX)
+		header.MaxY = math.Max(header.MaxY, file.Header.MaxY)
+		header.MaxZ = math.Max(header.MaxZ, file.Header.MaxZ)
+	}
+
+	return &lidarioMod.LasFile{Header: header}
+}
+
 // Object that can voxelize a LAS file into an output format
 type LASProcessor[T any] interface {
 
-	// Processes a chunk of the given LAS file
-	Process(inputFile *lidarioMod.LasFile, chunk *LASChunk, output chan<- *T, status *float64)
+	// Processes a chunk of the given LAS file, stopping with the context error if it is cancelled
+	Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *LASChunk, status *ChunkProgress) (*T, error)
 
 	// Gets the default state of the output object
 	EmptyOutput(inputFile *lidarioMod.LasFile) *T
 
-	// Combines output objects
+	// Combines output objects. Base is the empty output or the output of other chunks, and
+	// different pairs of outputs may be combined at the same time.
 	CombineOutput(base *T, incoming *T) *T
 }
 
+// Gets the record of a point from the raw bytes of its chunk, with the layout of its fields
+func pointRecord(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) ([]byte, *lidarioMod.PointLayout) {
+	layout := inputFile.PointLayout()
+
+	start := int64(layout.RecordLength) * int64(point - chunk.Start)
+
+	return rawBytes[start:start + int64(layout.RecordLength)], layout
+}
+
 // Reads the point data for a single point
 func ReadPointData(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) (float64, float64, float64) {
 
-	recordLength := inputFile.Header.PointRecordLength
+	record, _ := pointRecord(inputFile, chunk, rawBytes, point)
 
-	pointOffset := int64(recordLength) * int64(point - chunk.Start)
+	header := &inputFile.Header
 
-	x := float64(int32(binary.LittleEndian.Uint32(rawBytes[pointOffset:pointOffset+4])))*inputFile.Header.XScaleFactor + inputFile.Header.XOffset
-	pointOffset += 4
-	y := float64(int32(binary.LittleEndian.Uint32(rawBytes[pointOffset:pointOffset+4])))*inputFile.Header.YScaleFactor + inputFile.Header.YOffset
-	pointOffset += 4
-	z := float64(int32(binary.LittleEndian.Uint32(rawBytes[pointOffset:pointOffset+4])))*inputFile.Header.ZScaleFactor + inputFile.Header.ZOffset
+	x := float64(int32(binary.LittleEndian.Uint32(record[0:4]))) * header.XScaleFactor + header.XOffset
+	y := float64(int32(binary.LittleEndian.Uint32(record[4:8]))) * header.YScaleFactor + header.YOffset
+	z := float64(int32(binary.LittleEndian.Uint32(record[8:12]))) * header.ZScaleFactor + header.ZOffset
 
 	return x, y, z
 }
@@ -89,165 +204,463 @@ func ReadPointData(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []by
 // Gets the point source for a point
 func ReadPointSource(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
 
-	recordLength := inputFile.Header.PointRecordLength
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	return int(binary.LittleEndian.Uint16(record[layout.PointSource:]))
+}
+
+// Gets the return number and number of returns for a point
+func ReadReturns(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) (int, int) {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	returns := record[layout.Returns]
+
+	if layout.Extended {
+		// 4 bits each for return number and number of returns
+		return int(returns & 15), int(returns >> 4)
+	}
+
+	// 3 bits each for return number and number of returns
+	return int(returns & 7), int((returns >> 3) & 7)
+}
+
+// Gets the classification for a point
+func ReadClassification(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	if layout.Extended {
+		// full byte of classification, flags are stored separately
+		return int(record[layout.Classification])
+	}
+
+	// lower 5 bits are the classification, upper 3 are flags
+	return int(record[layout.Classification] & 31)
+}
+
+// Classification flags of a point
+type PointFlags struct {
+
+	// Whether the point was created by a technique other than LiDAR collection
+	Synthetic bool
+
+	// Whether the point is a model key point
+	KeyPoint bool
+
+	// Whether the point should be considered deleted
+	Withheld bool
+
+	// Whether the point is in the overlap of two swaths
+	Overlap bool
+
+}
+
+// Legacy (0-5) classification of overlap points, which have no overlap flag
+const legacyOverlapClass = 12
+
+// Gets the classification flags for a point
+func ReadFlags(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) PointFlags {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	flags := record[layout.Flags]
+
+	if layout.Extended {
+		// lower 4 bits of their own byte
+		return PointFlags{Synthetic: flags & 1 != 0, KeyPoint: flags & 2 != 0, Withheld: flags & 4 != 0, Overlap: flags & 8 != 0}
+	}
+
+	// upper 3 bits of the classification byte
+	return PointFlags{Synthetic: flags & 32 != 0, KeyPoint: flags & 64 != 0, Withheld: flags & 128 != 0,
+		Overlap: flags & 31 == legacyOverlapClass}
+}
+
+// Gets the scan angle for a point, in degrees
+func ReadScanAngle(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) float64 {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	if layout.Extended {
+		// in increments of 0.006 degrees
+		return float64(int16(binary.LittleEndian.Uint16(record[layout.ScanAngle:]))) * 0.006
+	}
+
+	// whole degrees
+	return float64(int8(record[layout.ScanAngle]))
+}
+
+// Gets the user data for a point, or 0 if the records don't store user data
+func ReadUserData(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	if layout.UserData < 0 {
+		return 0
+	}
+
+	return int(record[layout.UserData])
+}
+
+// Gets the scanner channel for a point, or 0 for legacy (0-5) records, which have no channel
+func ReadScannerChannel(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	if !layout.Extended {
+		return 0
+	}
+
+	// bits 4 and 5 of the byte of classification flags
+	return int((record[layout.Flags] >> 4) & 3)
+}
+
+// Whether the point records of the file store GPS time
+func HasGPSTime(inputFile *lidarioMod.LasFile) bool {
+	layout := inputFile.PointLayout()
+
+	return layout != nil && layout.GPSTime >= 0
+}
+
+// Gets the GPS time for a point, or 0 if the records don't store GPS time
+func ReadGPSTime(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) float64 {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	if layout.GPSTime < 0 {
+		return 0
+	}
+
+	return math.Float64frombits(binary.LittleEndian.Uint64(record[layout.GPSTime:]))
+}
+
+// Gets the intensity for a point, or 0 if the records don't store intensity
+func ReadIntensity(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	if layout.Intensity < 0 {
+		return 0
+	}
 
-	pointOffset := int64(recordLength) * int64(point - chunk.Start)
+	return int(binary.LittleEndian.Uint16(record[layout.Intensity:]))
+}
+
+// Whether the point records of the file store colour
+func HasRGB(inputFile *lidarioMod.LasFile) bool {
+	layout := inputFile.PointLayout()
+
+	return layout != nil && layout.RGB >= 0
+}
 
-	pointEnd := pointOffset + int64(recordLength)
+// Gets the red, green and blue values for a point, or 0s if the records don't store colour
+func ReadRGB(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) (int, int, int) {
 
-	var pointSourceStart int64
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
 
-	switch inputFile.Header.PointFormatID {
-	default:
-	case 0:
-		pointSourceStart = pointEnd - 2
-		break
+	if layout.RGB < 0 {
+		return 0, 0, 0
+	}
 
-	case 1:
-		pointSourceStart = pointEnd - 10
-		break
+	rgb := record[layout.RGB:]
 
-	case 2:
-		pointSourceStart = pointEnd - 8
-		break
+	r := binary.LittleEndian.Uint16(rgb[0:2])
+	g := binary.LittleEndian.Uint16(rgb[2:4])
+	b := binary.LittleEndian.Uint16(rgb[4:6])
 
-	case 3:
-		pointSourceStart = pointEnd - 16
-		break
+	return int(r), int(g), int(b)
+}
+
+// Gets the near infrared value for a point, or 0 if the records don't store near infrared
+func ReadNIR(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+
+	record, layout := pointRecord(inputFile, chunk, rawBytes, point)
+
+	if layout.NIR < 0 {
+		return 0
 	}
 
-	pointSource := binary.LittleEndian.Uint16(rawBytes[pointSourceStart:pointSourceStart+2])
+	return int(binary.LittleEndian.Uint16(record[layout.NIR:]))
+}
+
+// Gets the value of an extra bytes attribute of the file for a point, and whether the point has a value
+func ReadExtraBytes(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int, attribute *lidarioMod.ExtraBytesAttribute) (float64, bool) {
 
-	return int(pointSource)
+	record, _ := pointRecord(inputFile, chunk, rawBytes, point)
+
+	return attribute.Value(record)
+}
+
+// Number of items between checks for cancellation in long loops
+const CancelCheckInterval = 4096
+
+// Gets the error of a cancelled context, checking only every CancelCheckInterval items
+func CheckCancelled(ctx context.Context, item int) error {
+	if item % CancelCheckInterval != 0 {
+		return nil
+	}
+
+	return ctx.Err()
 }
 
-// Distributes the provided chunks over the provided channel, then sends nil
-func distributeChunks(chunks []*LASChunk, output chan<- *LASChunk, concurrency int, status *ConcurrentStatus) {
+// Distributes the provided chunks over the provided channel until cancelled, then closes it
+func distributeChunks(ctx context.Context, chunks []*LASChunk, output chan<- *LASChunk, status *ConcurrentStatus) {
+	defer close(output)
+
 	for i, chunk := range chunks {
-		*(status.CurrentChunk) = i
-		output <- chunk
+		status.currentChunk.Store(int64(i))
+		select {
+		case output <- chunk:
+		case <- ctx.Done():
+			return
+		}
 	}
-	*(status.CurrentChunk) = len(chunks)
-	for i := 0; i < concurrency; i++ {
-		output <- nil
+	status.currentChunk.Store(int64(len(chunks)))
+}
+
+// Output of processing a chunk
+type chunkResult[T any] struct {
+
+	// Output of the chunk, nil if processing failed
+	output *T
+
+	// Error from processing the chunk
+	err error
+
+}
+
+// Processes a chunk, wrapping any error with the chunk range
+func processChunk[T any](ctx context.Context, inputFile *lidarioMod.LasFile, chunk *LASChunk, processor LASProcessor[T], progress *ChunkProgress, status *ConcurrentStatus) (*T, error) {
+	status.startChunk(chunk, progress)
+
+	chunkError := &ChunkError{Start: chunk.Start, End: chunk.End}
+
+	// chunks of a mosaic are read from their own file
+	if chunk.File != nil {
+		inputFile = chunk.File
+		chunkError.File = chunk.File.FileName()
 	}
+
+	output, err := processor.Process(ctx, inputFile, chunk, progress)
+
+	if err != nil {
+		chunkError.Err = err
+		return nil, chunkError
+	}
+
+	status.finishChunk(chunk, progress)
+
+	return output, nil
 }
 
-// Concurrently processes some chunks
-func handleConcurrentProcess[T any](inputFile *lidarioMod.LasFile, inputChunk <-chan *LASChunk, processor LASProcessor[T], output chan<- *T, status *float64) {
-	// for non nil input chunks
-	for chunk := <- inputChunk; chunk != nil; chunk = <- inputChunk {
-		processor.Process(inputFile, chunk, output, status)
+// Concurrently processes some chunks, until the input channel is closed
+func handleConcurrentProcess[T any](ctx context.Context, inputFile *lidarioMod.LasFile, inputChunk <-chan *LASChunk, processor LASProcessor[T], output chan<- chunkResult[T], progress *ChunkProgress, status *ConcurrentStatus) {
+	for chunk := range inputChunk {
+		chunkOutput, err := processChunk(ctx, inputFile, chunk, processor, progress, status)
+		output <- chunkResult[T]{output: chunkOutput, err: err}
 	}
 }
 
-// Status of concurrent processing
-type ConcurrentStatus struct {
+// Output of one or more chunks being reduced into the final output
+type partialOutput[T any] struct {
 
-	// Total number of chunks
-	TotalChunks *int
+	// Combined output of the chunks
+	output *T
 
-	// Total number of concurrent processors
-	Concurrency *int
+	// Whether this includes the empty output, which stays the base of every combination so that it becomes the final output
+	root bool
 
-	// First chunk not being processed on (or TotalChunks if all are being processed on)
-	CurrentChunk *int
+}
+
+// Reduces the outputs of chunks pairwise as they finish, combining several pairs at the same time
+type reduction[T any] struct {
+
+	// Processor to combine outputs with
+	processor LASProcessor[T]
+
+	// Maximum number of combinations at the same time
+	concurrency int
+
+	// Status to count merges on
+	status *ConcurrentStatus
+
+	// Outputs waiting for another output to combine with
+	waiting []partialOutput[T]
 
-	// Progress on each currently processing chunk from 0% to 100% (0.0 - 1.0)
-	ChunkProgress []*float64
+	// Number of combinations in progress
+	merging int
 
-	// Number of merges performed
-	Merges *int
+	// Receives the result of each combination
+	merged chan partialOutput[T]
 
 }
 
-// Gets a new ConcurrentStatus
-func NewConcurrentStatus() *ConcurrentStatus {
+// Combines two partial outputs, keeping the root as the base
+func(reduction *reduction[T]) combine(first partialOutput[T], second partialOutput[T]) partialOutput[T] {
+	if second.root {
+		first, second = second, first
+	}
+
+	output := reduction.processor.CombineOutput(first.output, second.output)
 
-	totalChunks := 0
+	reduction.status.merges.Add(1)
 
-	concurrency := 0
+	return partialOutput[T]{output: output, root: first.root}
+}
 
-	currentChunk := 0
+// Starts combining waiting outputs in pairs, up to the maximum number of combinations
+func(reduction *reduction[T]) startMerges() {
+	for len(reduction.waiting) >= 2 && reduction.merging < reduction.concurrency {
+		last := len(reduction.waiting)
 
-	chunkProgress := []*float64{}
+		first, second := reduction.waiting[last - 2], reduction.waiting[last - 1]
 
-	merges := 0
+		reduction.waiting = reduction.waiting[:last - 2]
 
-	return &ConcurrentStatus{
-		TotalChunks: &totalChunks, 
-		Concurrency: &concurrency, 
-		CurrentChunk: &currentChunk, 
-		ChunkProgress: chunkProgress, 
-		Merges: &merges}
+		reduction.merging += 1
 
+		go func() {
+			reduction.merged <- reduction.combine(first, second)
+		}()
+	}
 }
 
-// Concurrently processes a LAS file into voxels in the specified output format
-func ConcurrentProcess[T any](inputFile *lidarioMod.LasFile, chunks []*LASChunk, processor LASProcessor[T], concurrency int, status *ConcurrentStatus) *T {
+// Collects and reduces the results of chunks until all workers have stopped and all combinations have finished,
+// leaving only the final output waiting. Stops reducing and cancels the workers at the first failed chunk or
+// cancellation, returning its error.
+func(reduction *reduction[T]) collect(ctx context.Context, results <-chan chunkResult[T], cancel context.CancelFunc) error {
+	var err error
+
+	for results != nil || reduction.merging > 0 {
+		select {
+		case result, ok := <- results:
+			if !ok {
+				results = nil
+				continue
+			}
+
+			if result.err != nil {
+				if err == nil {
+					err = result.err
+					cancel()
+				}
+				continue
+			}
+
+			if err == nil {
+				err = ctx.Err()
+			}
+
+			if err == nil {
+				reduction.waiting = append(reduction.waiting, partialOutput[T]{output: result.output})
+			}
+		case partial := <- reduction.merged:
+			reduction.merging -= 1
+			reduction.waiting = append(reduction.waiting, partial)
+		}
+
+		if err == nil {
+			reduction.startMerges()
+		}
+	}
+
+	return err
+}
+
+// Concurrently processes a LAS file into voxels in the specified output format,
+// returning the error from the first chunk that failed. Remaining chunks are abandoned
+// after a failure or once the context is cancelled, and every worker has stopped reading
+// the file by the time this returns.
+func ConcurrentProcess[T any](ctx context.Context, inputFile *lidarioMod.LasFile, chunks []*LASChunk, processor LASProcessor[T], concurrency int, status *ConcurrentStatus) (*T, error) {
 	
+	// without workers no chunk would ever be read
+	if concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+	}
+
 	if status == nil {
 		status = NewConcurrentStatus()
 	}
 
-	*(status.TotalChunks) = len(chunks)
+	chunkProgress := status.start(chunks, concurrency)
 
-	*(status.Concurrency) = concurrency
+	defer status.finish()
 
-	for i := 0; i < concurrency; i++ {
-		percent := 0.0
-		status.ChunkProgress = append(status.ChunkProgress, &percent)
-	}
+	// cancelled to stop the workers early when a chunk fails
+	workCtx, cancel := context.WithCancel(ctx)
 
+	defer cancel()
 
 	output := processor.EmptyOutput(inputFile)
 
-	outputChannel := make(chan *T)
+	outputChannel := make(chan chunkResult[T])
 
 	chunkChannel := make(chan *LASChunk)
 
 	// distribute chunks over chunk channel
-	go distributeChunks(chunks, chunkChannel, concurrency, status)
+	go distributeChunks(workCtx, chunks, chunkChannel, status)
+
+	workers := sync.WaitGroup{}
 
 	// start processing goroutines
 	for i := 0; i < concurrency; i++ {
-		go handleConcurrentProcess(inputFile, chunkChannel, processor, outputChannel, status.ChunkProgress[i])
+		workers.Add(1)
+		go func(progress *ChunkProgress) {
+			defer workers.Done()
+			handleConcurrentProcess(workCtx, inputFile, chunkChannel, processor, outputChannel, progress, status)
+		}(chunkProgress[i])
 	}
 
-	// collect outputs, stop when all have been read
-	for _, _ = range chunks {
-		readOutput := <- outputChannel
-		output = processor.CombineOutput(output, readOutput)
-		*(status.Merges) += 1
+	// close outputs once all workers have stopped
+	go func() {
+		workers.Wait()
+		close(outputChannel)
+	}()
+
+	reduction := &reduction[T]{processor: processor, concurrency: concurrency, status: status,
+		waiting: []partialOutput[T]{{output: output, root: true}}, merged: make(chan partialOutput[T])}
+
+	err := reduction.collect(ctx, outputChannel, cancel)
+
+	// report cancellation itself, rather than the chunk it interrupted
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
 
-	return output
+	if err != nil {
+		return nil, err
+	}
+
+	return reduction.waiting[0].output, nil
 }
 
-// Processes a LAS file sequentially
-func SequentialProcess[T any](inputFile *lidarioMod.LasFile, chunks []*LASChunk, processor LASProcessor[T], voxelSize float64, currentChunk *int, chunkProgress *float64) *T {
+// Processes a LAS file sequentially, stopping at the first chunk that fails
+func SequentialProcess[T any](ctx context.Context, inputFile *lidarioMod.LasFile, chunks []*LASChunk, processor LASProcessor[T], status *ConcurrentStatus) (*T, error) {
 	
-	if currentChunk == nil {
-		x := 0
-		currentChunk = &x
+	if status == nil {
+		status = NewConcurrentStatus()
 	}
 
-	if chunkProgress == nil {
-		x := 0.0
-		chunkProgress = &x
-	}
+	chunkProgress := status.start(chunks, 1)[0]
+
+	defer status.finish()
 	
 	output := processor.EmptyOutput(inputFile)
 
-	*currentChunk = 0
+	for i, chunk := range chunks {
+		status.currentChunk.Store(int64(i))
+
+		sequentialOutput, err := processChunk(ctx, inputFile, chunk, processor, chunkProgress, status)
+
+		if err != nil {
+			return nil, err
+		}
 
-	for _, chunk := range chunks {
-		channel := make(chan *T)
-		processor.Process(inputFile, chunk, channel, chunkProgress)
-		sequentialOutput := <- channel
 		output = processor.CombineOutput(output, sequentialOutput)
-		*currentChunk += 1
+		status.merges.Add(1)
 	}
 
-	return output
+	status.currentChunk.Store(int64(len(chunks)))
+
+	return output, nil
 }
@@ -0,0 +1,203 @@
+package lasProcessing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Reports progress as periodic plain log lines, for files and batch schedulers
+type PlainReporter struct {
+
+	// Where to write the log lines
+	Output io.Writer
+
+	// Time between log lines
+	Interval time.Duration
+
+}
+
+// Writes a timestamped log line
+func(reporter *PlainReporter) log(message string) {
+	fmt.Fprintln(reporter.Output, time.Now().Format(time.RFC3339) + " " + message)
+}
+
+// Reports the progress of concurrent processing as log lines
+func(reporter *PlainReporter) Processing(status *ConcurrentStatus, uiDone chan<- bool) {
+	for event := range status.Subscribe(reporter.Interval) {
+		if event.Done {
+			reporter.log(fmt.Sprintf("processing: finished %d chunks, %d points in %v (%.0f points/s)",
+				event.CompletedChunks, event.Points, event.Elapsed.Round(time.Millisecond), event.PointsPerSecond))
+			continue
+		}
+
+		// nothing to report before processing starts
+		if event.TotalChunks == 0 {
+			continue
+		}
+
+		reporter.log(fmt.Sprintf("processing: chunk %d / %d, %s", event.CompletedChunks, event.TotalChunks, progressSummary(event)))
+	}
+	uiDone <- true
+}
+
+// Reports the progress of a post processing pipeline as log lines
+func(reporter *PlainReporter) PostProcessing(status *PipelineStatus, uiDone chan<- bool) {
+	prevStep := ""
+	for event := range status.Subscribe(reporter.Interval) {
+		if event.Step != prevStep && prevStep != "" {
+			reporter.log("post processing: finished " + strings.ToLower(prevStep))
+		}
+
+		if event.Done {
+			reporter.log("post processing: finished")
+		} else if event.Step != "" {
+			message := fmt.Sprintf("post processing: %s %.2f%%", strings.ToLower(event.Step), math.Round(event.Progress * 10000) / 100)
+
+			if event.ETA > 0 {
+				message += ", ETA " + event.ETA.Round(time.Second).String()
+			}
+
+			reporter.log(message)
+		}
+
+		prevStep = event.Step
+	}
+	uiDone <- true
+}
+
+// Progress of concurrent processing as a JSON object
+type jsonProcessingEvent struct {
+
+	// Always "processing"
+	Type string `json:"type"`
+
+	// Time of the event
+	Time time.Time `json:"time"`
+
+	// Total number of chunks
+	TotalChunks int `json:"total_chunks"`
+
+	// First chunk not being processed on
+	CurrentChunk int `json:"current_chunk"`
+
+	// Number of chunks that have finished processing
+	CompletedChunks int `json:"completed_chunks"`
+
+	// Progress of each processor on its current chunk
+	ChunkProgress []float64 `json:"chunk_progress"`
+
+	// Number of merges performed
+	Merges int `json:"merges"`
+
+	// Approximate number of points processed
+	Points int `json:"points"`
+
+	// Total number of points to process
+	TotalPoints int `json:"total_points"`
+
+	// Points processed per second
+	PointsPerSecond float64 `json:"points_per_second"`
+
+	// Seconds since processing started
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+
+	// Estimated seconds until all points are processed, 0 if unknown
+	ETASeconds float64 `json:"eta_seconds"`
+
+	// Whether processing has finished
+	Done bool `json:"done"`
+
+}
+
+// Progress of a post processing pipeline as a JSON object
+type jsonPipelineEvent struct {
+
+	// Always "post_processing"
+	Type string `json:"type"`
+
+	// Time of the event
+	Time time.Time `json:"time"`
+
+	// Step name, empty once finished
+	Step string `json:"step"`
+
+	// Progress in the step
+	Progress float64 `json:"progress"`
+
+	// Seconds since the step started
+	StepElapsedSeconds float64 `json:"step_elapsed_seconds"`
+
+	// Estimated seconds until the step finishes, 0 if unknown
+	ETASeconds float64 `json:"eta_seconds"`
+
+	// Whether the pipeline has finished
+	Done bool `json:"done"`
+
+}
+
+// Reports progress as newline delimited JSON events, for other programs to consume
+type JSONReporter struct {
+
+	// Where to write the events
+	Output io.Writer
+
+	// Time between events
+	Interval time.Duration
+
+}
+
+// Writes an event as a line of JSON
+func(reporter *JSONReporter) write(event any) {
+	line, err := json.Marshal(event)
+
+	// progress is best effort, an event that can't be written is dropped
+	if err != nil {
+		return
+	}
+
+	reporter.Output.Write(append(line, '\n'))
+}
+
+// Reports the progress of concurrent processing as JSON events
+func(reporter *JSONReporter) Processing(status *ConcurrentStatus, uiDone chan<- bool) {
+	for event := range status.Subscribe(reporter.Interval) {
+		if event.TotalChunks == 0 && !event.Done {
+			continue
+		}
+
+		reporter.write(jsonProcessingEvent{
+			Type: "processing",
+			Time: time.Now(),
+			TotalChunks: event.TotalChunks,
+			CurrentChunk: event.CurrentChunk,
+			CompletedChunks: event.CompletedChunks,
+			ChunkProgress: event.ChunkProgress,
+			Merges: event.Merges,
+			Points: event.Points,
+			TotalPoints: event.TotalPoints,
+			PointsPerSecond: event.PointsPerSecond,
+			ElapsedSeconds: event.Elapsed.Seconds(),
+			ETASeconds: event.ETA.Seconds(),
+			Done: event.Done})
+	}
+	uiDone <- true
+}
+
+// Reports the progress of a post processing pipeline as JSON events
+func(reporter *JSONReporter) PostProcessing(status *PipelineStatus, uiDone chan<- bool) {
+	for event := range status.Subscribe(reporter.Interval) {
+		reporter.write(jsonPipelineEvent{
+			Type: "post_processing",
+			Time: time.Now(),
+			Step: event.Step,
+			Progress: event.Progress,
+			StepElapsedSeconds: event.StepElapsed.Seconds(),
+			ETASeconds: event.ETA.Seconds(),
+			Done: event.Done})
+	}
+	uiDone <- true
+}
@@ -0,0 +1,297 @@
+package lasProcessing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Post processing stage whose input and output types are only known at runtime, for assembling
+// pipelines from a configuration
+type DynamicStage struct {
+
+	// Name of the stage in the configuration
+	Name string
+
+	// Type the stage takes
+	Input reflect.Type
+
+	// Type the stage outputs
+	Output reflect.Type
+
+	// Processes an input of the input type into an output of the output type
+	process func(ctx context.Context, input any, status *PipelineStatus) (any, error)
+
+}
+
+// Gets the type of a type parameter
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Wraps a pipeline as a stage of the specified name
+func NewDynamicStage[I any, O any](name string, pipeline PostProcessingPipeline[I, O]) *DynamicStage {
+	return &DynamicStage{Name: name, Input: typeOf[I](), Output: typeOf[O](),
+		process: func(ctx context.Context, input any, status *PipelineStatus) (any, error) {
+			output, err := pipeline.Process(ctx, input.(I), status)
+
+			if err != nil {
+				return nil, wrapStageError(pipeline, err)
+			}
+
+			return output, nil
+		}}
+}
+
+// Processes an input of the input type of the stage
+func(stage *DynamicStage) Process(ctx context.Context, input any, status *PipelineStatus) (any, error) {
+	return stage.process(ctx, input, status)
+}
+
+// Marks dynamic stages as composite, as they wrap the errors of the pipelines they hold
+func(stage *DynamicStage) isComposite() {}
+
+// Chains stages in order, checking that each stage takes the output of the stage before it
+func ChainDynamicStages(stages []*DynamicStage) (*DynamicStage, error) {
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("pipeline has no stages")
+	}
+
+	for i := 1; i < len(stages); i++ {
+		previous, stage := stages[i - 1], stages[i]
+
+		if previous.Output != stage.Input {
+			return nil, fmt.Errorf("stage %d (%s) takes %v, but stage %d (%s) before it outputs %v",
+				i + 1, stage.Name, stage.Input, i, previous.Name, previous.Output)
+		}
+	}
+
+	names := make([]string, len(stages))
+
+	for i, stage := range stages {
+		names[i] = stage.Name
+	}
+
+	return &DynamicStage{Name: strings.Join(names, " -> "), Input: stages[0].Input, Output: stages[len(stages) - 1].Output,
+		process: func(ctx context.Context, input any, status *PipelineStatus) (any, error) {
+			intermediate := input
+
+			for _, stage := range stages {
+				// don't start the next stage once cancelled
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+
+				output, err := stage.Process(ctx, intermediate, status)
+
+				if err != nil {
+					return nil, err
+				}
+
+				intermediate = output
+			}
+
+			return intermediate, nil
+		}}, nil
+}
+
+// Pipeline of known types running a dynamic stage
+type typedPipeline[I any, O any] struct {
+
+	// Stage to run
+	stage *DynamicStage
+
+}
+
+// Processes the input with the stage
+func(pipeline *typedPipeline[I, O]) Process(ctx context.Context, input I, status *PipelineStatus) (O, error) {
+	var empty O
+
+	output, err := pipeline.stage.Process(ctx, input, status)
+
+	if err != nil {
+		return empty, err
+	}
+
+	return output.(O), nil
+}
+
+// Marks typed pipelines as composite, as their stages wrap their own errors
+func(pipeline *typedPipeline[I, O]) isComposite() {}
+
+// Gets a dynamic stage as a pipeline of known types, checking that it takes and outputs them
+func TypedPipeline[I any, O any](stage *DynamicStage) (PostProcessingPipeline[I, O], error) {
+	if input := typeOf[I](); stage.Input != input {
+		return nil, fmt.Errorf("pipeline has to start from %v, but it starts from %v", input, stage.Input)
+	}
+
+	if output := typeOf[O](); stage.Output != output {
+		return nil, fmt.Errorf("pipeline has to output %v, but it outputs %v", output, stage.Output)
+	}
+
+	return &typedPipeline[I, O]{stage: stage}, nil
+}
+
+// Stage of a pipeline configuration
+type StageConfig struct {
+
+	// Name of the stage in the registry
+	Stage string `json:"stage"`
+
+	// Parameters of the stage, as a JSON object, stages use their defaults for missing parameters
+	Parameters json.RawMessage `json:"parameters"`
+
+}
+
+// Pipeline described in a configuration file
+type PipelineConfig struct {
+
+	// Where the pipeline writes its output, empty to leave it to the command
+	Output string `json:"output"`
+
+	// Stages of the pipeline in order
+	Stages []StageConfig `json:"stages"`
+
+}
+
+// Reads a pipeline configuration from a JSON file, rejecting unknown fields
+func ReadPipelineConfig(fileName string) (*PipelineConfig, error) {
+	contents, err := os.ReadFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+
+	decoder.DisallowUnknownFields()
+
+	var config PipelineConfig
+
+	if err = decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", fileName, err)
+	}
+
+	if len(config.Stages) == 0 {
+		return nil, fmt.Errorf("reading %s: pipeline has no stages", fileName)
+	}
+
+	return &config, nil
+}
+
+// Stage that can be created from a configuration
+type registeredStage struct {
+
+	// Type the stage takes
+	input reflect.Type
+
+	// Type the stage outputs
+	output reflect.Type
+
+	// Creates the stage from its parameters
+	create func(parameters json.RawMessage) (*DynamicStage, error)
+
+}
+
+// Stages that pipelines can be configured from, by name
+type StageRegistry struct {
+
+	// Stages by name
+	stages map[string]registeredStage
+
+}
+
+// Creates a registry with no stages
+func NewStageRegistry() *StageRegistry {
+	return &StageRegistry{stages: make(map[string]registeredStage)}
+}
+
+// Registers a stage created from parameters of type P, decoded from JSON over a copy of the defaults.
+// Unknown parameters are rejected.
+func RegisterStage[I any, O any, P any](registry *StageRegistry, name string, defaults P, create func(parameters P) (PostProcessingPipeline[I, O], error)) {
+	registry.stages[name] = registeredStage{input: typeOf[I](), output: typeOf[O](),
+		create: func(raw json.RawMessage) (*DynamicStage, error) {
+			parameters := defaults
+
+			if len(raw) > 0 && string(raw) != "null" {
+				decoder := json.NewDecoder(bytes.NewReader(raw))
+
+				decoder.DisallowUnknownFields()
+
+				if err := decoder.Decode(&parameters); err != nil {
+					return nil, fmt.Errorf("parameters: %w", err)
+				}
+			}
+
+			pipeline, err := create(parameters)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return NewDynamicStage[I, O](name, pipeline), nil
+		}}
+}
+
+// Gets the names of the registered stages in order
+func(registry *StageRegistry) Names() []string {
+	names := make([]string, 0, len(registry.stages))
+
+	for name := range registry.stages {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Describes a registered stage by the types it takes and outputs
+func(registry *StageRegistry) Describe(name string) string {
+	stage := registry.stages[name]
+
+	return fmt.Sprintf("%s: %v -> %v", name, stage.input, stage.output)
+}
+
+// Creates the stages of a configuration and chains them, checking that each stage takes
+// the output of the stage before it before creating any of them
+func(registry *StageRegistry) Build(config *PipelineConfig) (*DynamicStage, error) {
+	for i, stageConfig := range config.Stages {
+		stage, known := registry.stages[stageConfig.Stage]
+
+		if !known {
+			return nil, fmt.Errorf("stage %d: unknown stage %q, must be one of %s", i + 1, stageConfig.Stage,
+				strings.Join(registry.Names(), ", "))
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		previous := registry.stages[config.Stages[i - 1].Stage]
+
+		if previous.output != stage.input {
+			return nil, fmt.Errorf("stage %d (%s) takes %v, but stage %d (%s) before it outputs %v",
+				i + 1, stageConfig.Stage, stage.input, i, config.Stages[i - 1].Stage, previous.output)
+		}
+	}
+
+	stages := make([]*DynamicStage, len(config.Stages))
+
+	for i, stageConfig := range config.Stages {
+		stage, err := registry.stages[stageConfig.Stage].create(stageConfig.Parameters)
+
+		if err != nil {
+			return nil, fmt.Errorf("stage %d (%s): %w", i + 1, stageConfig.Stage, err)
+		}
+
+		stages[i] = stage
+	}
+
+	return ChainDynamicStages(stages)
+}
@@ -0,0 +1,162 @@
+package lasProcessing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Parameters of the test scale stage
+type scaleParameters struct {
+
+	// Factor to multiply by
+	Factor int `json:"factor"`
+
+}
+
+// Multiplies ints by a factor
+type scaler struct {
+
+	// Factor to multiply by
+	factor int
+
+}
+
+// Multiplies an int by the factor
+func(scaler *scaler) Process(ctx context.Context, input int, status *PipelineStatus) (int, error) {
+	return input * scaler.factor, nil
+}
+
+// Formats ints as strings
+type formatter struct {
+
+}
+
+// Formats an int as a string
+func(formatter *formatter) Process(ctx context.Context, input int, status *PipelineStatus) (string, error) {
+	return strings.Repeat("*", input), nil
+}
+
+// creates a registry of the test stages, scaling by 2 by default
+func testRegistry() *StageRegistry {
+	registry := NewStageRegistry()
+
+	RegisterStage(registry, "scale", scaleParameters{Factor: 2},
+		func(parameters scaleParameters) (PostProcessingPipeline[int, int], error) {
+			return &scaler{factor: parameters.Factor}, nil
+		})
+
+	RegisterStage(registry, "format", struct{}{},
+		func(parameters struct{}) (PostProcessingPipeline[int, string], error) {
+			return &formatter{}, nil
+		})
+
+	return registry
+}
+
+// writes a pipeline configuration to a temporary file and reads it
+func readTestConfig(t *testing.T, contents string) *PipelineConfig {
+	fileName := filepath.Join(t.TempDir(), "pipeline.json")
+
+	if err := os.WriteFile(fileName, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ReadPipelineConfig(fileName)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestBuildRejectsIncompatibleStages(t *testing.T) {
+	config := readTestConfig(t, `{"stages": [{"stage": "scale"}, {"stage": "format"}, {"stage": "scale"}]}`)
+
+	_, err := testRegistry().Build(config)
+
+	expected := "stage 3 (scale) takes int, but stage 2 (format) before it outputs string"
+
+	if err == nil || err.Error() != expected {
+		t.Fatalf("got error %v, expected %q", err, expected)
+	}
+}
+
+func TestChainDynamicStagesRejectsIncompatibleStages(t *testing.T) {
+	stages := []*DynamicStage{
+		NewDynamicStage[int, string]("format", &formatter{}),
+		NewDynamicStage[int, int]("scale", &scaler{factor: 2})}
+
+	_, err := ChainDynamicStages(stages)
+
+	expected := "stage 2 (scale) takes int, but stage 1 (format) before it outputs string"
+
+	if err == nil || err.Error() != expected {
+		t.Fatalf("got error %v, expected %q", err, expected)
+	}
+}
+
+func TestBuildRejectsUnknownStagesAndParameters(t *testing.T) {
+	registry := testRegistry()
+
+	_, err := registry.Build(readTestConfig(t, `{"stages": [{"stage": "shrink"}]}`))
+
+	if err == nil || !strings.Contains(err.Error(), `stage 1: unknown stage "shrink", must be one of format, scale`) {
+		t.Fatalf("got error %v for an unknown stage", err)
+	}
+
+	_, err = registry.Build(readTestConfig(t, `{"stages": [{"stage": "scale", "parameters": {"factr": 3}}]}`))
+
+	if err == nil || !strings.Contains(err.Error(), `stage 1 (scale): parameters: json: unknown field "factr"`) {
+		t.Fatalf("got error %v for an unknown parameter", err)
+	}
+}
+
+func TestStageParametersReachStages(t *testing.T) {
+	config := readTestConfig(t, `{"stages": [
+		{"stage": "scale", "parameters": {"factor": 3}},
+		{"stage": "scale"},
+		{"stage": "format"}]}`)
+
+	stage, err := testRegistry().Build(config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline, err := TypedPipeline[int, string](stage)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// scaled by the configured 3, then the default 2
+	output, err := pipeline.Process(context.Background(), 1, &PipelineStatus{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != "******" {
+		t.Fatalf("got %q, expected 6 stars", output)
+	}
+}
+
+func TestTypedPipelineChecksTypes(t *testing.T) {
+	stage, err := testRegistry().Build(readTestConfig(t, `{"stages": [{"stage": "scale"}]}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = TypedPipeline[string, int](stage); err == nil || err.Error() != "pipeline has to start from string, but it starts from int" {
+		t.Fatalf("got error %v for the wrong input", err)
+	}
+
+	if _, err = TypedPipeline[int, string](stage); err == nil || err.Error() != "pipeline has to output string, but it outputs int" {
+		t.Fatalf("got error %v for the wrong output", err)
+	}
+}
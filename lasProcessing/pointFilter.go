@@ -0,0 +1,200 @@
+package lasProcessing
+
+import (
+	"math"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Decides which points of a chunk to process
+type PointFilter interface {
+
+	// Whether to process a point
+	Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool
+
+}
+
+// Filters that a point has to pass all of, keeping every point if there are none
+type PointFilters []PointFilter
+
+// Whether a point passes every filter
+func(filters PointFilters) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	for _, filter := range filters {
+		if !filter.Keep(inputFile, chunk, rawBytes, point) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Keeps points by classification
+type ClassFilter struct {
+
+	// Classifications to keep, or to drop if excluding
+	Classes map[int]bool
+
+	// Whether to drop the classifications instead of keeping only them
+	Exclude bool
+
+}
+
+// Whether the classification of a point is kept
+func(filter *ClassFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	return filter.Classes[ReadClassification(inputFile, chunk, rawBytes, point)] != filter.Exclude
+}
+
+// Keeps points by return, keeping a point that matches any of the selected returns
+type ReturnFilter struct {
+
+	// Return numbers to keep
+	Numbers map[int]bool
+
+	// Whether to keep first returns
+	First bool
+
+	// Whether to keep last returns
+	Last bool
+
+	// Whether to keep the returns of pulses with only one return
+	Single bool
+
+	// Whether to keep the returns of pulses with more than one return
+	Multiple bool
+
+}
+
+// Whether the return of a point is kept
+func(filter *ReturnFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	returnNumber, numberOfReturns := ReadReturns(inputFile, chunk, rawBytes, point)
+
+	// points without a return count are neither first nor last returns
+	counted := numberOfReturns > 0
+
+	return filter.Numbers[returnNumber] ||
+		(filter.First && counted && returnNumber == 1) ||
+		(filter.Last && counted && returnNumber == numberOfReturns) ||
+		(filter.Single && numberOfReturns == 1) ||
+		(filter.Multiple && numberOfReturns > 1)
+}
+
+// Drops points with any of the selected classification flags
+type FlagFilter struct {
+
+	// Whether to drop synthetic points
+	Synthetic bool
+
+	// Whether to drop model key points
+	KeyPoint bool
+
+	// Whether to drop withheld points
+	Withheld bool
+
+	// Whether to drop overlap points, flagged in formats 6-10 and class 12 in formats 0-5
+	Overlap bool
+
+}
+
+// Whether a point has none of the dropped flags
+func(filter *FlagFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	flags := ReadFlags(inputFile, chunk, rawBytes, point)
+
+	return !(filter.Synthetic && flags.Synthetic) &&
+		!(filter.KeyPoint && flags.KeyPoint) &&
+		!(filter.Withheld && flags.Withheld) &&
+		!(filter.Overlap && flags.Overlap)
+}
+
+// Keeps points scanned close to nadir
+type ScanAngleFilter struct {
+
+	// Largest absolute scan angle to keep, in degrees
+	MaxAngle float64
+
+}
+
+// Whether the scan angle of a point is kept
+func(filter *ScanAngleFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	return math.Abs(ReadScanAngle(inputFile, chunk, rawBytes, point)) <= filter.MaxAngle
+}
+
+// Keeps points with an intensity in a range
+type IntensityFilter struct {
+
+	// Lowest intensity to keep
+	Min float64
+
+	// Highest intensity to keep
+	Max float64
+
+}
+
+// Whether the intensity of a point is in the range
+func(filter *IntensityFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	intensity := float64(ReadIntensity(inputFile, chunk, rawBytes, point))
+
+	return intensity >= filter.Min && intensity <= filter.Max
+}
+
+// Keeps points captured in a GPS time window, only for files that store GPS time
+type GPSTimeFilter struct {
+
+	// Earliest GPS time to keep
+	Min float64
+
+	// Latest GPS time to keep
+	Max float64
+
+}
+
+// Whether the GPS time of a point is in the window
+func(filter *GPSTimeFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	time := ReadGPSTime(inputFile, chunk, rawBytes, point)
+
+	return time >= filter.Min && time <= filter.Max
+}
+
+// Keeps points with an elevation in a range
+type ZFilter struct {
+
+	// Lowest elevation to keep, in the units of the file
+	Min float64
+
+	// Highest elevation to keep, in the units of the file
+	Max float64
+
+}
+
+// Whether the elevation of a point is in the range
+func(filter *ZFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	_, _, z := ReadPointData(inputFile, chunk, rawBytes, point)
+
+	return z >= filter.Min && z <= filter.Max
+}
+
+// Keeps points with a value of an extra bytes attribute in a range, dropping points without a value
+type ExtraBytesFilter struct {
+
+	// Name of the attribute in the Extra Bytes VLR
+	Name string
+
+	// Lowest value to keep
+	Min float64
+
+	// Highest value to keep
+	Max float64
+
+}
+
+// Whether the value of the attribute for a point is in the range
+func(filter *ExtraBytesFilter) Keep(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) bool {
+	attribute := inputFile.PointLayout().ExtraAttribute(filter.Name)
+
+	if attribute == nil {
+		return false
+	}
+
+	value, ok := ReadExtraBytes(inputFile, chunk, rawBytes, point, attribute)
+
+	return ok && value >= filter.Min && value <= filter.Max
+}
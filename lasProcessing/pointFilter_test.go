@@ -0,0 +1,71 @@
+package lasProcessing
+
+import (
+	"testing"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Counts the points of a file a filter keeps, reading the file in several chunks
+func countKept(t *testing.T, file *lidarioMod.LasFile, filter PointFilter) int {
+	kept := 0
+
+	for _, chunk := range ChunkFile(file, 3) {
+		rawBytes, err := chunk.ReadOnFile(file)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := chunk.Start; i < chunk.End; i++ {
+			if filter.Keep(file, chunk, rawBytes, i) {
+				kept += 1
+			}
+		}
+	}
+
+	return kept
+}
+
+// extrabytes.las stores for point i a Reflectance of ((i*37)%2000-1000) scaled by 0.01, and a Confidence of i%100
+// that every seventh point has no value of
+func TestExtraBytesFilter(t *testing.T) {
+	for _, name := range []string{"extrabytes.las", "extrabytes.laz"} {
+		file, err := lidarioMod.NewLasFile("../lidarioMod/testdata/" + name, "rh")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer file.Close()
+
+		reflectance, confidence, high := 0, 0, 0
+
+		for i := 0; i < file.Header.NumberPoints; i++ {
+			if value := float64((i * 37) % 2000 - 1000) * 0.01; value >= -2 && value <= 3 {
+				reflectance += 1
+			}
+
+			if i % 7 != 0 {
+				confidence += 1
+			}
+
+			if i % 7 != 0 && i % 100 >= 90 {
+				high += 1
+			}
+		}
+
+		for _, c := range []struct{ filter *ExtraBytesFilter; expected int }{
+			{&ExtraBytesFilter{Name: "Reflectance", Min: -2, Max: 3}, reflectance},
+			// points without a value are dropped, whatever the range
+			{&ExtraBytesFilter{Name: "Confidence", Min: 0, Max: 255}, confidence},
+			{&ExtraBytesFilter{Name: "Confidence", Min: 90, Max: 254}, high},
+			// a file without the attribute keeps no points
+			{&ExtraBytesFilter{Name: "Range", Min: -1000, Max: 1000}, 0},
+		} {
+			if kept := countKept(t, file, c.filter); kept != c.expected {
+				t.Fatalf("%s: %+v kept %d points, expected %d", name, *c.filter, kept, c.expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package lasProcessing
+
+import (
+	"context"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Writes the points of a LAS file as CSV rows, counting the points written. Rows are written
+// as each chunk is processed, so it has to be used with SequentialProcess to keep them in order.
+type PointCSVWriter struct {
+
+	// Where to write the rows
+	Output io.Writer
+
+	// Points to write
+	Filters PointFilters
+
+	// Whether to write the intensity, returns, classification and point source of each point
+	Attributes bool
+
+	// Whether to write the GPS time of each point with its attributes
+	GPSTime bool
+
+	// Whether to write the colour of each point with its attributes
+	RGB bool
+
+}
+
+// Creates a writer for the points of a file, writing the attributes it stores if requested
+func NewPointCSVWriter(output io.Writer, inputFile *lidarioMod.LasFile, filters PointFilters, attributes bool) *PointCSVWriter {
+	return &PointCSVWriter{Output: output, Filters: filters, Attributes: attributes,
+		GPSTime: attributes && HasGPSTime(inputFile), RGB: attributes && HasRGB(inputFile)}
+}
+
+// Writes the header row
+func(writer *PointCSVWriter) WriteHeader() error {
+	header := "x,y,z"
+
+	if writer.Attributes {
+		header += ",intensity,return_number,number_of_returns,classification,point_source"
+	}
+
+	if writer.GPSTime {
+		header += ",gps_time"
+	}
+
+	if writer.RGB {
+		header += ",red,green,blue"
+	}
+
+	_, err := io.WriteString(writer.Output, header + "\n")
+
+	return err
+}
+
+// Gets the decimal places needed to write positions at the precision of a scale factor
+func scaleDecimals(scale float64) int {
+	if scale <= 0 {
+		return 6
+	}
+
+	return int(math.Max(math.Ceil(-math.Log10(scale) - 1e-9), 0))
+}
+
+// Writes the kept points of a chunk, getting how many were written
+func(writer *PointCSVWriter) Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *LASChunk, status *ChunkProgress) (*int, error) {
+
+	status.Set(0.0)
+
+	rawBytes, err := chunk.ReadOnFile(inputFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	header := &inputFile.Header
+
+	xDecimals, yDecimals, zDecimals := scaleDecimals(header.XScaleFactor), scaleDecimals(header.YScaleFactor), scaleDecimals(header.ZScaleFactor)
+
+	rows := make([]byte, 0, 64 * (chunk.End - chunk.Start))
+
+	written := 0
+
+	for i := chunk.Start; i < chunk.End; i++ {
+		if err := CheckCancelled(ctx, i - chunk.Start); err != nil {
+			return nil, err
+		}
+
+		status.Set(float64(i - chunk.Start) / float64(chunk.End - chunk.Start))
+
+		if !writer.Filters.Keep(inputFile, chunk, rawBytes, i) {
+			continue
+		}
+
+		x, y, z := ReadPointData(inputFile, chunk, rawBytes, i)
+
+		rows = strconv.AppendFloat(rows, x, 'f', xDecimals, 64)
+		rows = append(rows, ',')
+		rows = strconv.AppendFloat(rows, y, 'f', yDecimals, 64)
+		rows = append(rows, ',')
+		rows = strconv.AppendFloat(rows, z, 'f', zDecimals, 64)
+
+		if writer.Attributes {
+			returnNumber, returns := ReadReturns(inputFile, chunk, rawBytes, i)
+
+			for _, value := range []int{ReadIntensity(inputFile, chunk, rawBytes, i), returnNumber, returns,
+				ReadClassification(inputFile, chunk, rawBytes, i), ReadPointSource(inputFile, chunk, rawBytes, i)} {
+				rows = append(rows, ',')
+				rows = strconv.AppendInt(rows, int64(value), 10)
+			}
+		}
+
+		if writer.GPSTime {
+			rows = append(rows, ',')
+			rows = strconv.AppendFloat(rows, ReadGPSTime(inputFile, chunk, rawBytes, i), 'f', -1, 64)
+		}
+
+		if writer.RGB {
+			r, g, b := ReadRGB(inputFile, chunk, rawBytes, i)
+
+			for _, value := range []int{r, g, b} {
+				rows = append(rows, ',')
+				rows = strconv.AppendInt(rows, int64(value), 10)
+			}
+		}
+
+		rows = append(rows, '\n')
+
+		written += 1
+	}
+
+	if _, err := writer.Output.Write(rows); err != nil {
+		return nil, err
+	}
+
+	status.Set(1.0)
+
+	return &written, nil
+}
+
+// Gets a count of no points
+func(writer *PointCSVWriter) EmptyOutput(inputFile *lidarioMod.LasFile) *int {
+	written := 0
+
+	return &written
+}
+
+// Adds the points written for a chunk to the count
+func(writer *PointCSVWriter) CombineOutput(base *int, incoming *int) *int {
+	total := *base + *incoming
+
+	return &total
+}
@@ -1,49 +1,414 @@
 package lasProcessing
 
-// Status of a PostProcessingPipeline
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status of a PostProcessingPipeline, safe to update and read from any goroutine
 type PipelineStatus struct {
 
+	// Guards the status
+	lock sync.Mutex
+
 	// Step name
-	Step string;
+	step string
 
 	// Progress in the step
-	Progress float64;
+	progress float64
+
+	// When the current step started
+	stepStarted time.Time
+
+	// Whether the pipeline has finished
+	done bool
+
+	// Closed once the pipeline finishes
+	finished chan struct{}
+
+}
+
+// Snapshot of the status of a PostProcessingPipeline
+type PipelineEvent struct {
+
+	// Step name, empty once finished
+	Step string
+
+	// Progress in the step from 0% to 100% (0.0 - 1.0)
+	Progress float64
+
+	// Time since the step started
+	StepElapsed time.Duration
+
+	// Estimated time until the step finishes, 0 if unknown
+	ETA time.Duration
+
+	// Whether the pipeline has finished
+	Done bool
+
+}
+
+// Sets the current step and the progress in it
+func(status *PipelineStatus) Set(step string, progress float64) {
+	status.lock.Lock()
+	defer status.lock.Unlock()
+
+	if step != status.step || status.stepStarted.IsZero() {
+		status.stepStarted = time.Now()
+	}
+
+	status.step = step
+	status.progress = progress
+}
+
+// Gets the channel closed once the pipeline finishes, must be called with the lock held
+func(status *PipelineStatus) finishedChannel() chan struct{} {
+	if status.finished == nil {
+		status.finished = make(chan struct{})
+	}
+
+	return status.finished
+}
+
+// Marks the pipeline as finished
+func(status *PipelineStatus) finish() {
+	status.lock.Lock()
+	defer status.lock.Unlock()
+
+	status.step = ""
+
+	if !status.done {
+		status.done = true
+		close(status.finishedChannel())
+	}
+}
+
+// Gets a snapshot of the status
+func(status *PipelineStatus) Snapshot() PipelineEvent {
+	status.lock.Lock()
+	defer status.lock.Unlock()
+
+	event := PipelineEvent{Step: status.step, Progress: status.progress, Done: status.done}
+
+	if !status.stepStarted.IsZero() {
+		event.StepElapsed = time.Since(status.stepStarted)
+	}
+
+	if event.Progress > 0 && event.Progress < 1 {
+		event.ETA = time.Duration(float64(event.StepElapsed) * (1 - event.Progress) / event.Progress)
+	}
 
+	return event
 }
 
-// Pipeline for post processing from input to output
+// Subscribes to the status, getting a snapshot every interval and a final snapshot once the pipeline finishes.
+// The channel is closed after the final snapshot, and must be read until then.
+func(status *PipelineStatus) Subscribe(interval time.Duration) <-chan PipelineEvent {
+	status.lock.Lock()
+	finished := status.finishedChannel()
+	status.lock.Unlock()
+
+	return subscribe(interval, status.Snapshot, func(event PipelineEvent) bool { return event.Done }, finished)
+}
+
+// Pipeline for post processing from input to output, stopping with the context error if it is cancelled
 type PostProcessingPipeline[I any, O any] interface {
-	Process(input I, output *PipelineStatus) O
+	Process(ctx context.Context, input I, output *PipelineStatus) (O, error)
+}
+
+// Error from a stage of a pipeline
+type StageError struct {
+
+	// Name of the stage that failed
+	Stage string
+
+	// Error from the stage
+	Err error
+
+}
+
+// Gets the error message
+func(err *StageError) Error() string {
+	return err.Stage + ": " + err.Err.Error()
+}
+
+// Gets the error from the stage
+func(err *StageError) Unwrap() error {
+	return err.Err
+}
+
+// Pipeline made of other pipelines, whose errors are already wrapped
+type compositePipeline interface {
+	isComposite()
+}
+
+// Gets the name of a pipeline stage from its type
+func stageName(stage any) string {
+	stageType := reflect.TypeOf(stage)
+
+	for stageType.Kind() == reflect.Pointer {
+		stageType = stageType.Elem()
+	}
+
+	// drop any type parameters
+	name, _, _ := strings.Cut(stageType.Name(), "[")
+
+	return name
+}
+
+// Wraps an error from a pipeline stage with the name of the stage
+func wrapStageError(stage any, err error) error {
+	if _, composite := stage.(compositePipeline); composite {
+		return err
+	}
+
+	return &StageError{Stage: stageName(stage), Err: err}
 }
 
 // Monitorable post processing pipeline
 type chainedPipeline[I any, O any] struct {
 	// All the steps in the pipeline to be executed in
-	callChain func(input I, output *PipelineStatus) O
+	callChain func(ctx context.Context, input I, output *PipelineStatus) (O, error)
 }
 
-// Chains some monitorable pipelines
+// Chains some monitorable pipelines, errors from either are wrapped in a StageError
 func ChainPipeline[I any, C any, O any](firstPipeline PostProcessingPipeline[I, C], secondPipeline PostProcessingPipeline[C, O]) PostProcessingPipeline[I, O] {
-	return &chainedPipeline[I, O]{callChain: func(input I, output *PipelineStatus) O {
-		return secondPipeline.Process(firstPipeline.Process(input, output), output)
+	return &chainedPipeline[I, O]{callChain: func(ctx context.Context, input I, output *PipelineStatus) (O, error) {
+		var empty O
+
+		intermediate, err := firstPipeline.Process(ctx, input, output)
+
+		if err != nil {
+			return empty, wrapStageError(firstPipeline, err)
+		}
+
+		// don't start the next stage once cancelled
+		if err = ctx.Err(); err != nil {
+			return empty, err
+		}
+
+		result, err := secondPipeline.Process(ctx, intermediate, output)
+
+		if err != nil {
+			return empty, wrapStageError(secondPipeline, err)
+		}
+
+		return result, nil
 	}}
 }
 
 // Executes a monitorable pipeline
-func(pipeline *chainedPipeline[I, O]) Process(input I, output *PipelineStatus) O {
-	return pipeline.callChain(input, output)
+func(pipeline *chainedPipeline[I, O]) Process(ctx context.Context, input I, output *PipelineStatus) (O, error) {
+	return pipeline.callChain(ctx, input, output)
+}
+
+// Marks chained pipelines as composite
+func(pipeline *chainedPipeline[I, O]) isComposite() {}
+
+// Pipeline running several branches on the same input
+type fanOutPipeline[I any, O any] struct {
+
+	// Branches to run, each given the same input
+	branches []PostProcessingPipeline[I, O]
+
+	// Whether to run the branches at the same time
+	concurrent bool
+
+}
+
+// Runs several pipelines on the same input, getting their outputs in the order of the branches. Branches
+// share their input, so they must not modify it. Concurrent branches run at the same time and report to the
+// same status, and once one fails the others are cancelled. Errors are wrapped in a StageError as for ChainPipeline.
+func FanOut[I any, O any](concurrent bool, branches ...PostProcessingPipeline[I, O]) PostProcessingPipeline[I, []O] {
+	return &fanOutPipeline[I, O]{branches: branches, concurrent: concurrent}
+}
+
+// Runs one branch, wrapping its error
+func(pipeline *fanOutPipeline[I, O]) runBranch(ctx context.Context, branch PostProcessingPipeline[I, O], input I, status *PipelineStatus) (O, error) {
+	output, err := branch.Process(ctx, input, status)
+
+	if err != nil {
+		return output, wrapStageError(branch, err)
+	}
+
+	return output, nil
+}
+
+// Runs each branch on the input
+func(pipeline *fanOutPipeline[I, O]) Process(ctx context.Context, input I, status *PipelineStatus) ([]O, error) {
+	outputs := make([]O, len(pipeline.branches))
+
+	if !pipeline.concurrent {
+		for i, branch := range pipeline.branches {
+			// don't start the next branch once cancelled
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			output, err := pipeline.runBranch(ctx, branch, input, status)
+
+			if err != nil {
+				return nil, err
+			}
+
+			outputs[i] = output
+		}
+
+		return outputs, nil
+	}
+
+	err := ProcessConcurrently(ctx, len(pipeline.branches), len(pipeline.branches), func(ctx context.Context, i int) error {
+		output, err := pipeline.runBranch(ctx, pipeline.branches[i], input, status)
+
+		outputs[i] = output
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// Marks fan outs as composite
+func(pipeline *fanOutPipeline[I, O]) isComposite() {}
+
+// Pipeline running its own pipeline on each input
+type eachPipeline[I any, O any] struct {
+
+	// Names of the inputs, for errors
+	names []string
+
+	// Pipeline for each input
+	pipelines []PostProcessingPipeline[I, O]
+
+	// Most pipelines to run at the same time
+	concurrency int
+
+}
+
+// Runs the pipeline of the same index on each input, getting their outputs in order. At most concurrency
+// pipelines run at the same time, reporting to the same status, and once one fails the others are cancelled.
+// Errors are wrapped in a StageError as for ChainPipeline, then prefixed with the name of their input.
+func ProcessEach[I any, O any](concurrency int, names []string, pipelines []PostProcessingPipeline[I, O]) PostProcessingPipeline[[]I, []O] {
+	return &eachPipeline[I, O]{names: names, pipelines: pipelines, concurrency: concurrency}
+}
+
+// Runs each pipeline on its input
+func(pipeline *eachPipeline[I, O]) Process(ctx context.Context, inputs []I, status *PipelineStatus) ([]O, error) {
+	if len(inputs) != len(pipeline.pipelines) {
+		return nil, fmt.Errorf("%d inputs for %d pipelines", len(inputs), len(pipeline.pipelines))
+	}
+
+	outputs := make([]O, len(inputs))
+
+	err := ProcessConcurrently(ctx, len(inputs), pipeline.concurrency, func(ctx context.Context, i int) error {
+		output, err := pipeline.pipelines[i].Process(ctx, inputs[i], status)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", pipeline.names[i], wrapStageError(pipeline.pipelines[i], err))
+		}
+
+		outputs[i] = output
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// Marks pipelines run on each input as composite
+func(pipeline *eachPipeline[I, O]) isComposite() {}
+
+// Calls process with each index from 0 up to count, running at most concurrency calls at a time. Once a call
+// fails no more are started and the running calls are cancelled. Cancellation of ctx is reported itself, and
+// otherwise the error of the first call to fail in order of index, rather than the cancellation of the others.
+func ProcessConcurrently(ctx context.Context, count int, concurrency int, process func(ctx context.Context, i int) error) error {
+	// cancelled to stop the other calls when one fails
+	processCtx, cancel := context.WithCancel(ctx)
+
+	defer cancel()
+
+	errs := make([]error, count)
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	slots := make(chan struct{}, concurrency)
+
+	calls := sync.WaitGroup{}
+
+	for i := 0; i < count; i++ {
+		slots <- struct{}{}
+
+		// don't start the next call once cancelled
+		if processCtx.Err() != nil {
+			<- slots
+			break
+		}
+
+		calls.Add(1)
+		go func(i int) {
+			defer calls.Done()
+			defer func() { <- slots }()
+
+			errs[i] = process(processCtx, i)
+
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+
+	calls.Wait()
+
+	// report cancellation itself, rather than the calls it interrupted
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, err := range errs {
+		// calls cancelled by another call failing report the failure rather than the cancellation
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Processes with the specified pipeline
-func ProcessWithPipeline[I any, O any](input I, pipeline PostProcessingPipeline[I, O], status *PipelineStatus) O {
-	
+func ProcessWithPipeline[I any, O any](ctx context.Context, input I, pipeline PostProcessingPipeline[I, O], status *PipelineStatus) (O, error) {
+
 	if status == nil {
 		status = &PipelineStatus{}
 	}
-	
-	output := pipeline.Process(input, status)
 
-	status.Step = ""
+	output, err := pipeline.Process(ctx, input, status)
+
+	status.finish()
+
+	if err != nil {
+		return output, wrapStageError(pipeline, err)
+	}
 
-	return output
+	return output, nil
 }
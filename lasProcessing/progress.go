@@ -0,0 +1,236 @@
+package lasProcessing
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress of a processor on its current chunk, safe to update and read from any goroutine
+type ChunkProgress struct {
+
+	// Bits of the progress on the chunk from 0% to 100% (0.0 - 1.0)
+	progress atomic.Uint64
+
+	// Number of points in the chunk, 0 once they have been counted as processed
+	points atomic.Int64
+
+}
+
+// Sets the progress on the current chunk from 0% to 100% (0.0 - 1.0)
+func(chunkProgress *ChunkProgress) Set(progress float64) {
+	chunkProgress.progress.Store(math.Float64bits(progress))
+}
+
+// Gets the progress on the current chunk from 0% to 100% (0.0 - 1.0)
+func(chunkProgress *ChunkProgress) Get() float64 {
+	return math.Float64frombits(chunkProgress.progress.Load())
+}
+
+// Snapshot of the progress of concurrent processing
+type ProgressEvent struct {
+
+	// Total number of chunks
+	TotalChunks int
+
+	// First chunk not being processed on (or TotalChunks if all are being processed on)
+	CurrentChunk int
+
+	// Number of chunks that have finished processing
+	CompletedChunks int
+
+	// Total number of concurrent processors
+	Concurrency int
+
+	// Progress of each processor on its current chunk from 0% to 100% (0.0 - 1.0)
+	ChunkProgress []float64
+
+	// Number of merges performed
+	Merges int
+
+	// Approximate number of points processed
+	Points int
+
+	// Total number of points to process
+	TotalPoints int
+
+	// Time since processing started
+	Elapsed time.Duration
+
+	// Points processed per second
+	PointsPerSecond float64
+
+	// Estimated time until all points are processed, 0 if unknown
+	ETA time.Duration
+
+	// Whether processing has finished
+	Done bool
+
+}
+
+// Status of concurrent processing, safe to update and read from any goroutine
+type ConcurrentStatus struct {
+
+	// Guards the processor progress and the start time
+	lock sync.Mutex
+
+	// Total number of chunks
+	totalChunks atomic.Int64
+
+	// Total number of points in all chunks
+	totalPoints atomic.Int64
+
+	// First chunk not being processed on
+	currentChunk atomic.Int64
+
+	// Number of chunks that have finished processing
+	completedChunks atomic.Int64
+
+	// Number of points in chunks that have finished processing
+	completedPoints atomic.Int64
+
+	// Number of merges performed
+	merges atomic.Int64
+
+	// Progress of each processor
+	chunkProgress []*ChunkProgress
+
+	// When processing started
+	started time.Time
+
+	// Whether processing has finished
+	done atomic.Bool
+
+	// Closed once processing finishes
+	finished chan struct{}
+
+}
+
+// Gets a new ConcurrentStatus
+func NewConcurrentStatus() *ConcurrentStatus {
+	return &ConcurrentStatus{finished: make(chan struct{})}
+}
+
+// Marks processing of the specified chunks as started, and gets the progress for each processor
+func(status *ConcurrentStatus) start(chunks []*LASChunk, concurrency int) []*ChunkProgress {
+	status.lock.Lock()
+	defer status.lock.Unlock()
+
+	status.totalChunks.Store(int64(len(chunks)))
+
+	totalPoints := 0
+
+	for _, chunk := range chunks {
+		totalPoints += chunk.End - chunk.Start
+	}
+
+	status.totalPoints.Store(int64(totalPoints))
+
+	status.chunkProgress = make([]*ChunkProgress, concurrency)
+
+	for i := range status.chunkProgress {
+		status.chunkProgress[i] = &ChunkProgress{}
+	}
+
+	status.started = time.Now()
+
+	return status.chunkProgress
+}
+
+// Marks a chunk as being processed by the processor with the specified progress
+func(status *ConcurrentStatus) startChunk(chunk *LASChunk, progress *ChunkProgress) {
+	progress.Set(0.0)
+	progress.points.Store(int64(chunk.End - chunk.Start))
+}
+
+// Marks a chunk as processed by the processor with the specified progress
+func(status *ConcurrentStatus) finishChunk(chunk *LASChunk, progress *ChunkProgress) {
+	status.completedPoints.Add(int64(chunk.End - chunk.Start))
+	status.completedChunks.Add(1)
+	progress.points.Store(0)
+}
+
+// Marks processing as finished
+func(status *ConcurrentStatus) finish() {
+	if !status.done.Swap(true) {
+		close(status.finished)
+	}
+}
+
+// Gets a snapshot of the status
+func(status *ConcurrentStatus) Snapshot() ProgressEvent {
+	status.lock.Lock()
+	defer status.lock.Unlock()
+
+	event := ProgressEvent{
+		TotalChunks: int(status.totalChunks.Load()),
+		CurrentChunk: int(status.currentChunk.Load()),
+		CompletedChunks: int(status.completedChunks.Load()),
+		Concurrency: len(status.chunkProgress),
+		ChunkProgress: make([]float64, len(status.chunkProgress)),
+		Merges: int(status.merges.Load()),
+		TotalPoints: int(status.totalPoints.Load()),
+		Done: status.done.Load()}
+
+	points := float64(status.completedPoints.Load())
+
+	// include the part of each chunk that has been processed so far
+	for i, progress := range status.chunkProgress {
+		event.ChunkProgress[i] = progress.Get()
+		points += event.ChunkProgress[i] * float64(progress.points.Load())
+	}
+
+	event.Points = int(points)
+
+	if !status.started.IsZero() {
+		event.Elapsed = time.Since(status.started)
+	}
+
+	if event.Elapsed > 0 {
+		event.PointsPerSecond = points / event.Elapsed.Seconds()
+	}
+
+	if event.PointsPerSecond > 0 && !event.Done {
+		remaining := float64(event.TotalPoints - event.Points) / event.PointsPerSecond
+		event.ETA = time.Duration(remaining * float64(time.Second))
+	}
+
+	return event
+}
+
+// Subscribes to the status, getting a snapshot every interval and a final snapshot once processing finishes.
+// The channel is closed after the final snapshot, and must be read until then.
+func(status *ConcurrentStatus) Subscribe(interval time.Duration) <-chan ProgressEvent {
+	return subscribe(interval, status.Snapshot, func(event ProgressEvent) bool { return event.Done }, status.finished)
+}
+
+// Sends snapshots over a channel every interval, or as soon as finished is closed, until a snapshot is done
+func subscribe[E any](interval time.Duration, snapshot func() E, done func(E) bool, finished <-chan struct{}) <-chan E {
+	events := make(chan E)
+
+	go func() {
+		defer close(events)
+
+		ticker := time.NewTicker(interval)
+
+		defer ticker.Stop()
+
+		for {
+			event := snapshot()
+
+			events <- event
+
+			if done(event) {
+				return
+			}
+
+			select {
+			case <- ticker.C:
+			case <- finished:
+			}
+		}
+	}()
+
+	return events
+}
@@ -0,0 +1,108 @@
+package lasProcessing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Counts the points of chunks without reading them, reporting progress point by point
+type countingProcessor struct {
+
+}
+
+// Counts the points of a chunk
+func(processor *countingProcessor) Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *LASChunk, status *ChunkProgress) (*int, error) {
+	count := 0
+
+	for i := chunk.Start; i < chunk.End; i++ {
+		if err := CheckCancelled(ctx, i - chunk.Start); err != nil {
+			return nil, err
+		}
+
+		count += 1
+		status.Set(float64(i - chunk.Start) / float64(chunk.End - chunk.Start))
+	}
+
+	status.Set(1.0)
+
+	return &count, nil
+}
+
+// Gets a count of 0
+func(processor *countingProcessor) EmptyOutput(inputFile *lidarioMod.LasFile) *int {
+	count := 0
+	return &count
+}
+
+// Adds two counts
+func(processor *countingProcessor) CombineOutput(base *int, incoming *int) *int {
+	*base += *incoming
+	return base
+}
+
+// Run with -race, the status is read by a subscriber and by direct snapshots while it is updated by every worker
+func TestConcurrentStatusWhileProcessing(t *testing.T) {
+	chunks := make([]*LASChunk, 0)
+
+	for start := 0; start < 200000; start += 1000 {
+		chunks = append(chunks, &LASChunk{Start: start, End: start + 1000})
+	}
+
+	status := NewConcurrentStatus()
+
+	events := status.Subscribe(time.Millisecond)
+
+	readers := sync.WaitGroup{}
+
+	var last ProgressEvent
+
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for event := range events {
+			for _, progress := range event.ChunkProgress {
+				if progress < 0 || progress > 1 {
+					t.Errorf("chunk progress %v is out of range", progress)
+				}
+			}
+			last = event
+		}
+	}()
+
+	stop := make(chan struct{})
+
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <- stop:
+				return
+			default:
+				status.Snapshot()
+			}
+		}
+	}()
+
+	count, err := ConcurrentProcess[int](context.Background(), &lidarioMod.LasFile{}, chunks, &countingProcessor{}, 8, status)
+
+	close(stop)
+
+	readers.Wait()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *count != 200000 {
+		t.Fatalf("counted %d points, expected 200000", *count)
+	}
+
+	if !last.Done || last.CompletedChunks != len(chunks) || last.Points != 200000 {
+		t.Fatalf("final event %+v is not of every chunk being done", last)
+	}
+}
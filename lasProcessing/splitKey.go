@@ -0,0 +1,148 @@
+package lasProcessing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Splits points into groups by a key read from their records
+type SplitKey interface {
+
+	// Name of the attribute the key is read from
+	Name() string
+
+	// Gets the key of a point
+	Key(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int
+
+	// Names the group of a key, for naming its outputs
+	Label(key int) string
+
+}
+
+// Splits points by point source ID, usually the flight line
+type SourceKey struct {
+
+}
+
+// Gets the name of the key
+func(key *SourceKey) Name() string {
+	return "source"
+}
+
+// Gets the point source of a point
+func(key *SourceKey) Key(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+	return ReadPointSource(inputFile, chunk, rawBytes, point)
+}
+
+// Names the group by the point source ID
+func(key *SourceKey) Label(value int) string {
+	return fmt.Sprint(value)
+}
+
+// Splits points by classification
+type ClassKey struct {
+
+}
+
+// Gets the name of the key
+func(key *ClassKey) Name() string {
+	return "class"
+}
+
+// Gets the classification of a point
+func(key *ClassKey) Key(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+	return ReadClassification(inputFile, chunk, rawBytes, point)
+}
+
+// Names the group by the classification
+func(key *ClassKey) Label(value int) string {
+	return fmt.Sprint(value)
+}
+
+// Splits points by return number
+type ReturnKey struct {
+
+}
+
+// Gets the name of the key
+func(key *ReturnKey) Name() string {
+	return "return"
+}
+
+// Gets the return number of a point
+func(key *ReturnKey) Key(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+	number, _ := ReadReturns(inputFile, chunk, rawBytes, point)
+
+	return number
+}
+
+// Names the group by the return number
+func(key *ReturnKey) Label(value int) string {
+	return fmt.Sprint(value)
+}
+
+// Splits points by user data
+type UserDataKey struct {
+
+}
+
+// Gets the name of the key
+func(key *UserDataKey) Name() string {
+	return "user-data"
+}
+
+// Gets the user data of a point
+func(key *UserDataKey) Key(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+	return ReadUserData(inputFile, chunk, rawBytes, point)
+}
+
+// Names the group by the user data
+func(key *UserDataKey) Label(value int) string {
+	return fmt.Sprint(value)
+}
+
+// Splits points by scanner channel, which only LAS 1.4 point formats (6-10) store
+type ChannelKey struct {
+
+}
+
+// Gets the name of the key
+func(key *ChannelKey) Name() string {
+	return "channel"
+}
+
+// Gets the scanner channel of a point
+func(key *ChannelKey) Key(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+	return ReadScannerChannel(inputFile, chunk, rawBytes, point)
+}
+
+// Names the group by the scanner channel
+func(key *ChannelKey) Label(value int) string {
+	return fmt.Sprint(value)
+}
+
+// Splits points into windows of GPS time of equal length, such as the passes of a flight
+type GPSTimeKey struct {
+
+	// Length of each window, in seconds
+	Window float64
+
+}
+
+// Gets the name of the key
+func(key *GPSTimeKey) Name() string {
+	return "gps-time"
+}
+
+// Gets the window the GPS time of a point is in, counted from GPS time 0
+func(key *GPSTimeKey) Key(inputFile *lidarioMod.LasFile, chunk *LASChunk, rawBytes []byte, point int) int {
+	return int(math.Floor(ReadGPSTime(inputFile, chunk, rawBytes, point) / key.Window))
+}
+
+// Names the group by the GPS time its window starts at
+func(key *GPSTimeKey) Label(value int) string {
+	return strconv.FormatFloat(float64(value) * key.Window, 'f', -1, 64)
+}
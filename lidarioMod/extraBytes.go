@@ -0,0 +1,211 @@
+package lidarioMod
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Length of an extra bytes descriptor in an Extra Bytes VLR
+const extraBytesDescriptorLength = 192
+
+// Names and sizes of the scalar extra bytes data types, by data type
+var extraBytesTypes = [11]struct {
+	name string
+	size int
+}{{"undocumented", 0}, {"uint8", 1}, {"int8", 1}, {"uint16", 2}, {"int16", 2}, {"uint32", 4}, {"int32", 4},
+	{"uint64", 8}, {"int64", 8}, {"float32", 4}, {"float64", 8}}
+
+// ExtraBytesAttribute describes an attribute stored in the extra bytes of the point records, from
+// a descriptor in the Extra Bytes VLR (LASF_Spec, record ID 4).
+type ExtraBytesAttribute struct {
+	// Name of the attribute
+	Name string `json:"name"`
+
+	// Description of the attribute
+	Description string `json:"description,omitempty"`
+
+	// Data type from the descriptor, 1-10 for scalars
+	DataType int `json:"data_type"`
+
+	// Name of the data type
+	TypeName string `json:"type"`
+
+	// Offset of the attribute from the start of a point record
+	Offset int `json:"offset"`
+
+	// Size of the attribute in bytes
+	Size int `json:"size"`
+
+	// Whether raw values equal to NoData mean the point has no value
+	HasNoData bool `json:"has_no_data"`
+
+	// Raw value for points without a value
+	NoData float64 `json:"no_data,omitempty"`
+
+	// Scale applied to raw values, 1 if the descriptor has none
+	Scale float64 `json:"scale"`
+
+	// Offset added to scaled values, 0 if the descriptor has none
+	ValueOffset float64 `json:"value_offset"`
+}
+
+// Scalar reports whether the attribute holds a single number that can be read.
+func (attribute *ExtraBytesAttribute) Scalar() bool {
+	return attribute.DataType >= 1 && attribute.DataType <= 10
+}
+
+// raw reads the raw value of a scalar attribute from a point record.
+func (attribute *ExtraBytesAttribute) raw(record []byte) float64 {
+	data := record[attribute.Offset:]
+
+	switch attribute.DataType {
+	case 1:
+		return float64(data[0])
+	case 2:
+		return float64(int8(data[0]))
+	case 3:
+		return float64(binary.LittleEndian.Uint16(data))
+	case 4:
+		return float64(int16(binary.LittleEndian.Uint16(data)))
+	case 5:
+		return float64(binary.LittleEndian.Uint32(data))
+	case 6:
+		return float64(int32(binary.LittleEndian.Uint32(data)))
+	case 7:
+		return float64(binary.LittleEndian.Uint64(data))
+	case 8:
+		return float64(int64(binary.LittleEndian.Uint64(data)))
+	case 9:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))
+	case 10:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data))
+	}
+
+	return 0
+}
+
+// Value reads the scaled value of the attribute from a point record, and whether the point has a value.
+// Attributes that are not scalars have no value.
+func (attribute *ExtraBytesAttribute) Value(record []byte) (float64, bool) {
+	if !attribute.Scalar() {
+		return 0, false
+	}
+
+	raw := attribute.raw(record)
+
+	if attribute.HasNoData && raw == attribute.NoData {
+		return 0, false
+	}
+
+	return raw*attribute.Scale + attribute.ValueOffset, true
+}
+
+// anyValue reads an 8 byte no data, min or max field of a descriptor in the data type of the attribute.
+func anyValue(dataType int, data []byte) float64 {
+	switch dataType {
+	case 1, 3, 5, 7:
+		return float64(binary.LittleEndian.Uint64(data))
+	case 2, 4, 6, 8:
+		return float64(int64(binary.LittleEndian.Uint64(data)))
+	case 9, 10:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data))
+	}
+
+	return 0
+}
+
+// ParseExtraBytes parses the descriptors of an Extra Bytes VLR into attributes laid out one after another
+// from the start of the extra bytes, at the specified offset in the point records. Undocumented extra bytes
+// and the deprecated array types take up space but are not scalars.
+func ParseExtraBytes(data []byte, start int) ([]ExtraBytesAttribute, error) {
+	if len(data)%extraBytesDescriptorLength != 0 {
+		return nil, fmt.Errorf("extra bytes VLR of %d bytes is not made of %d byte descriptors", len(data), extraBytesDescriptorLength)
+	}
+
+	attributes := make([]ExtraBytesAttribute, 0, len(data)/extraBytesDescriptorLength)
+
+	offset := start
+
+	for i := 0; i < len(data); i += extraBytesDescriptorLength {
+		descriptor := data[i : i+extraBytesDescriptorLength]
+
+		dataType, options := int(descriptor[2]), descriptor[3]
+
+		attribute := ExtraBytesAttribute{
+			Name:        strings.TrimRight(string(descriptor[4:36]), "\x00 "),
+			Description: strings.TrimRight(string(descriptor[160:192]), "\x00 "),
+			DataType:    dataType,
+			Offset:      offset,
+			Scale:       1,
+		}
+
+		switch {
+		case dataType == 0:
+			// the options hold the number of undocumented bytes
+			attribute.TypeName, attribute.Size = "undocumented", int(options)
+		case dataType <= 10:
+			attribute.TypeName, attribute.Size = extraBytesTypes[dataType].name, extraBytesTypes[dataType].size
+		case dataType <= 30:
+			// deprecated arrays of 2 (11-20) or 3 (21-30) of the scalar types
+			scalar, count := (dataType-1)%10+1, (dataType-1)/10+1
+			attribute.TypeName = fmt.Sprintf("%s[%d]", extraBytesTypes[scalar].name, count)
+			attribute.Size = extraBytesTypes[scalar].size * count
+		default:
+			return nil, fmt.Errorf("extra bytes attribute %q has unknown data type %d", attribute.Name, dataType)
+		}
+
+		if attribute.Scalar() {
+			if options&1 != 0 {
+				attribute.HasNoData, attribute.NoData = true, anyValue(dataType, descriptor[40:48])
+			}
+
+			if options&8 != 0 {
+				attribute.Scale = math.Float64frombits(binary.LittleEndian.Uint64(descriptor[112:120]))
+			}
+
+			if options&16 != 0 {
+				attribute.ValueOffset = math.Float64frombits(binary.LittleEndian.Uint64(descriptor[136:144]))
+			}
+		}
+
+		attributes = append(attributes, attribute)
+
+		offset += attribute.Size
+	}
+
+	return attributes, nil
+}
+
+// readExtraBytes adds the attributes of the Extra Bytes VLR, or EVLR, to the layout of the point records.
+func (las *LasFile) readExtraBytes() error {
+	for _, vlr := range append(append([]VLR{}, las.VlrData...), las.EvlrData...) {
+		if vlr.UserID != "LASF_Spec" || vlr.RecordID != 4 {
+			continue
+		}
+
+		attributes, err := ParseExtraBytes(vlr.BinaryData, las.pointLayout.ExtraBytes)
+
+		if err != nil {
+			return err
+		}
+
+		described := 0
+
+		for _, attribute := range attributes {
+			described += attribute.Size
+		}
+
+		if described > las.pointLayout.ExtraBytesLength {
+			return fmt.Errorf("extra bytes VLR describes %d bytes, but point records have %d extra bytes",
+				described, las.pointLayout.ExtraBytesLength)
+		}
+
+		las.pointLayout.ExtraAttributes = attributes
+
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package lidarioMod
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// extraBytesDescriptor builds a 192 byte descriptor of an Extra Bytes VLR. The no data value is stored
+// as raw bits, as it is an unsigned, signed or floating point number depending on the data type.
+func extraBytesDescriptor(dataType byte, options byte, name string, noData uint64, scale float64, offset float64, description string) []byte {
+	descriptor := make([]byte, extraBytesDescriptorLength)
+	descriptor[2], descriptor[3] = dataType, options
+	copy(descriptor[4:36], name)
+	binary.LittleEndian.PutUint64(descriptor[40:], noData)
+	binary.LittleEndian.PutUint64(descriptor[112:], math.Float64bits(scale))
+	binary.LittleEndian.PutUint64(descriptor[136:], math.Float64bits(offset))
+	copy(descriptor[160:192], description)
+	return descriptor
+}
+
+// A VLR of mixed types after 20 bytes of standard fields, laid out as
+//
+//	20 Confidence uint8, no data 255
+//	21 Reflectance int16, scaled by 0.01 and offset by -10
+//	23 3 undocumented bytes
+//	26 Range float64, no data -1, with a scale that is ignored without its option
+//	34 Pair of deprecated int32[2]
+//	42 Count uint32
+//	46
+func mixedExtraBytes() []byte {
+	return bytes.Join([][]byte{
+		extraBytesDescriptor(1, 1, "Confidence", 255, 0, 0, "confidence of the point"),
+		extraBytesDescriptor(4, 8|16, "Reflectance", 0, 0.01, -10, "reflectance in dB"),
+		extraBytesDescriptor(0, 3, "", 0, 0, 0, ""),
+		extraBytesDescriptor(10, 1, "Range", math.Float64bits(-1), 1000, 0, "range to the scanner"),
+		extraBytesDescriptor(16, 0, "Pair", 0, 0, 0, ""),
+		extraBytesDescriptor(5, 0, "Count", 0, 0, 0, ""),
+	}, nil)
+}
+
+func TestParseExtraBytes(t *testing.T) {
+	attributes, err := ParseExtraBytes(mixedExtraBytes(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ExtraBytesAttribute{
+		{Name: "Confidence", Description: "confidence of the point", DataType: 1, TypeName: "uint8", Offset: 20, Size: 1,
+			HasNoData: true, NoData: 255, Scale: 1},
+		{Name: "Reflectance", Description: "reflectance in dB", DataType: 4, TypeName: "int16", Offset: 21, Size: 2,
+			Scale: 0.01, ValueOffset: -10},
+		{DataType: 0, TypeName: "undocumented", Offset: 23, Size: 3, Scale: 1},
+		{Name: "Range", Description: "range to the scanner", DataType: 10, TypeName: "float64", Offset: 26, Size: 8,
+			HasNoData: true, NoData: -1, Scale: 1},
+		{Name: "Pair", DataType: 16, TypeName: "int32[2]", Offset: 34, Size: 8, Scale: 1},
+		{Name: "Count", DataType: 5, TypeName: "uint32", Offset: 42, Size: 4, Scale: 1},
+	}
+
+	if len(attributes) != len(expected) {
+		t.Fatalf("parsed %v attributes, expected %v", len(attributes), len(expected))
+	}
+	for i := range expected {
+		if attributes[i] != expected[i] {
+			t.Fatalf("attribute %v parsed as %+v, expected %+v", i, attributes[i], expected[i])
+		}
+	}
+
+	layout := &PointLayout{ExtraAttributes: attributes}
+
+	if attribute := layout.ExtraAttribute("Reflectance"); attribute == nil || attribute.Offset != 21 {
+		t.Fatalf("found Reflectance as %+v, expected the attribute at offset 21", attribute)
+	}
+	if attribute := layout.ExtraAttribute("Intensity"); attribute != nil {
+		t.Fatalf("found an attribute of an unknown name, %+v", attribute)
+	}
+}
+
+func TestExtraBytesValues(t *testing.T) {
+	attributes, err := ParseExtraBytes(mixedExtraBytes(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layout := &PointLayout{ExtraAttributes: attributes}
+
+	// a record with a value of each attribute, then one of no data
+	record := make([]byte, 46)
+	record[20] = 200
+	binary.LittleEndian.PutUint16(record[21:], uint16(0xFFFF&-1234))
+	binary.LittleEndian.PutUint64(record[26:], math.Float64bits(12.5))
+	binary.LittleEndian.PutUint32(record[42:], 4000000000)
+
+	noData := make([]byte, 46)
+	noData[20] = 255
+	binary.LittleEndian.PutUint64(noData[26:], math.Float64bits(-1))
+
+	for _, c := range []struct {
+		record []byte
+		name   string
+		value  float64
+		ok     bool
+	}{
+		{record, "Confidence", 200, true},
+		{record, "Reflectance", -1234*0.01 - 10, true},
+		{record, "Range", 12.5, true},
+		{record, "Count", 4000000000, true},
+		{record, "Pair", 0, false},
+		{noData, "Confidence", 0, false},
+		{noData, "Reflectance", -10, true},
+		{noData, "Range", 0, false},
+	} {
+		attribute := layout.ExtraAttribute(c.name)
+
+		if value, ok := attribute.Value(c.record); ok != c.ok || math.Abs(value-c.value) > 1e-9 {
+			t.Fatalf("%v read as %v (%v), expected %v (%v)", c.name, value, ok, c.value, c.ok)
+		}
+	}
+
+	if attributes[2].Scalar() {
+		t.Fatal("undocumented extra bytes are a scalar")
+	}
+	if value, ok := attributes[2].Value(record); ok {
+		t.Fatalf("undocumented extra bytes read as %v", value)
+	}
+}
+
+func TestParseExtraBytesRejectsInvalidDescriptors(t *testing.T) {
+	if _, err := ParseExtraBytes(make([]byte, extraBytesDescriptorLength+1), 20); err == nil {
+		t.Fatal("parsed a VLR that is not made of whole descriptors")
+	}
+
+	if _, err := ParseExtraBytes(extraBytesDescriptor(31, 0, "Vector", 0, 0, 0, ""), 20); err == nil {
+		t.Fatal("parsed a descriptor of an unknown data type")
+	}
+}
+
+// extrabytes.las (format 1) describes its 3 extra bytes as Reflectance, an int16 of point i of
+// ((i*37)%2000-1000) scaled by 0.01, and Confidence, a uint8 of i%100 that every seventh point has no value of
+func TestReadExtraBytesOfFile(t *testing.T) {
+	las := openTestFile(t, "extrabytes.las")
+
+	layout := las.PointLayout()
+
+	if layout.ExtraBytes != 28 || layout.ExtraBytesLength != 3 || len(layout.ExtraAttributes) != 2 {
+		t.Fatalf("extrabytes.las has %v extra bytes at %v with %v attributes, expected 3 at 28 with 2",
+			layout.ExtraBytesLength, layout.ExtraBytes, len(layout.ExtraAttributes))
+	}
+
+	records, err := las.ReadPointRecords(0, las.Header.NumberPoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reflectance, confidence := layout.ExtraAttribute("Reflectance"), layout.ExtraAttribute("Confidence")
+
+	for i := 0; i < las.Header.NumberPoints; i++ {
+		record := records[i*layout.RecordLength : (i+1)*layout.RecordLength]
+
+		if value, ok := reflectance.Value(record); !ok || math.Abs(value-float64((i*37)%2000-1000)*0.01) > 1e-9 {
+			t.Fatalf("point %v has a reflectance of %v (%v), expected %v", i, value, ok, float64((i*37)%2000-1000)*0.01)
+		}
+
+		if value, ok := confidence.Value(record); ok != (i%7 != 0) || (ok && value != float64(i%100)) {
+			t.Fatalf("point %v has a confidence of %v (%v), expected %v (%v)", i, value, ok, i%100, i%7 != 0)
+		}
+	}
+}
+
+// A VLR describing more bytes than the records have must fail on open
+func TestExtraBytesLongerThanRecordsAreRejected(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "extrabytes.las"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Confidence as a uint32 describes 6 bytes of the 3
+	name := bytes.Index(data, []byte("Confidence"))
+	if name < 0 {
+		t.Fatal("extrabytes.las has no Confidence attribute")
+	}
+	data[name-2] = 5
+
+	fileName := filepath.Join(t.TempDir(), "long.las")
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	las, err := NewLasFile(fileName, "rh")
+	if err == nil {
+		las.Close()
+		t.Fatal("opening a file with more extra bytes described than stored succeeded")
+	}
+	if !strings.Contains(err.Error(), "extra bytes") {
+		t.Fatalf("opening the file failed with %q, expected it to name the extra bytes", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 )
 
@@ -2477,3 +2478,65 @@ var verticalCSTypeMap = map[uint]string{
 	5105: "VertCS_Baltic_Sea",
 	5106: "VertCS_Caspian_Sea",
 }
+
+// GeoKey is a key of a GeoKey directory, with its value decoded to text.
+type GeoKey struct {
+	Code  int    `json:"code"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Decode returns the keys of the GeoKey directory in order, naming the keys and
+// short values that are known. Unlike PrintGeokeys, unknown keys and references
+// outside the parameters are reported rather than panicking.
+func (gk *GeoKeys) Decode() []GeoKey {
+	if len(gk.GeoKeyDirectory) < 4 {
+		return nil
+	}
+
+	numKeys := int(gk.GeoKeyDirectory[3])
+
+	keys := make([]GeoKey, 0, numKeys)
+	for i := 1; i <= numKeys && 4*i+3 < len(gk.GeoKeyDirectory); i++ {
+		code := int(gk.GeoKeyDirectory[4*i])
+		location := gk.GeoKeyDirectory[4*i+1]
+		count := int(gk.GeoKeyDirectory[4*i+2])
+		valueOffset := int(gk.GeoKeyDirectory[4*i+3])
+
+		key := GeoKey{Code: code, Name: fmt.Sprintf("Unknown%d", code)}
+		if tag, ok := tagMap[code]; ok {
+			key.Name = tag.Name
+		}
+
+		switch location {
+		case 0:
+			key.Value = fmt.Sprint(valueOffset)
+			if names, ok := keywordMap[code]; ok {
+				if name, ok := names[uint(valueOffset)]; ok {
+					key.Value = fmt.Sprintf("%d (%s)", valueOffset, name)
+				}
+			}
+		case 34736:
+			if valueOffset+count > len(gk.GeoDoubleParams) {
+				key.Value = "invalid double parameter reference"
+			} else {
+				values := make([]string, count)
+				for j, value := range gk.GeoDoubleParams[valueOffset : valueOffset+count] {
+					values[j] = strconv.FormatFloat(value, 'g', -1, 64)
+				}
+				key.Value = strings.Join(values, ", ")
+			}
+		case 34737:
+			if valueOffset+count > len(gk.GeoASCIIParams) {
+				key.Value = "invalid ASCII parameter reference"
+			} else {
+				key.Value = strings.TrimRight(gk.GeoASCIIParams[valueOffset:valueOffset+count], "|\x00")
+			}
+		default:
+			key.Value = fmt.Sprintf("stored in tag %d", location)
+		}
+
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,322 @@
+package lidarioMod
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// LASzip compressor types
+const (
+	lazCompressorNone             = 0
+	lazCompressorPointwise        = 1
+	lazCompressorPointwiseChunked = 2
+	lazCompressorLayeredChunked   = 3
+)
+
+// lazVariableChunkSize marks a chunk table storing the point count of every chunk
+const lazVariableChunkSize = 0xFFFFFFFF
+
+// LaszipItem describes one group of fields in a compressed point record
+type LaszipItem struct {
+	Type    uint16
+	Size    uint16
+	Version uint16
+}
+
+// LaszipVLR is the content of the "laszip encoded" VLR that describes how points are compressed
+type LaszipVLR struct {
+	Compressor   uint16
+	Coder        uint16
+	VersionMajor uint8
+	VersionMinor uint8
+	Revision     uint16
+	Options      uint32
+	ChunkSize    uint32
+	Items        []LaszipItem
+}
+
+// LazChunk is an independently decodable run of compressed points
+type LazChunk struct {
+	// First point in the chunk
+	PointStart int
+	// Number of points in the chunk
+	PointCount int
+	// Offset of the compressed chunk in the file
+	Offset int64
+	// Number of compressed bytes in the chunk
+	ByteCount int64
+}
+
+// parseLaszipVLR parses the binary data of a LASzip VLR
+func parseLaszipVLR(data []byte) (*LaszipVLR, error) {
+	if len(data) < 34 {
+		return nil, errors.New("LASzip VLR is too short")
+	}
+	v := LaszipVLR{}
+	v.Compressor = binary.LittleEndian.Uint16(data[0:2])
+	v.Coder = binary.LittleEndian.Uint16(data[2:4])
+	v.VersionMajor = data[4]
+	v.VersionMinor = data[5]
+	v.Revision = binary.LittleEndian.Uint16(data[6:8])
+	v.Options = binary.LittleEndian.Uint32(data[8:12])
+	v.ChunkSize = binary.LittleEndian.Uint32(data[12:16])
+	// bytes 16-32 hold the unused special EVLR count and offset
+	numItems := int(binary.LittleEndian.Uint16(data[32:34]))
+	if len(data) < 34+6*numItems {
+		return nil, errors.New("LASzip VLR item list is truncated")
+	}
+	offset := 34
+	for i := 0; i < numItems; i++ {
+		item := LaszipItem{}
+		item.Type = binary.LittleEndian.Uint16(data[offset : offset+2])
+		item.Size = binary.LittleEndian.Uint16(data[offset+2 : offset+4])
+		item.Version = binary.LittleEndian.Uint16(data[offset+4 : offset+6])
+		v.Items = append(v.Items, item)
+		offset += 6
+	}
+	return &v, nil
+}
+
+// IsCompressed returns whether the point data of the file is LAZ compressed
+func (las *LasFile) IsCompressed() bool {
+	return las.laszip != nil
+}
+
+// LazChunks returns the compressed chunks of a LAZ file, or nil if the file is not compressed
+func (las *LasFile) LazChunks() []LazChunk {
+	return las.lazChunks
+}
+
+// readLaszip finds the LASzip VLR and reads the chunk table of a compressed file
+func (las *LasFile) readLaszip() error {
+	las.Lock()
+	defer las.Unlock()
+
+	for _, vlr := range las.VlrData {
+		if vlr.UserID == "laszip encoded" && vlr.RecordID == 22204 {
+			laszip, err := parseLaszipVLR(vlr.BinaryData)
+			if err != nil {
+				return err
+			}
+			las.laszip = laszip
+		}
+	}
+
+	if las.laszip == nil {
+		if las.compressedFlag {
+			return errors.New("the point format is flagged as compressed but there is no LASzip VLR")
+		}
+		return nil
+	}
+
+	if las.laszip.Coder != 0 {
+		return fmt.Errorf("unsupported LASzip coder %v", las.laszip.Coder)
+	}
+
+	switch las.laszip.Compressor {
+	case lazCompressorPointwise:
+		// a single chunk spanning all of the point data
+		end, err := las.pointDataEnd()
+		if err != nil {
+			return err
+		}
+		start := int64(las.Header.OffsetToPoints)
+		las.lazChunks = []LazChunk{{PointStart: 0, PointCount: las.Header.NumberPoints, Offset: start, ByteCount: end - start}}
+		return nil
+	case lazCompressorPointwiseChunked:
+		return las.readLazChunkTable()
+	case lazCompressorLayeredChunked:
+		return fmt.Errorf("layered LAZ compression of point formats 6-10 (this file has point format %v) is not supported, "+
+			"decompress the file to LAS first", las.Header.PointFormatID)
+	default:
+		return fmt.Errorf("unsupported LASzip compressor %v", las.laszip.Compressor)
+	}
+}
+
+// pointDataEnd returns the offset of the end of the point data
+func (las *LasFile) pointDataEnd() (int64, error) {
+	if las.Header.StartOfFirstEVLR != 0 {
+		return int64(las.Header.StartOfFirstEVLR), nil
+	}
+	info, err := las.RawFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// readLazChunkTable reads the compressed table of chunk sizes
+func (las *LasFile) readLazChunkTable() error {
+	b := make([]byte, 8)
+	if _, err := las.RawFile.ReadAt(b, int64(las.Header.OffsetToPoints)); err != nil {
+		return fmt.Errorf("reading LAZ chunk table offset: %w", err)
+	}
+	tableOffset := int64(binary.LittleEndian.Uint64(b))
+
+	if tableOffset == -1 {
+		// the table offset was written at the end of the file by a streaming writer
+		info, err := las.RawFile.Stat()
+		if err != nil {
+			return err
+		}
+		if _, err := las.RawFile.ReadAt(b, info.Size()-8); err != nil {
+			return fmt.Errorf("reading LAZ chunk table offset: %w", err)
+		}
+		tableOffset = int64(binary.LittleEndian.Uint64(b))
+	}
+
+	end, err := las.pointDataEnd()
+	if err != nil {
+		return err
+	}
+	if tableOffset <= int64(las.Header.OffsetToPoints) || tableOffset > end {
+		return errors.New("the LAZ chunk table offset is invalid")
+	}
+
+	b = make([]byte, end-tableOffset)
+	if _, err := las.RawFile.ReadAt(b, tableOffset); err != nil && err != io.EOF {
+		return fmt.Errorf("reading LAZ chunk table: %w", err)
+	}
+	if len(b) < 8 {
+		return errors.New("the LAZ chunk table is truncated")
+	}
+	numChunks := int(binary.LittleEndian.Uint32(b[4:8]))
+
+	variable := las.laszip.ChunkSize == lazVariableChunkSize
+	dec := newArithmeticDecoder(b, 8)
+	dec.init()
+	ic := newIntegerDecompressor(32, 2)
+
+	las.lazChunks = make([]LazChunk, 0, numChunks)
+	offset := int64(las.Header.OffsetToPoints) + 8
+	pointStart := 0
+	var prevPoints, prevBytes int32
+	for i := 0; i < numChunks; i++ {
+		pointCount := int(las.laszip.ChunkSize)
+		if variable {
+			prevPoints = ic.decompress(dec, prevPoints, 0)
+			pointCount = int(uint32(prevPoints))
+		}
+		prevBytes = ic.decompress(dec, prevBytes, 1)
+		byteCount := int64(uint32(prevBytes))
+
+		if pointStart+pointCount > las.Header.NumberPoints {
+			pointCount = las.Header.NumberPoints - pointStart
+		}
+
+		las.lazChunks = append(las.lazChunks, LazChunk{PointStart: pointStart, PointCount: pointCount, Offset: offset, ByteCount: byteCount})
+		pointStart += pointCount
+		offset += byteCount
+	}
+
+	if dec.overrun() {
+		return errors.New("the LAZ chunk table is truncated")
+	}
+	if pointStart < las.Header.NumberPoints {
+		return fmt.Errorf("the LAZ chunk table covers %v of %v points", pointStart, las.Header.NumberPoints)
+	}
+
+	return nil
+}
+
+// decompressChunk decodes points [start, end) of a compressed chunk into raw point records
+func (las *LasFile) decompressChunk(chunk LazChunk, start, end int, output []byte) error {
+	recordLength := las.Header.PointRecordLength
+
+	decoders := make([]lazItemDecoder, len(las.laszip.Items))
+	itemTotal := 0
+	for i, item := range las.laszip.Items {
+		decoder, err := newLazItemDecoder(item)
+		if err != nil {
+			return err
+		}
+		decoders[i] = decoder
+		itemTotal += int(item.Size)
+	}
+	if itemTotal != recordLength {
+		return fmt.Errorf("LAZ items total %v bytes but point records are %v bytes", itemTotal, recordLength)
+	}
+
+	data := make([]byte, chunk.ByteCount)
+	if _, err := las.RawFile.ReadAt(data, chunk.Offset); err != nil && err != io.EOF {
+		return err
+	}
+	if len(data) < recordLength {
+		return errors.New("compressed chunk is truncated")
+	}
+
+	record := make([]byte, recordLength)
+
+	// the first point of each chunk is stored raw
+	copy(record, data[:recordLength])
+	offset := 0
+	for i, item := range las.laszip.Items {
+		decoders[i].init(record[offset : offset+int(item.Size)])
+		offset += int(item.Size)
+	}
+
+	dec := newArithmeticDecoder(data, recordLength)
+	dec.init()
+
+	for point := chunk.PointStart; point < end; point++ {
+		if point > chunk.PointStart {
+			offset = 0
+			for i, item := range las.laszip.Items {
+				decoders[i].decompress(dec, record[offset:offset+int(item.Size)])
+				offset += int(item.Size)
+			}
+		}
+		if point >= start {
+			copy(output[(point-start)*recordLength:], record)
+		}
+	}
+
+	if dec.overrun() {
+		return fmt.Errorf("compressed chunk at offset %v is truncated", chunk.Offset)
+	}
+
+	return nil
+}
+
+// ReadPointRecords reads points [start, end) into raw point records, decompressing them if required.
+// This only uses ReadAt on the underlying file, so it is safe to call concurrently.
+func (las *LasFile) ReadPointRecords(start, end int) ([]byte, error) {
+	recordLength := las.Header.PointRecordLength
+	output := make([]byte, (end-start)*recordLength)
+
+	if !las.IsCompressed() {
+		offset := int64(las.Header.OffsetToPoints) + int64(start)*int64(recordLength)
+		n, err := las.RawFile.ReadAt(output, offset)
+		if n < len(output) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("reading points [%v, %v): %w", start, end, err)
+		}
+		return output, nil
+	}
+
+	for _, chunk := range las.lazChunks {
+		chunkEnd := chunk.PointStart + chunk.PointCount
+		if chunkEnd <= start || chunk.PointStart >= end {
+			continue
+		}
+
+		decodeStart, decodeEnd := start, end
+		if decodeStart < chunk.PointStart {
+			decodeStart = chunk.PointStart
+		}
+		if decodeEnd > chunkEnd {
+			decodeEnd = chunkEnd
+		}
+
+		err := las.decompressChunk(chunk, decodeStart, decodeEnd, output[(decodeStart-start)*recordLength:])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
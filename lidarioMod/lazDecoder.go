@@ -0,0 +1,400 @@
+package lidarioMod
+
+// Entropy decoding used by LASzip compressed point data. This is a port of the
+// arithmetic decoder, models and integer decompressor from LASzip (LGPL) by
+// Martin Isenburg, which itself is based on the coder by Amir Said.
+
+const (
+	acMinLength = uint32(0x01000000)
+	acMaxLength = uint32(0xFFFFFFFF)
+
+	bmLengthShift = 13
+	bmMaxCount    = uint32(1) << bmLengthShift
+
+	dmLengthShift = 15
+	dmMaxCount    = uint32(1) << dmLengthShift
+)
+
+// arithmeticDecoder decodes symbols and raw bits from a compressed byte stream
+type arithmeticDecoder struct {
+	data   []byte
+	pos    int
+	value  uint32
+	length uint32
+}
+
+// newArithmeticDecoder creates a decoder reading from data, starting at the given position
+func newArithmeticDecoder(data []byte, pos int) *arithmeticDecoder {
+	return &arithmeticDecoder{data: data, pos: pos}
+}
+
+// getByte returns the next byte of the stream, or zero once the stream is exhausted
+func (dec *arithmeticDecoder) getByte() uint32 {
+	if dec.pos >= len(dec.data) {
+		dec.pos++
+		return 0
+	}
+	b := dec.data[dec.pos]
+	dec.pos++
+	return uint32(b)
+}
+
+// init reads the first four bytes of the stream into the decoder state
+func (dec *arithmeticDecoder) init() {
+	dec.length = acMaxLength
+	dec.value = dec.getByte() << 24
+	dec.value |= dec.getByte() << 16
+	dec.value |= dec.getByte() << 8
+	dec.value |= dec.getByte()
+}
+
+// overrun returns whether the decoder has read past the end of its data
+func (dec *arithmeticDecoder) overrun() bool {
+	return dec.pos > len(dec.data)
+}
+
+func (dec *arithmeticDecoder) renormalize() {
+	for {
+		dec.value = (dec.value << 8) | dec.getByte()
+		dec.length <<= 8
+		if dec.length >= acMinLength {
+			break
+		}
+	}
+}
+
+func (dec *arithmeticDecoder) decodeBit(m *arithmeticBitModel) uint32 {
+	x := m.bit0Prob * (dec.length >> bmLengthShift)
+	var sym uint32
+	if dec.value < x {
+		dec.length = x
+		m.bit0Count++
+	} else {
+		sym = 1
+		dec.value -= x
+		dec.length -= x
+	}
+
+	if dec.length < acMinLength {
+		dec.renormalize()
+	}
+	m.bitsUntilUpdate--
+	if m.bitsUntilUpdate == 0 {
+		m.update()
+	}
+	return sym
+}
+
+func (dec *arithmeticDecoder) decodeSymbol(m *arithmeticModel) uint32 {
+	var n, sym, x uint32
+	y := dec.length
+
+	if m.decoderTable != nil {
+		dec.length >>= dmLengthShift
+		dv := dec.value / dec.length
+		t := dv >> m.tableShift
+
+		sym = m.decoderTable[t]
+		n = m.decoderTable[t+1] + 1
+
+		for n > sym+1 {
+			k := (sym + n) >> 1
+			if m.distribution[k] > dv {
+				n = k
+			} else {
+				sym = k
+			}
+		}
+
+		x = m.distribution[sym] * dec.length
+		if sym != m.lastSymbol {
+			y = m.distribution[sym+1] * dec.length
+		}
+	} else {
+		dec.length >>= dmLengthShift
+		n = m.symbols
+		k := n >> 1
+		for {
+			z := dec.length * m.distribution[k]
+			if z > dec.value {
+				n = k
+				y = z
+			} else {
+				sym = k
+				x = z
+			}
+			k = (sym + n) >> 1
+			if k == sym {
+				break
+			}
+		}
+	}
+
+	dec.value -= x
+	dec.length = y - x
+
+	if dec.length < acMinLength {
+		dec.renormalize()
+	}
+
+	m.symbolCount[sym]++
+	m.symbolsUntilUpdate--
+	if m.symbolsUntilUpdate == 0 {
+		m.update()
+	}
+	return sym
+}
+
+func (dec *arithmeticDecoder) readBits(bits uint32) uint32 {
+	if bits > 19 {
+		lower := dec.readShort()
+		bits -= 16
+		upper := dec.readBits(bits) << 16
+		return upper | lower
+	}
+
+	dec.length >>= bits
+	sym := dec.value / dec.length
+	dec.value -= dec.length * sym
+
+	if dec.length < acMinLength {
+		dec.renormalize()
+	}
+	return sym
+}
+
+func (dec *arithmeticDecoder) readShort() uint32 {
+	dec.length >>= 16
+	sym := dec.value / dec.length
+	dec.value -= dec.length * sym
+
+	if dec.length < acMinLength {
+		dec.renormalize()
+	}
+	return sym
+}
+
+func (dec *arithmeticDecoder) readInt() uint32 {
+	lower := dec.readShort()
+	upper := dec.readShort()
+	return (upper << 16) | lower
+}
+
+func (dec *arithmeticDecoder) readInt64() uint64 {
+	lower := uint64(dec.readInt())
+	upper := uint64(dec.readInt())
+	return (upper << 32) | lower
+}
+
+// arithmeticModel is an adaptive model over a fixed number of symbols
+type arithmeticModel struct {
+	symbols            uint32
+	lastSymbol         uint32
+	tableShift         uint32
+	tableSize          uint32
+	totalCount         uint32
+	updateCycle        uint32
+	symbolsUntilUpdate uint32
+	distribution       []uint32
+	symbolCount        []uint32
+	decoderTable       []uint32
+}
+
+// newArithmeticModel creates and initializes a model for decoding the given number of symbols
+func newArithmeticModel(symbols uint32) *arithmeticModel {
+	m := &arithmeticModel{symbols: symbols, lastSymbol: symbols - 1}
+	if symbols > 16 {
+		tableBits := uint32(3)
+		for symbols > (uint32(1) << (tableBits + 2)) {
+			tableBits++
+		}
+		m.tableSize = uint32(1) << tableBits
+		m.tableShift = dmLengthShift - tableBits
+		m.decoderTable = make([]uint32, m.tableSize+2)
+	}
+	m.distribution = make([]uint32, symbols)
+	m.symbolCount = make([]uint32, symbols)
+	for k := range m.symbolCount {
+		m.symbolCount[k] = 1
+	}
+	m.updateCycle = symbols
+	m.update()
+	m.updateCycle = (symbols + 6) >> 1
+	m.symbolsUntilUpdate = m.updateCycle
+	return m
+}
+
+func (m *arithmeticModel) update() {
+	m.totalCount += m.updateCycle
+	if m.totalCount > dmMaxCount {
+		m.totalCount = 0
+		for n := uint32(0); n < m.symbols; n++ {
+			m.symbolCount[n] = (m.symbolCount[n] + 1) >> 1
+			m.totalCount += m.symbolCount[n]
+		}
+	}
+
+	sum := uint32(0)
+	scale := uint32(0x80000000) / m.totalCount
+
+	if m.tableSize == 0 {
+		for k := uint32(0); k < m.symbols; k++ {
+			m.distribution[k] = (scale * sum) >> (31 - dmLengthShift)
+			sum += m.symbolCount[k]
+		}
+	} else {
+		s := uint32(0)
+		for k := uint32(0); k < m.symbols; k++ {
+			m.distribution[k] = (scale * sum) >> (31 - dmLengthShift)
+			sum += m.symbolCount[k]
+			w := m.distribution[k] >> m.tableShift
+			for s < w {
+				s++
+				m.decoderTable[s] = k - 1
+			}
+		}
+		m.decoderTable[0] = 0
+		for s <= m.tableSize {
+			s++
+			m.decoderTable[s] = m.symbols - 1
+		}
+	}
+
+	m.updateCycle = (5 * m.updateCycle) >> 2
+	maxCycle := (m.symbols + 6) << 3
+	if m.updateCycle > maxCycle {
+		m.updateCycle = maxCycle
+	}
+	m.symbolsUntilUpdate = m.updateCycle
+}
+
+// arithmeticBitModel is an adaptive model over a single bit
+type arithmeticBitModel struct {
+	bit0Count       uint32
+	bitCount        uint32
+	bit0Prob        uint32
+	bitsUntilUpdate uint32
+	updateCycle     uint32
+}
+
+// newArithmeticBitModel creates and initializes a bit model
+func newArithmeticBitModel() *arithmeticBitModel {
+	return &arithmeticBitModel{
+		bit0Count:       1,
+		bitCount:        2,
+		bit0Prob:        uint32(1) << (bmLengthShift - 1),
+		updateCycle:     4,
+		bitsUntilUpdate: 4,
+	}
+}
+
+func (m *arithmeticBitModel) update() {
+	m.bitCount += m.updateCycle
+	if m.bitCount > bmMaxCount {
+		m.bitCount = (m.bitCount + 1) >> 1
+		m.bit0Count = (m.bit0Count + 1) >> 1
+		if m.bit0Count == m.bitCount {
+			m.bitCount++
+		}
+	}
+
+	scale := uint32(0x80000000) / m.bitCount
+	m.bit0Prob = (m.bit0Count * scale) >> (31 - bmLengthShift)
+
+	m.updateCycle = (5 * m.updateCycle) >> 2
+	if m.updateCycle > 64 {
+		m.updateCycle = 64
+	}
+	m.bitsUntilUpdate = m.updateCycle
+}
+
+// integerDecompressor decodes integers predicted from a previous value
+type integerDecompressor struct {
+	k           uint32
+	bitsHigh    uint32
+	corrBits    uint32
+	corrRange   uint32
+	corrMin     int32
+	mBits       []*arithmeticModel
+	mCorrector0 *arithmeticBitModel
+	mCorrector  []*arithmeticModel
+}
+
+// newIntegerDecompressor creates a decompressor for integers of the given bit width,
+// with the given number of contexts
+func newIntegerDecompressor(bits uint32, contexts uint32) *integerDecompressor {
+	ic := &integerDecompressor{bitsHigh: 8}
+
+	if bits > 0 && bits < 32 {
+		ic.corrBits = bits
+		ic.corrRange = uint32(1) << bits
+		ic.corrMin = -int32(ic.corrRange / 2)
+	} else {
+		ic.corrBits = 32
+		ic.corrRange = 0
+		ic.corrMin = -2147483648
+	}
+
+	ic.mBits = make([]*arithmeticModel, contexts)
+	for i := range ic.mBits {
+		ic.mBits[i] = newArithmeticModel(ic.corrBits + 1)
+	}
+
+	ic.mCorrector0 = newArithmeticBitModel()
+	ic.mCorrector = make([]*arithmeticModel, ic.corrBits+1)
+	for i := uint32(1); i <= ic.corrBits; i++ {
+		if i <= ic.bitsHigh {
+			ic.mCorrector[i] = newArithmeticModel(uint32(1) << i)
+		} else {
+			ic.mCorrector[i] = newArithmeticModel(uint32(1) << ic.bitsHigh)
+		}
+	}
+
+	return ic
+}
+
+// getK returns the number of corrector bits used by the last decompressed value
+func (ic *integerDecompressor) getK() uint32 {
+	return ic.k
+}
+
+func (ic *integerDecompressor) decompress(dec *arithmeticDecoder, pred int32, context uint32) int32 {
+	real := pred + ic.readCorrector(dec, ic.mBits[context])
+	if real < 0 {
+		real += int32(ic.corrRange)
+	} else if uint32(real) >= ic.corrRange {
+		real -= int32(ic.corrRange)
+	}
+	return real
+}
+
+func (ic *integerDecompressor) readCorrector(dec *arithmeticDecoder, mBits *arithmeticModel) int32 {
+	var c int32
+
+	ic.k = dec.decodeSymbol(mBits)
+
+	if ic.k != 0 {
+		if ic.k < 32 {
+			if ic.k <= ic.bitsHigh {
+				c = int32(dec.decodeSymbol(ic.mCorrector[ic.k]))
+			} else {
+				k1 := ic.k - ic.bitsHigh
+				c = int32(dec.decodeSymbol(ic.mCorrector[ic.k]))
+				c1 := int32(dec.readBits(k1))
+				c = (c << k1) | c1
+			}
+
+			if c >= int32(1)<<(ic.k-1) {
+				c += 1
+			} else {
+				c -= int32((uint32(1) << ic.k) - 1)
+			}
+		} else {
+			c = ic.corrMin
+		}
+	} else {
+		c = int32(dec.decodeBit(ic.mCorrector0))
+	}
+
+	return c
+}
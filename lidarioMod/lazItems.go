@@ -0,0 +1,609 @@
+package lidarioMod
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// LASzip item types as stored in the LASzip VLR
+const (
+	lazItemByte         = 0
+	lazItemPoint10      = 6
+	lazItemGpsTime11    = 7
+	lazItemRgb12        = 8
+	lazItemWavepacket13 = 9
+	lazItemPoint14      = 10
+	lazItemRgb14        = 11
+	lazItemRgbNir14     = 12
+	lazItemWavepacket14 = 13
+	lazItemByte14       = 14
+)
+
+// lazItemDecoder decompresses one item (a group of fields) of a point record
+type lazItemDecoder interface {
+	// init sets up the decoder with the first item of a chunk, which is stored raw
+	init(item []byte)
+	// decompress decodes the next item into the provided slice
+	decompress(dec *arithmeticDecoder, item []byte)
+}
+
+// newLazItemDecoder creates a decoder for the described item
+func newLazItemDecoder(item LaszipItem) (lazItemDecoder, error) {
+	switch item.Type {
+	case lazItemPoint10:
+		if item.Version != 2 {
+			return nil, fmt.Errorf("unsupported LAZ POINT10 item version %v", item.Version)
+		}
+		return newPoint10Decoder(), nil
+	case lazItemGpsTime11:
+		if item.Version != 2 {
+			return nil, fmt.Errorf("unsupported LAZ GPSTIME11 item version %v", item.Version)
+		}
+		return newGpsTime11Decoder(), nil
+	case lazItemRgb12:
+		if item.Version != 2 {
+			return nil, fmt.Errorf("unsupported LAZ RGB12 item version %v", item.Version)
+		}
+		return newRgb12Decoder(), nil
+	case lazItemWavepacket13:
+		if item.Version != 1 {
+			return nil, fmt.Errorf("unsupported LAZ WAVEPACKET13 item version %v", item.Version)
+		}
+		return newWavepacket13Decoder(), nil
+	case lazItemByte:
+		if item.Version != 2 {
+			return nil, fmt.Errorf("unsupported LAZ BYTE item version %v", item.Version)
+		}
+		return newByteDecoder(int(item.Size)), nil
+	default:
+		return nil, fmt.Errorf("unsupported LAZ item type %v", item.Type)
+	}
+}
+
+// Tables mapping number of returns and return number to contexts (laszip_common_v2)
+var numberReturnMap = [8][8]uint8{
+	{15, 14, 13, 12, 11, 10, 9, 8},
+	{14, 0, 1, 3, 6, 10, 10, 9},
+	{13, 1, 2, 4, 7, 11, 11, 10},
+	{12, 3, 4, 5, 8, 12, 12, 11},
+	{11, 6, 7, 8, 9, 13, 13, 12},
+	{10, 10, 11, 12, 13, 14, 14, 13},
+	{9, 10, 11, 12, 13, 14, 15, 14},
+	{8, 9, 10, 11, 12, 13, 14, 15},
+}
+
+var numberReturnLevel = [8][8]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7},
+	{1, 0, 1, 2, 3, 4, 5, 6},
+	{2, 1, 0, 1, 2, 3, 4, 5},
+	{3, 2, 1, 0, 1, 2, 3, 4},
+	{4, 3, 2, 1, 0, 1, 2, 3},
+	{5, 4, 3, 2, 1, 0, 1, 2},
+	{6, 5, 4, 3, 2, 1, 0, 1},
+	{7, 6, 5, 4, 3, 2, 1, 0},
+}
+
+// foldByte wraps a value into the range of a byte
+func foldByte(n int32) uint8 {
+	if n < 0 {
+		return uint8(n + 256)
+	} else if n > 255 {
+		return uint8(n - 256)
+	}
+	return uint8(n)
+}
+
+// clampByte clamps a value into the range of a byte
+func clampByte(n int32) int32 {
+	if n <= 0 {
+		return 0
+	} else if n >= 255 {
+		return 255
+	}
+	return n
+}
+
+// streamingMedian5 tracks the median of the last five values added
+type streamingMedian5 struct {
+	values [5]int32
+	high   bool
+}
+
+func newStreamingMedian5() streamingMedian5 {
+	return streamingMedian5{high: true}
+}
+
+func (m *streamingMedian5) add(v int32) {
+	if m.high {
+		if v < m.values[2] {
+			m.values[4] = m.values[3]
+			m.values[3] = m.values[2]
+			if v < m.values[0] {
+				m.values[2] = m.values[1]
+				m.values[1] = m.values[0]
+				m.values[0] = v
+			} else if v < m.values[1] {
+				m.values[2] = m.values[1]
+				m.values[1] = v
+			} else {
+				m.values[2] = v
+			}
+		} else {
+			if v < m.values[3] {
+				m.values[4] = m.values[3]
+				m.values[3] = v
+			} else {
+				m.values[4] = v
+			}
+			m.high = false
+		}
+	} else {
+		if m.values[2] < v {
+			m.values[0] = m.values[1]
+			m.values[1] = m.values[2]
+			if m.values[4] < v {
+				m.values[2] = m.values[3]
+				m.values[3] = m.values[4]
+				m.values[4] = v
+			} else if m.values[3] < v {
+				m.values[2] = m.values[3]
+				m.values[3] = v
+			} else {
+				m.values[2] = v
+			}
+		} else {
+			if m.values[1] < v {
+				m.values[0] = m.values[1]
+				m.values[1] = v
+			} else {
+				m.values[0] = v
+			}
+			m.high = true
+		}
+	}
+}
+
+func (m *streamingMedian5) get() int32 {
+	return m.values[2]
+}
+
+// point10Decoder decodes the 20 byte core of point formats 0-5 (POINT10 version 2)
+type point10Decoder struct {
+	last             [20]byte
+	lastXDiffMedian5 [16]streamingMedian5
+	lastYDiffMedian5 [16]streamingMedian5
+	lastIntensity    [16]uint16
+	lastHeight       [8]int32
+	mChangedValues   *arithmeticModel
+	icIntensity      *integerDecompressor
+	mScanAngleRank   [2]*arithmeticModel
+	icPointSourceID  *integerDecompressor
+	mBitByte         [256]*arithmeticModel
+	mClassification  [256]*arithmeticModel
+	mUserData        [256]*arithmeticModel
+	icDx             *integerDecompressor
+	icDy             *integerDecompressor
+	icZ              *integerDecompressor
+}
+
+func newPoint10Decoder() *point10Decoder {
+	d := &point10Decoder{
+		mChangedValues:  newArithmeticModel(64),
+		icIntensity:     newIntegerDecompressor(16, 4),
+		mScanAngleRank:  [2]*arithmeticModel{newArithmeticModel(256), newArithmeticModel(256)},
+		icPointSourceID: newIntegerDecompressor(16, 1),
+		icDx:            newIntegerDecompressor(32, 2),
+		icDy:            newIntegerDecompressor(32, 22),
+		icZ:             newIntegerDecompressor(32, 20),
+	}
+	for i := 0; i < 16; i++ {
+		d.lastXDiffMedian5[i] = newStreamingMedian5()
+		d.lastYDiffMedian5[i] = newStreamingMedian5()
+	}
+	return d
+}
+
+func (d *point10Decoder) init(item []byte) {
+	copy(d.last[:], item[:20])
+	// the intensity of the last item starts at zero
+	d.last[12] = 0
+	d.last[13] = 0
+}
+
+// symbolModel returns the lazily created model at index i
+func symbolModel(models *[256]*arithmeticModel, i uint8) *arithmeticModel {
+	if models[i] == nil {
+		models[i] = newArithmeticModel(256)
+	}
+	return models[i]
+}
+
+// zeroBit0 clears the lowest bit of n
+func zeroBit0(n uint32) uint32 {
+	return n & 0xFFFFFFFE
+}
+
+func (d *point10Decoder) decompress(dec *arithmeticDecoder, item []byte) {
+	last := d.last[:]
+
+	changedValues := dec.decodeSymbol(d.mChangedValues)
+
+	if changedValues != 0 {
+		// return number, number of returns, scan direction and edge of flight line
+		if changedValues&32 != 0 {
+			last[14] = uint8(dec.decodeSymbol(symbolModel(&d.mBitByte, last[14])))
+		}
+	}
+
+	r := last[14] & 7
+	n := (last[14] >> 3) & 7
+	m := numberReturnMap[n][r]
+	l := numberReturnLevel[n][r]
+
+	if changedValues != 0 {
+		if changedValues&16 != 0 {
+			context := uint32(m)
+			if context > 3 {
+				context = 3
+			}
+			intensity := uint16(d.icIntensity.decompress(dec, int32(d.lastIntensity[m]), context))
+			d.lastIntensity[m] = intensity
+			binary.LittleEndian.PutUint16(last[12:14], intensity)
+		} else {
+			binary.LittleEndian.PutUint16(last[12:14], d.lastIntensity[m])
+		}
+
+		if changedValues&8 != 0 {
+			last[15] = uint8(dec.decodeSymbol(symbolModel(&d.mClassification, last[15])))
+		}
+
+		if changedValues&4 != 0 {
+			scanDirection := (last[14] >> 6) & 1
+			val := int32(dec.decodeSymbol(d.mScanAngleRank[scanDirection]))
+			last[16] = foldByte(val + int32(last[16]))
+		}
+
+		if changedValues&2 != 0 {
+			last[17] = uint8(dec.decodeSymbol(symbolModel(&d.mUserData, last[17])))
+		}
+
+		if changedValues&1 != 0 {
+			pointSource := binary.LittleEndian.Uint16(last[18:20])
+			pointSource = uint16(d.icPointSourceID.decompress(dec, int32(pointSource), 0))
+			binary.LittleEndian.PutUint16(last[18:20], pointSource)
+		}
+	}
+
+	single := uint32(0)
+	if n == 1 {
+		single = 1
+	}
+
+	// x coordinate
+	median := d.lastXDiffMedian5[m].get()
+	diff := d.icDx.decompress(dec, median, single)
+	x := int32(binary.LittleEndian.Uint32(last[0:4])) + diff
+	binary.LittleEndian.PutUint32(last[0:4], uint32(x))
+	d.lastXDiffMedian5[m].add(diff)
+
+	// y coordinate
+	median = d.lastYDiffMedian5[m].get()
+	kBits := d.icDx.getK()
+	context := single
+	if kBits < 20 {
+		context += zeroBit0(kBits)
+	} else {
+		context += 20
+	}
+	diff = d.icDy.decompress(dec, median, context)
+	y := int32(binary.LittleEndian.Uint32(last[4:8])) + diff
+	binary.LittleEndian.PutUint32(last[4:8], uint32(y))
+	d.lastYDiffMedian5[m].add(diff)
+
+	// z coordinate
+	kBits = (d.icDx.getK() + d.icDy.getK()) / 2
+	context = single
+	if kBits < 18 {
+		context += zeroBit0(kBits)
+	} else {
+		context += 18
+	}
+	z := d.icZ.decompress(dec, d.lastHeight[l], context)
+	binary.LittleEndian.PutUint32(last[8:12], uint32(z))
+	d.lastHeight[l] = z
+
+	copy(item, last)
+}
+
+const (
+	gpsTimeMulti          = 500
+	gpsTimeMultiMinus     = -10
+	gpsTimeMultiUnchanged = gpsTimeMulti - gpsTimeMultiMinus + 1
+	gpsTimeMultiCodeFull  = gpsTimeMulti - gpsTimeMultiMinus + 2
+	gpsTimeMultiTotal     = gpsTimeMulti - gpsTimeMultiMinus + 6
+)
+
+// gpsTime11Decoder decodes the GPS time of a point (GPSTIME11 version 2)
+type gpsTime11Decoder struct {
+	last                int
+	next                int
+	lastGpsTime         [4]uint64
+	lastGpsTimeDiff     [4]int32
+	multiExtremeCounter [4]int32
+	mGpsTimeMulti       *arithmeticModel
+	mGpsTime0Diff       *arithmeticModel
+	icGpsTime           *integerDecompressor
+}
+
+func newGpsTime11Decoder() *gpsTime11Decoder {
+	return &gpsTime11Decoder{
+		mGpsTimeMulti: newArithmeticModel(gpsTimeMultiTotal),
+		mGpsTime0Diff: newArithmeticModel(6),
+		icGpsTime:     newIntegerDecompressor(32, 9),
+	}
+}
+
+func (d *gpsTime11Decoder) init(item []byte) {
+	d.lastGpsTime[0] = binary.LittleEndian.Uint64(item[0:8])
+}
+
+// readFull reads a GPS time that could not be predicted and starts a new sequence
+func (d *gpsTime11Decoder) readFull(dec *arithmeticDecoder) {
+	d.next = (d.next + 1) & 3
+	upper := d.icGpsTime.decompress(dec, int32(d.lastGpsTime[d.last]>>32), 8)
+	d.lastGpsTime[d.next] = uint64(uint32(upper)) << 32
+	d.lastGpsTime[d.next] |= uint64(dec.readInt())
+	d.last = d.next
+	d.lastGpsTimeDiff[d.last] = 0
+	d.multiExtremeCounter[d.last] = 0
+}
+
+func (d *gpsTime11Decoder) read(dec *arithmeticDecoder) {
+	if d.lastGpsTimeDiff[d.last] == 0 {
+		multi := int32(dec.decodeSymbol(d.mGpsTime0Diff))
+		if multi == 1 {
+			d.lastGpsTimeDiff[d.last] = d.icGpsTime.decompress(dec, 0, 0)
+			d.lastGpsTime[d.last] = uint64(int64(d.lastGpsTime[d.last]) + int64(d.lastGpsTimeDiff[d.last]))
+			d.multiExtremeCounter[d.last] = 0
+		} else if multi == 2 {
+			d.readFull(dec)
+		} else if multi > 2 {
+			d.last = (d.last + int(multi) - 2) & 3
+			d.read(dec)
+		}
+		return
+	}
+
+	multi := int32(dec.decodeSymbol(d.mGpsTimeMulti))
+	if multi == 1 {
+		diff := d.icGpsTime.decompress(dec, d.lastGpsTimeDiff[d.last], 1)
+		d.lastGpsTime[d.last] = uint64(int64(d.lastGpsTime[d.last]) + int64(diff))
+		d.multiExtremeCounter[d.last] = 0
+	} else if multi < gpsTimeMultiUnchanged {
+		var diff int32
+		if multi == 0 {
+			diff = d.icGpsTime.decompress(dec, 0, 7)
+			d.multiExtremeCounter[d.last]++
+			if d.multiExtremeCounter[d.last] > 3 {
+				d.lastGpsTimeDiff[d.last] = diff
+				d.multiExtremeCounter[d.last] = 0
+			}
+		} else if multi < gpsTimeMulti {
+			if multi < 10 {
+				diff = d.icGpsTime.decompress(dec, multi*d.lastGpsTimeDiff[d.last], 2)
+			} else {
+				diff = d.icGpsTime.decompress(dec, multi*d.lastGpsTimeDiff[d.last], 3)
+			}
+		} else if multi == gpsTimeMulti {
+			diff = d.icGpsTime.decompress(dec, gpsTimeMulti*d.lastGpsTimeDiff[d.last], 4)
+			d.multiExtremeCounter[d.last]++
+			if d.multiExtremeCounter[d.last] > 3 {
+				d.lastGpsTimeDiff[d.last] = diff
+				d.multiExtremeCounter[d.last] = 0
+			}
+		} else {
+			multi = gpsTimeMulti - multi
+			if multi > gpsTimeMultiMinus {
+				diff = d.icGpsTime.decompress(dec, multi*d.lastGpsTimeDiff[d.last], 5)
+			} else {
+				diff = d.icGpsTime.decompress(dec, gpsTimeMultiMinus*d.lastGpsTimeDiff[d.last], 6)
+				d.multiExtremeCounter[d.last]++
+				if d.multiExtremeCounter[d.last] > 3 {
+					d.lastGpsTimeDiff[d.last] = diff
+					d.multiExtremeCounter[d.last] = 0
+				}
+			}
+		}
+		d.lastGpsTime[d.last] = uint64(int64(d.lastGpsTime[d.last]) + int64(diff))
+	} else if multi == gpsTimeMultiCodeFull {
+		d.readFull(dec)
+	} else if multi > gpsTimeMultiCodeFull {
+		d.last = (d.last + int(multi) - gpsTimeMultiCodeFull) & 3
+		d.read(dec)
+	}
+}
+
+func (d *gpsTime11Decoder) decompress(dec *arithmeticDecoder, item []byte) {
+	d.read(dec)
+	binary.LittleEndian.PutUint64(item[0:8], d.lastGpsTime[d.last])
+}
+
+// rgb12Decoder decodes the colour of a point (RGB12 version 2)
+type rgb12Decoder struct {
+	last      [3]uint16
+	mByteUsed *arithmeticModel
+	mRgbDiff  [6]*arithmeticModel
+}
+
+func newRgb12Decoder() *rgb12Decoder {
+	d := &rgb12Decoder{mByteUsed: newArithmeticModel(128)}
+	for i := range d.mRgbDiff {
+		d.mRgbDiff[i] = newArithmeticModel(256)
+	}
+	return d
+}
+
+func (d *rgb12Decoder) init(item []byte) {
+	for i := 0; i < 3; i++ {
+		d.last[i] = binary.LittleEndian.Uint16(item[2*i : 2*i+2])
+	}
+}
+
+// decodeRgbByte decodes a colour byte predicted from the given value
+func (d *rgb12Decoder) decodeRgbByte(dec *arithmeticDecoder, model int, prediction int32) uint16 {
+	corr := int32(uint8(dec.decodeSymbol(d.mRgbDiff[model])))
+	return uint16(foldByte(corr + prediction))
+}
+
+func (d *rgb12Decoder) decompress(dec *arithmeticDecoder, item []byte) {
+	var rgb [3]uint16
+	last := d.last
+
+	sym := dec.decodeSymbol(d.mByteUsed)
+
+	if sym&(1<<0) != 0 {
+		rgb[0] = d.decodeRgbByte(dec, 0, int32(last[0]&255))
+	} else {
+		rgb[0] = last[0] & 0xFF
+	}
+	if sym&(1<<1) != 0 {
+		rgb[0] |= d.decodeRgbByte(dec, 1, int32(last[0]>>8)) << 8
+	} else {
+		rgb[0] |= last[0] & 0xFF00
+	}
+
+	if sym&(1<<6) != 0 {
+		diff := int32(rgb[0]&0x00FF) - int32(last[0]&0x00FF)
+		if sym&(1<<2) != 0 {
+			rgb[1] = d.decodeRgbByte(dec, 2, clampByte(diff+int32(last[1]&255)))
+		} else {
+			rgb[1] = last[1] & 0xFF
+		}
+		if sym&(1<<4) != 0 {
+			diff = (diff + (int32(rgb[1]&0x00FF) - int32(last[1]&0x00FF))) / 2
+			rgb[2] = d.decodeRgbByte(dec, 4, clampByte(diff+int32(last[2]&255)))
+		} else {
+			rgb[2] = last[2] & 0xFF
+		}
+
+		diff = int32(rgb[0]>>8) - int32(last[0]>>8)
+		if sym&(1<<3) != 0 {
+			rgb[1] |= d.decodeRgbByte(dec, 3, clampByte(diff+int32(last[1]>>8))) << 8
+		} else {
+			rgb[1] |= last[1] & 0xFF00
+		}
+		if sym&(1<<5) != 0 {
+			diff = (diff + (int32(rgb[1]>>8) - int32(last[1]>>8))) / 2
+			rgb[2] |= d.decodeRgbByte(dec, 5, clampByte(diff+int32(last[2]>>8))) << 8
+		} else {
+			rgb[2] |= last[2] & 0xFF00
+		}
+	} else {
+		rgb[1] = rgb[0]
+		rgb[2] = rgb[0]
+	}
+
+	d.last = rgb
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint16(item[2*i:2*i+2], rgb[i])
+	}
+}
+
+// byteDecoder decodes extra bytes attached to a point (BYTE version 2)
+type byteDecoder struct {
+	last  []byte
+	mByte []*arithmeticModel
+}
+
+func newByteDecoder(size int) *byteDecoder {
+	d := &byteDecoder{last: make([]byte, size), mByte: make([]*arithmeticModel, size)}
+	for i := range d.mByte {
+		d.mByte[i] = newArithmeticModel(256)
+	}
+	return d
+}
+
+func (d *byteDecoder) init(item []byte) {
+	copy(d.last, item)
+}
+
+func (d *byteDecoder) decompress(dec *arithmeticDecoder, item []byte) {
+	for i := range d.last {
+		value := int32(d.last[i]) + int32(dec.decodeSymbol(d.mByte[i]))
+		item[i] = foldByte(value)
+	}
+	copy(d.last, item)
+}
+
+// wavepacket13Decoder decodes the waveform packet of a point (WAVEPACKET13 version 1)
+type wavepacket13Decoder struct {
+	last              [28]byte
+	lastDiff32        int32
+	symLastOffsetDiff uint32
+	mPacketIndex      *arithmeticModel
+	mOffsetDiff       [4]*arithmeticModel
+	icOffsetDiff      *integerDecompressor
+	icPacketSize      *integerDecompressor
+	icReturnPoint     *integerDecompressor
+	icXYZ             *integerDecompressor
+}
+
+func newWavepacket13Decoder() *wavepacket13Decoder {
+	d := &wavepacket13Decoder{
+		mPacketIndex:  newArithmeticModel(256),
+		icOffsetDiff:  newIntegerDecompressor(32, 1),
+		icPacketSize:  newIntegerDecompressor(32, 1),
+		icReturnPoint: newIntegerDecompressor(32, 1),
+		icXYZ:         newIntegerDecompressor(32, 3),
+	}
+	for i := range d.mOffsetDiff {
+		d.mOffsetDiff[i] = newArithmeticModel(4)
+	}
+	return d
+}
+
+func (d *wavepacket13Decoder) init(item []byte) {
+	copy(d.last[:], item[1:29])
+}
+
+func (d *wavepacket13Decoder) decompress(dec *arithmeticDecoder, item []byte) {
+	item[0] = uint8(dec.decodeSymbol(d.mPacketIndex))
+	current := item[1:29]
+	last := d.last[:]
+
+	lastOffset := binary.LittleEndian.Uint64(last[0:8])
+	lastPacketSize := binary.LittleEndian.Uint32(last[8:12])
+
+	var offset uint64
+	d.symLastOffsetDiff = dec.decodeSymbol(d.mOffsetDiff[d.symLastOffsetDiff])
+	switch d.symLastOffsetDiff {
+	case 0:
+		offset = lastOffset
+	case 1:
+		offset = lastOffset + uint64(lastPacketSize)
+	case 2:
+		d.lastDiff32 = d.icOffsetDiff.decompress(dec, d.lastDiff32, 0)
+		offset = uint64(int64(lastOffset) + int64(d.lastDiff32))
+	default:
+		offset = dec.readInt64()
+	}
+	binary.LittleEndian.PutUint64(current[0:8], offset)
+
+	packetSize := d.icPacketSize.decompress(dec, int32(lastPacketSize), 0)
+	binary.LittleEndian.PutUint32(current[8:12], uint32(packetSize))
+
+	// the return point and the xyz offsets are floats compressed as their integer bits
+	for i := 0; i < 4; i++ {
+		start := 12 + 4*i
+		lastValue := int32(binary.LittleEndian.Uint32(last[start : start+4]))
+		var value int32
+		if i == 0 {
+			value = d.icReturnPoint.decompress(dec, lastValue, 0)
+		} else {
+			value = d.icXYZ.decompress(dec, lastValue, uint32(i-1))
+		}
+		binary.LittleEndian.PutUint32(current[start:start+4], uint32(value))
+	}
+
+	copy(d.last[:], current)
+}
@@ -0,0 +1,110 @@
+package lidarioMod
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// openTestFile opens a file of testdata for reading its header and points
+func openTestFile(t *testing.T, name string) *LasFile {
+	t.Helper()
+	las, err := NewLasFile(filepath.Join("testdata", name), "rh")
+	if err != nil {
+		t.Fatalf("opening %v: %v", name, err)
+	}
+	t.Cleanup(func() { las.Close() })
+	return las
+}
+
+// Each LAZ file of testdata holds the points of the LAS file of the same name, compressed in chunks of
+// 300 points so the last chunk is partial:
+//   - points.laz (format 3) with POINT10, GPSTIME11 and RGB12 items
+//   - extrabytes.laz (format 1 with 3 extra bytes) with POINT10, GPSTIME11 and BYTE items
+//   - wavepackets.laz (format 4) with POINT10, GPSTIME11 and WAVEPACKET13 items, whose waveform offsets
+//     repeat, follow the last packet, jump by 32 bit differences and jump past 32 bits
+var lazTestFiles = []struct {
+	name  string
+	items []LaszipItem
+}{
+	{"points", []LaszipItem{{lazItemPoint10, 20, 2}, {lazItemGpsTime11, 8, 2}, {lazItemRgb12, 6, 2}}},
+	{"extrabytes", []LaszipItem{{lazItemPoint10, 20, 2}, {lazItemGpsTime11, 8, 2}, {lazItemByte, 3, 2}}},
+	{"wavepackets", []LaszipItem{{lazItemPoint10, 20, 2}, {lazItemGpsTime11, 8, 2}, {lazItemWavepacket13, 29, 1}}},
+}
+
+func TestReadPointRecordsLazMatchesLas(t *testing.T) {
+	for _, file := range lazTestFiles {
+		las, laz := openTestFile(t, file.name+".las"), openTestFile(t, file.name+".laz")
+
+		if las.IsCompressed() || !laz.IsCompressed() {
+			t.Fatalf("%v.las compressed is %v and %v.laz compressed is %v", file.name, las.IsCompressed(), file.name, laz.IsCompressed())
+		}
+		if !reflect.DeepEqual(laz.laszip.Items, file.items) {
+			t.Fatalf("%v.laz has items %v, expected %v", file.name, laz.laszip.Items, file.items)
+		}
+		if len(laz.LazChunks()) != 4 {
+			t.Fatalf("%v.laz has %v chunks, expected 4", file.name, len(laz.LazChunks()))
+		}
+		if las.Header.NumberPoints != laz.Header.NumberPoints || las.Header.PointRecordLength != laz.Header.PointRecordLength {
+			t.Fatalf("%v.laz has %v points of %v bytes, expected %v of %v", file.name, laz.Header.NumberPoints,
+				laz.Header.PointRecordLength, las.Header.NumberPoints, las.Header.PointRecordLength)
+		}
+
+		recordLength := las.Header.PointRecordLength
+
+		// ranges inside, across and at the ends of chunks
+		for _, r := range [][2]int{{0, las.Header.NumberPoints}, {0, 1}, {299, 301}, {250, 950}, {600, 900}, {999, 1000}, {500, 500}} {
+			expected, err := las.ReadPointRecords(r[0], r[1])
+			if err != nil {
+				t.Fatalf("reading points %v of %v.las: %v", r, file.name, err)
+			}
+			actual, err := laz.ReadPointRecords(r[0], r[1])
+			if err != nil {
+				t.Fatalf("reading points %v of %v.laz: %v", r, file.name, err)
+			}
+
+			if !bytes.Equal(expected, actual) {
+				for i := 0; i < r[1]-r[0]; i++ {
+					record := expected[i*recordLength : (i+1)*recordLength]
+					if decoded := actual[i*recordLength : (i+1)*recordLength]; !bytes.Equal(record, decoded) {
+						t.Fatalf("reading points %v of %v.laz, point %v decoded as %v, expected %v", r, file.name, r[0]+i, decoded, record)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Layered compression (compressor 3, used by point formats 6-10) is not decoded, so it must fail on open
+// rather than produce wrong points
+func TestLayeredLazIsRejected(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "points.laz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the VLR header is 54 bytes, starting 2 bytes before its user ID
+	vlr := bytes.Index(data, []byte("laszip encoded"))
+	if vlr < 0 {
+		t.Fatal("points.laz has no LASzip VLR")
+	}
+	binary.LittleEndian.PutUint16(data[vlr-2+54:], lazCompressorLayeredChunked)
+
+	fileName := filepath.Join(t.TempDir(), "layered.laz")
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	las, err := NewLasFile(fileName, "rh")
+	if err == nil {
+		las.Close()
+		t.Fatal("opening a layered LAZ file succeeded")
+	}
+	if !strings.Contains(err.Error(), "layered") {
+		t.Fatalf("opening a layered LAZ file failed with %q, expected it to name the layered compression", err)
+	}
+}
@@ -27,17 +27,22 @@ type LasFile struct {
 	RawFile                *os.File
 	Header                 LasHeader
 	VlrData                []VLR
+	EvlrData               []VLR
 	geokeys                GeoKeys
 	pointData              []PointRecord0
 	gpsData                []float64
 	rgbData                []RgbData
 	usePointIntensity      bool
 	usePointUserdata       bool
+	pointLayout            *PointLayout
 	headerIsSet            bool
 	fixedRadiusSearch2DSet bool
 	frs2D                  *fixedRadiusSearch
 	fixedRadiusSearch3DSet bool
 	frs3D                  *fixedRadiusSearch
+	compressedFlag         bool
+	laszip                 *LaszipVLR
+	lazChunks              []LazChunk
 	sync.RWMutex
 }
 
@@ -360,6 +365,15 @@ func (las *LasFile) read() error {
 	if err := las.readVLRs(); err != nil {
 		return err
 	}
+	if err := las.readEVLRs(); err != nil {
+		return err
+	}
+	if err := las.readExtraBytes(); err != nil {
+		return err
+	}
+	if err := las.readLaszip(); err != nil {
+		return err
+	}
 	if las.fileMode != "rh" {
 		if err := las.readPoints(); err != nil {
 			return err
@@ -371,8 +385,8 @@ func (las *LasFile) read() error {
 func (las *LasFile) readHeader() error {
 	las.Lock()
 	defer las.Unlock()
-	b := make([]byte, 243)
-	if _, err := las.RawFile.ReadAt(b[0:243], 0); err != nil && err != io.EOF {
+	b := make([]byte, 375)
+	if _, err := las.RawFile.ReadAt(b[0:375], 0); err != nil && err != io.EOF {
 		return err
 	}
 
@@ -430,7 +444,9 @@ func (las *LasFile) readHeader() error {
 	offset += 4
 	las.Header.NumberOfVLRs = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
 	offset += 4
-	las.Header.PointFormatID = b[104]
+	// The upper two bits of the point format are used by LAZ to flag compression
+	las.Header.PointFormatID = b[104] & 63
+	las.compressedFlag = b[104]&192 != 0
 	offset++
 	las.Header.PointRecordLength = int(binary.LittleEndian.Uint16(b[offset : offset+2]))
 	offset += 2
@@ -465,28 +481,63 @@ func (las *LasFile) readHeader() error {
 	offset += 8
 	las.Header.MinZ = math.Float64frombits(binary.LittleEndian.Uint64(b[offset : offset+8]))
 	offset += 8
-	if las.Header.VersionMajor == 1 && las.Header.VersionMinor == 3 {
+	if las.Header.VersionMajor == 1 && las.Header.VersionMinor >= 3 {
 		las.Header.WaveformDataStart = binary.LittleEndian.Uint64(b[offset : offset+8])
 	}
+	offset += 8
+	if las.Header.VersionMajor == 1 && las.Header.VersionMinor >= 4 {
+		// LAS 1.4 extended header with EVLRs and 64-bit point counts
+		las.Header.StartOfFirstEVLR = binary.LittleEndian.Uint64(b[offset : offset+8])
+		offset += 8
+		las.Header.NumberOfEVLRs = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
+		offset += 4
+		numberPoints := int(binary.LittleEndian.Uint64(b[offset : offset+8]))
+		offset += 8
+		for i := 0; i < 15; i++ {
+			las.Header.ExtendedNumberPointsByReturn[i] = int(binary.LittleEndian.Uint64(b[offset : offset+8]))
+			offset += 8
+		}
 
-	// Intensity and userdata are both optional. Figure out if they need to be read.
-	// The only way to do this is to compare the point record length by point format
-	recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
-
-	if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-		las.usePointIntensity = true
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-		las.usePointIntensity = false
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-		las.usePointIntensity = true
-		las.usePointUserdata = false
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-		las.usePointIntensity = false
-		las.usePointUserdata = false
+		// The legacy counts must be zero for point formats 6-10 and for files with more
+		// than 2^32 - 1 points, in which case the extended counts are authoritative.
+		if las.Header.NumberPoints == 0 || numberPoints > las.Header.NumberPoints {
+			las.Header.NumberPoints = numberPoints
+		}
+		legacyByReturn := 0
+		for i := 0; i < 5; i++ {
+			legacyByReturn += las.Header.NumberPointsByReturn[i]
+		}
+		if legacyByReturn == 0 {
+			for i := 0; i < 5; i++ {
+				las.Header.NumberPointsByReturn[i] = las.Header.ExtendedNumberPointsByReturn[i]
+			}
+		}
+	} else {
+		for i := 0; i < 5; i++ {
+			las.Header.ExtendedNumberPointsByReturn[i] = las.Header.NumberPointsByReturn[i]
+		}
+	}
+
+	if las.Header.PointFormatID > 10 {
+		return fmt.Errorf("unsupported point format %v", las.Header.PointFormatID)
+	}
+
+	return las.readPointLayout()
+}
+
+// readPointLayout works out the layout of the point records from the header, including
+// whether the optional intensity and userdata need to be read.
+func (las *LasFile) readPointLayout() error {
+	layout, err := NewPointLayout(int(las.Header.PointFormatID), las.Header.PointRecordLength)
+
+	if err != nil {
+		return err
 	}
 
+	las.pointLayout = layout
+	las.usePointIntensity = layout.Intensity >= 0
+	las.usePointUserdata = layout.UserData >= 0
+
 	return nil
 }
 
@@ -543,9 +594,45 @@ func (las *LasFile) readVLRs() error {
 	return nil
 }
 
+// readEVLRs reads the extended variable length records found after the point data in LAS 1.4 files
+func (las *LasFile) readEVLRs() error {
+	las.Lock()
+	defer las.Unlock()
+	las.EvlrData = make([]VLR, 0, las.Header.NumberOfEVLRs)
+	if las.Header.NumberOfEVLRs == 0 || las.Header.StartOfFirstEVLR == 0 {
+		return nil
+	}
+
+	offset := int64(las.Header.StartOfFirstEVLR)
+	h := make([]byte, 60)
+	for i := 0; i < las.Header.NumberOfEVLRs; i++ {
+		if _, err := las.RawFile.ReadAt(h, offset); err != nil {
+			return fmt.Errorf("reading EVLR %v header: %w", i, err)
+		}
+		vlr := VLR{}
+		vlr.Reserved = int(binary.LittleEndian.Uint16(h[0:2]))
+		vlr.UserID = strings.Trim(strings.Trim(string(h[2:18]), " "), "\x00")
+		vlr.RecordID = int(binary.LittleEndian.Uint16(h[18:20]))
+		vlr.RecordLengthAfterHeader = int(binary.LittleEndian.Uint64(h[20:28]))
+		vlr.Description = strings.Trim(strings.Trim(string(h[28:60]), " "), "\x00")
+		offset += 60
+		vlr.BinaryData = make([]uint8, vlr.RecordLengthAfterHeader)
+		if _, err := las.RawFile.ReadAt(vlr.BinaryData, offset); err != nil && err != io.EOF {
+			return fmt.Errorf("reading EVLR %v data: %w", i, err)
+		}
+		offset += int64(vlr.RecordLengthAfterHeader)
+		las.EvlrData = append(las.EvlrData, vlr)
+	}
+
+	return nil
+}
+
 func (las *LasFile) readPoints() error {
 	las.Lock()
 	defer las.Unlock()
+	if las.Header.PointFormatID > 5 {
+		return fmt.Errorf("point format %v can only be opened in 'rh' mode", las.Header.PointFormatID)
+	}
 	las.pointData = make([]PointRecord0, las.Header.NumberPoints)
 	if las.Header.PointFormatID == 1 || las.Header.PointFormatID == 3 {
 		las.gpsData = make([]float64, las.Header.NumberPoints)
@@ -554,31 +641,12 @@ func (las *LasFile) readPoints() error {
 		las.rgbData = make([]RgbData, las.Header.NumberPoints)
 	}
 
-	// Estimate how many bytes are used to store the points
-	pointsLength := las.Header.NumberPoints * las.Header.PointRecordLength
-	b := make([]byte, pointsLength)
-	if _, err := las.RawFile.ReadAt(b, int64(las.Header.OffsetToPoints)); err != nil && err != io.EOF {
+	// Read (and decompress if required) all of the point records
+	b, err := las.ReadPointRecords(0, las.Header.NumberPoints)
+	if err != nil {
 		return err
 	}
 
-	// Intensity and userdata are both optional. Figure out if they need to be read.
-	// The only way to do this is to compare the point record length by point format
-	recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
-
-	if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-		las.usePointIntensity = true
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-		las.usePointIntensity = false
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-		las.usePointIntensity = true
-		las.usePointUserdata = false
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-		las.usePointIntensity = false
-		las.usePointUserdata = false
-	}
-
 	numCPUs := runtime.NumCPU()
 	var wg sync.WaitGroup
 	blockSize := las.Header.NumberPoints / numCPUs
@@ -1407,6 +1475,43 @@ func (las *LasFile) SetFixedRadiusSearchDistance(radius float64, threeDimensiona
 	return nil
 }
 
+// HasPointIntensity returns whether the point records of the file store intensity.
+// Intensity is optional in point formats 0-5 and mandatory in formats 6-10.
+func (las *LasFile) HasPointIntensity() bool {
+	return las.usePointIntensity
+}
+
+// HasPointUserdata returns whether the point records of the file store user data.
+// User data is optional in point formats 0-5 and mandatory in formats 6-10.
+func (las *LasFile) HasPointUserdata() bool {
+	return las.usePointUserdata
+}
+
+// FileName returns the name of the file.
+func (las *LasFile) FileName() string {
+	return las.fileName
+}
+
+// GeoKeys returns the GeoKeys of the file, or nil if it has none.
+func (las *LasFile) GeoKeys() *GeoKeys {
+	if len(las.geokeys.GeoKeyDirectory) == 0 {
+		return nil
+	}
+	return &las.geokeys
+}
+
+// WKT returns the OGC coordinate system WKT of the file from its VLRs or EVLRs,
+// or an empty string if it has none.
+func (las *LasFile) WKT() string {
+	records := append(append([]VLR{}, las.VlrData...), las.EvlrData...)
+	for _, vlr := range records {
+		if vlr.UserID == "LASF_Projection" && vlr.RecordID == 2112 {
+			return strings.TrimRight(string(vlr.BinaryData), "\x00")
+		}
+	}
+	return ""
+}
+
 // PrintGeokeys interprets the Geokeys, if there are any.
 func (las *LasFile) PrintGeokeys() string {
 	return las.geokeys.interpretGeokeys()
@@ -1447,7 +1552,11 @@ type LasHeader struct {
 	MaxZ                 float64
 	MinZ                 float64
 	WaveformDataStart    uint64
-	projectIDUsed        bool
+	StartOfFirstEVLR     uint64
+	NumberOfEVLRs        int
+	// ExtendedNumberPointsByReturn holds the LAS 1.4 counts for returns 1-15
+	ExtendedNumberPointsByReturn [15]int
+	projectIDUsed                bool
 }
 
 func (h LasHeader) String() string {
@@ -0,0 +1,186 @@
+package lidarioMod
+
+import "fmt"
+
+// PointLayout describes where each field of a point record is stored, as byte offsets from
+// the start of the record. Fields the records don't store have an offset of -1.
+type PointLayout struct {
+	// Point data record format
+	Format int
+
+	// Length of a record in bytes, including extra bytes
+	RecordLength int
+
+	// Whether the records use the LAS 1.4 point formats (6-10), with 4 bit return numbers,
+	// a separate byte of classification flags and scanner channel, and 16 bit scan angles
+	Extended bool
+
+	// Offset of the 16 bit intensity
+	Intensity int
+
+	// Offset of the byte of return number and number of returns
+	Returns int
+
+	// Offset of the byte holding the classification flags, the classification byte for formats 0-5
+	Flags int
+
+	// Offset of the classification byte
+	Classification int
+
+	// Offset of the scan angle, 8 bit whole degrees for formats 0-5 and 16 bit in 0.006 degrees for 6-10
+	ScanAngle int
+
+	// Offset of the user data byte
+	UserData int
+
+	// Offset of the 16 bit point source ID
+	PointSource int
+
+	// Offset of the 64 bit GPS time
+	GPSTime int
+
+	// Offset of the 16 bit red, green and blue values
+	RGB int
+
+	// Offset of the 16 bit near infrared value
+	NIR int
+
+	// Offset of the 29 byte wave packet descriptor
+	WavePacket int
+
+	// Offset of the extra bytes after the standard fields, the record length if there are none
+	ExtraBytes int
+
+	// Number of extra bytes at the end of each record
+	ExtraBytesLength int
+
+	// Attributes stored in the extra bytes, from the Extra Bytes VLR, empty if the file has none
+	ExtraAttributes []ExtraBytesAttribute
+}
+
+// Lengths of the wave packet descriptor, GPS time, colour and near infrared fields
+const (
+	wavePacketLength = 29
+	gpsTimeLength    = 8
+	rgbLength        = 6
+	nirLength        = 2
+)
+
+// standardLength gets the length of the standard fields of a point format, with intensity and user data.
+func standardLength(format int) int {
+	lengths := [11]int{20, 28, 26, 34, 57, 63, 30, 36, 38, 59, 67}
+
+	return lengths[format]
+}
+
+// NewPointLayout gets the layout of the records of a point format. Intensity and user data are always
+// stored in formats 6-10, and some writers leave them out of formats 0-5: records 1 byte short of the
+// standard length have no user data, 2 bytes short no intensity and 3 bytes short neither. Bytes after
+// the standard fields are extra bytes. Records shorter than that are an error.
+func NewPointLayout(format int, recordLength int) (*PointLayout, error) {
+	if format < 0 || format > 10 {
+		return nil, fmt.Errorf("unsupported point format %v", format)
+	}
+
+	intensity, userData := true, true
+
+	if format < 6 {
+		switch standardLength(format) - recordLength {
+		case 1:
+			userData = false
+		case 2:
+			intensity = false
+		case 3:
+			intensity, userData = false, false
+		}
+	}
+
+	layout := &PointLayout{Format: format, RecordLength: recordLength, Extended: format >= 6,
+		Intensity: -1, UserData: -1, GPSTime: -1, RGB: -1, NIR: -1, WavePacket: -1}
+
+	// x, y and z come first in every format
+	offset := 12
+
+	if layout.Extended {
+		layout.Intensity, layout.Returns, layout.Flags, layout.Classification = 12, 14, 15, 16
+		layout.UserData, layout.ScanAngle, layout.PointSource, layout.GPSTime = 17, 18, 20, 22
+
+		offset = 30
+
+		if format == 7 || format == 8 || format == 10 {
+			layout.RGB, offset = offset, offset+rgbLength
+		}
+
+		if format == 8 || format == 10 {
+			layout.NIR, offset = offset, offset+nirLength
+		}
+
+		if format == 9 || format == 10 {
+			layout.WavePacket, offset = offset, offset+wavePacketLength
+		}
+	} else {
+		if intensity {
+			layout.Intensity, offset = offset, offset+2
+		}
+
+		// the classification flags share the classification byte
+		layout.Returns, layout.Classification, layout.Flags, layout.ScanAngle = offset, offset+1, offset+1, offset+2
+		offset += 3
+
+		if userData {
+			layout.UserData, offset = offset, offset+1
+		}
+
+		layout.PointSource, offset = offset, offset+2
+
+		if format == 1 || format >= 3 {
+			layout.GPSTime, offset = offset, offset+gpsTimeLength
+		}
+
+		if format == 2 || format == 3 || format == 5 {
+			layout.RGB, offset = offset, offset+rgbLength
+		}
+
+		if format == 4 || format == 5 {
+			layout.WavePacket, offset = offset, offset+wavePacketLength
+		}
+	}
+
+	if recordLength < offset {
+		minimum := offset
+
+		if !layout.Extended {
+			// without the optional intensity and user data
+			minimum = standardLength(format) - 3
+		}
+
+		return nil, fmt.Errorf("point records of %d bytes are too short for point format %d, which needs at least %d", recordLength, format, minimum)
+	}
+
+	layout.ExtraBytes, layout.ExtraBytesLength = offset, recordLength-offset
+
+	return layout, nil
+}
+
+// PointLayout returns the layout of the point records of the file, nil if the header has no valid layout.
+func (las *LasFile) PointLayout() *PointLayout {
+	if las.pointLayout != nil {
+		return las.pointLayout
+	}
+
+	// files made from a header alone, such as mosaics, have their layout worked out when needed
+	layout, _ := NewPointLayout(int(las.Header.PointFormatID), las.Header.PointRecordLength)
+
+	return layout
+}
+
+// ExtraAttribute returns the extra bytes attribute of the specified name, nil if the records don't store it.
+func (layout *PointLayout) ExtraAttribute(name string) *ExtraBytesAttribute {
+	for i := range layout.ExtraAttributes {
+		if layout.ExtraAttributes[i].Name == name {
+			return &layout.ExtraAttributes[i]
+		}
+	}
+
+	return nil
+}
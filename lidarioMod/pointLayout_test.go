@@ -0,0 +1,137 @@
+package lidarioMod
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Offsets of the fields of each point format at its standard record length, from the tables of the LAS 1.4 specification
+var standardLayouts = []PointLayout{
+	{Format: 0, RecordLength: 20, Intensity: 12, Returns: 14, Flags: 15, Classification: 15, ScanAngle: 16, UserData: 17,
+		PointSource: 18, GPSTime: -1, RGB: -1, NIR: -1, WavePacket: -1, ExtraBytes: 20},
+	{Format: 1, RecordLength: 28, Intensity: 12, Returns: 14, Flags: 15, Classification: 15, ScanAngle: 16, UserData: 17,
+		PointSource: 18, GPSTime: 20, RGB: -1, NIR: -1, WavePacket: -1, ExtraBytes: 28},
+	{Format: 2, RecordLength: 26, Intensity: 12, Returns: 14, Flags: 15, Classification: 15, ScanAngle: 16, UserData: 17,
+		PointSource: 18, GPSTime: -1, RGB: 20, NIR: -1, WavePacket: -1, ExtraBytes: 26},
+	{Format: 3, RecordLength: 34, Intensity: 12, Returns: 14, Flags: 15, Classification: 15, ScanAngle: 16, UserData: 17,
+		PointSource: 18, GPSTime: 20, RGB: 28, NIR: -1, WavePacket: -1, ExtraBytes: 34},
+	{Format: 4, RecordLength: 57, Intensity: 12, Returns: 14, Flags: 15, Classification: 15, ScanAngle: 16, UserData: 17,
+		PointSource: 18, GPSTime: 20, RGB: -1, NIR: -1, WavePacket: 28, ExtraBytes: 57},
+	{Format: 5, RecordLength: 63, Intensity: 12, Returns: 14, Flags: 15, Classification: 15, ScanAngle: 16, UserData: 17,
+		PointSource: 18, GPSTime: 20, RGB: 28, NIR: -1, WavePacket: 34, ExtraBytes: 63},
+	{Format: 6, RecordLength: 30, Extended: true, Intensity: 12, Returns: 14, Flags: 15, Classification: 16, UserData: 17,
+		ScanAngle: 18, PointSource: 20, GPSTime: 22, RGB: -1, NIR: -1, WavePacket: -1, ExtraBytes: 30},
+	{Format: 7, RecordLength: 36, Extended: true, Intensity: 12, Returns: 14, Flags: 15, Classification: 16, UserData: 17,
+		ScanAngle: 18, PointSource: 20, GPSTime: 22, RGB: 30, NIR: -1, WavePacket: -1, ExtraBytes: 36},
+	{Format: 8, RecordLength: 38, Extended: true, Intensity: 12, Returns: 14, Flags: 15, Classification: 16, UserData: 17,
+		ScanAngle: 18, PointSource: 20, GPSTime: 22, RGB: 30, NIR: 36, WavePacket: -1, ExtraBytes: 38},
+	{Format: 9, RecordLength: 59, Extended: true, Intensity: 12, Returns: 14, Flags: 15, Classification: 16, UserData: 17,
+		ScanAngle: 18, PointSource: 20, GPSTime: 22, RGB: -1, NIR: -1, WavePacket: 30, ExtraBytes: 59},
+	{Format: 10, RecordLength: 67, Extended: true, Intensity: 12, Returns: 14, Flags: 15, Classification: 16, UserData: 17,
+		ScanAngle: 18, PointSource: 20, GPSTime: 22, RGB: 30, NIR: 36, WavePacket: 38, ExtraBytes: 67},
+}
+
+func TestNewPointLayoutStandardOffsets(t *testing.T) {
+	for _, expected := range standardLayouts {
+		if length := standardLength(expected.Format); length != expected.RecordLength {
+			t.Fatalf("format %d has a standard length of %d, expected %d", expected.Format, length, expected.RecordLength)
+		}
+
+		layout, err := NewPointLayout(expected.Format, expected.RecordLength)
+		if err != nil {
+			t.Fatalf("format %d: %v", expected.Format, err)
+		}
+
+		if !reflect.DeepEqual(*layout, expected) {
+			t.Fatalf("format %d has layout %+v, expected %+v", expected.Format, *layout, expected)
+		}
+	}
+}
+
+// Bytes after the standard fields are extra bytes, which move no other field
+func TestNewPointLayoutExtraBytes(t *testing.T) {
+	for _, standard := range standardLayouts {
+		for _, extra := range []int{1, 3, 192} {
+			layout, err := NewPointLayout(standard.Format, standard.RecordLength+extra)
+			if err != nil {
+				t.Fatalf("format %d with %d extra bytes: %v", standard.Format, extra, err)
+			}
+
+			expected := standard
+			expected.RecordLength += extra
+			expected.ExtraBytesLength = extra
+
+			if !reflect.DeepEqual(*layout, expected) {
+				t.Fatalf("format %d with %d extra bytes has layout %+v, expected %+v", standard.Format, extra, *layout, expected)
+			}
+		}
+	}
+}
+
+// Records of formats 0-5 up to 3 bytes short leave out the user data, the intensity or both, moving the fields after them
+func TestNewPointLayoutShortRecords(t *testing.T) {
+	for _, standard := range standardLayouts[:6] {
+		for _, c := range []struct {
+			short     int
+			intensity bool
+			userData  bool
+		}{{1, true, false}, {2, false, true}, {3, false, false}} {
+			layout, err := NewPointLayout(standard.Format, standard.RecordLength-c.short)
+			if err != nil {
+				t.Fatalf("format %d %d bytes short: %v", standard.Format, c.short, err)
+			}
+
+			// the fields after the left out ones move back by their length
+			move := func(offset int) int {
+				moved := offset
+				if !c.intensity && offset > 12 {
+					moved -= 2
+				}
+				if !c.userData && offset > 17 {
+					moved -= 1
+				}
+				return moved
+			}
+
+			expected := standard
+			expected.RecordLength -= c.short
+			expected.Intensity, expected.UserData = -1, -1
+
+			if c.intensity {
+				expected.Intensity = 12
+			}
+			if c.userData {
+				expected.UserData = move(17)
+			}
+
+			for _, offset := range []*int{&expected.Returns, &expected.Flags, &expected.Classification, &expected.ScanAngle,
+				&expected.PointSource, &expected.GPSTime, &expected.RGB, &expected.WavePacket, &expected.ExtraBytes} {
+				if *offset >= 0 {
+					*offset = move(*offset)
+				}
+			}
+
+			if !reflect.DeepEqual(*layout, expected) {
+				t.Fatalf("format %d %d bytes short has layout %+v, expected %+v", standard.Format, c.short, *layout, expected)
+			}
+		}
+	}
+}
+
+func TestNewPointLayoutRejectsInvalidRecords(t *testing.T) {
+	for _, c := range []struct {
+		format       int
+		recordLength int
+	}{
+		// more than the optional intensity and user data short
+		{0, 16}, {3, 30}, {5, 59},
+		// formats 6-10 always store every standard field
+		{6, 29}, {8, 37}, {10, 66},
+		// formats outside the specification
+		{-1, 20}, {11, 67}, {99, 100},
+	} {
+		if layout, err := NewPointLayout(c.format, c.recordLength); err == nil {
+			t.Fatalf("format %d with records of %d bytes has layout %+v, expected an error", c.format, c.recordLength, *layout)
+		}
+	}
+}
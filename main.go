@@ -1,20 +1,67 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
 	"github.com/Jacob4649/go-voxelize/go-voxelize/voxels"
 )
 
+// What a command outputs
+type outputKind int
+
+// Kinds of output
+const (
+	// voxels, with their attributes if tracked
+	voxelOutput outputKind = iota
+
+	// measurements of each column
+	measurementOutput
+
+	// number of voxels at each height
+	gradientOutput
+)
+
+// Gets the name of a kind of output
+func(kind outputKind) String() string {
+	switch kind {
+	case measurementOutput:
+		return "metrics"
+	case gradientOutput:
+		return "profile"
+	default:
+		return "voxels"
+	}
+}
+
+// Output written to a file
+type outputFile struct {
+
+	// what to output
+	kind outputKind
+
+	// file to write the output to
+	fileName string
+
+}
+
 // Arguments passed to run the program
 type executionArgs struct {
 	
-	// input file name
-	fileName string
+	// input file names
+	fileNames []string
 
 	// output file name
 	destName string
@@ -31,239 +78,1421 @@ type executionArgs struct {
 	// voxel size to use
 	voxelSize float64
 
+	// grid to voxelize onto
+	grid voxels.VoxelGrid
+
 	// whether to normalize
 	normalize bool
 
-	// whether to convert to a gradient
-	gradient bool
+	// what to output
+	output outputKind
 
-	// whether to convert to measurements
-	measurements bool
+	// other outputs to write from the same voxels
+	alsoOutputs []outputFile
+
+	// whether to write the outputs at the same time
+	concurrentOutputs bool
 
 	// where to output minimum heights
 	minimumImagePath string
 
-	// whether to split into sources
-	splitSources bool
+	// key to split points into groups by, nil to not split them
+	splitKey lasProcessing.SplitKey
+
+	// most groups to post process at the same time
+	splitConcurrency int
+
+	// where to write the manifest of the outputs of each group, empty to name it after the output
+	manifestPath string
+
+	// whether to aggregate point attributes for each voxel
+	attributes bool
+
+	// extra bytes attributes to average over each voxel with the other attributes
+	extraAttributes []string
+
+	// whether to filter ground for normalization and measurements
+	ground bool
+
+	// whether to use ground classified points for the ground when present
+	groundClass bool
+
+	// ground filter to use
+	groundFilter voxels.GroundFilter
+
+	// how outputs relate voxels to the CRS of the input file
+	georeferencing voxels.Georeferencing
+
+	// how long to run for before cancelling, 0 for no limit
+	timeout time.Duration
+
+	// how to report progress
+	progress lasProcessing.ProgressReporter
+
+	// side length of the tiles to process separately, 0 to process everything at once
+	tileSize float64
+
+	// buffer processed around each tile
+	tileBuffer float64
+
+	// points to voxelize
+	pointFilters lasProcessing.PointFilters
+
+	// columns to process, nil to process every column
+	region *voxels.Region
+
+	// features to clip to one at a time, nil to not process features separately
+	clipFeatures []lasProcessing.ClipFeature
+
+	// attribute naming the output of each feature
+	clipEach string
+
+	// how to describe the inputs, text or json
+	infoFormat string
+
+	// post processing pipeline to use instead of the one chosen by the output, nil to choose one
+	pipeline *lasProcessing.PipelineConfig
 }
 
-// parses the specified arguments
-func parseArgs() executionArgs {
-	
-	destName := flag.String("output", "output.csv", "the file to output results to")
+// gets every output of a configuration, the main output first
+func(config *executionArgs) outputFiles() []outputFile {
+	return append([]outputFile{{kind: config.output, fileName: config.destName}}, config.alsoOutputs...)
+}
+
+// prefixes the names of every output file and image of a configuration
+func(config *executionArgs) prefixOutputs(prefix string) {
+	config.destName = prefixedName(prefix, config.destName)
+
+	prefixed := make([]outputFile, len(config.alsoOutputs))
+
+	for i, output := range config.alsoOutputs {
+		prefixed[i] = outputFile{kind: output.kind, fileName: prefixedName(prefix, output.fileName)}
+	}
+
+	config.alsoOutputs = prefixed
+
+	if config.minimumImagePath != "" {
+		config.minimumImagePath = prefixedName(prefix, config.minimumImagePath)
+	}
+}
+
+// whether a file name is for a LAS or LAZ file
+func isLAS(fileName string) bool {
+	extension := strings.ToLower(filepath.Ext(fileName))
+
+	return extension == ".las" || extension == ".laz"
+}
 
-	concurrency := flag.Int("concurrency", 32, "how many concurrent reads and processes to use")
+// expands input arguments, which can be files, globs or directories of LAS and LAZ files, into file names
+func expandInputs(args []string) ([]string, error) {
+	fileNames := make([]string, 0)
 
-	chunkNumber := flag.Int("chunks", 256, "how many chunks to split the file into")
+	seen := make(map[string]bool)
 
-	density := flag.Int("density", 20, "point density in a voxel to be filled")
+	add := func(fileName string) {
+		if !seen[fileName] {
+			seen[fileName] = true
+			fileNames = append(fileNames, fileName)
+		}
+	}
 
-	voxelSize := flag.Float64("voxel", 0.1, "side length for a voxel")
+	for _, arg := range args {
+		info, err := os.Stat(arg)
 
-	normalize := flag.Bool("normalize", false, "whether to normalize output")
+		if err == nil && info.IsDir() {
+			entries, err := os.ReadDir(arg)
 
-	gradient := flag.Bool("gradient", false, "whether to convert output to a height gradient")
+			if err != nil {
+				return nil, err
+			}
 
-	measurements := flag.Bool("measurements", false, "whether to convert output to measurements")
+			// entries are sorted by name
+			for _, entry := range entries {
+				if !entry.IsDir() && isLAS(entry.Name()) {
+					add(filepath.Join(arg, entry.Name()))
+				}
+			}
+
+			continue
+		}
 
-	minimumImagePath := flag.String("minimum-output", "", "file path to output the PNG minimums image")
+		if err == nil {
+			add(arg)
+			continue
+		}
 
-	splitSources := flag.Bool("split-sources", false, "whether to split input by source before processing")
+		matches, err := filepath.Glob(arg)
 
-	flag.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("input %q: %w", arg, err)
+		}
 
-	fileName := flag.Arg(0)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input files match %q", arg)
+		}
 
-	if fileName == "" {
-		print("must define an input file")
-		os.Exit(0)
+		for _, match := range matches {
+			add(match)
+		}
 	}
 
-	return executionArgs{fileName: fileName, destName: *destName, 
-		concurrency: *concurrency, chunkNumber: *chunkNumber, density: *density, voxelSize: *voxelSize,
-		normalize: *normalize, gradient: *gradient, minimumImagePath: *minimumImagePath, splitSources: *splitSources,
-		measurements: *measurements}
+	return fileNames, nil
 }
 
-// performs the main processing of the LAS file
-func mainProcessing[O any](file *lidarioMod.LasFile, processor lasProcessing.LASProcessor[O], config executionArgs) *O {
-	chunks := lasProcessing.ChunkFile(file, config.chunkNumber)
+// opens the input files, checking that they all have the same CRS if they have to share one
+func openInputs(fileNames []string, shareCRS bool) ([]*lidarioMod.LasFile, error) {
+	files := make([]*lidarioMod.LasFile, 0, len(fileNames))
 
-	status := lasProcessing.NewConcurrentStatus()
+	for _, fileName := range fileNames {
+		file, err := lidarioMod.NewLasFile(fileName, "rh")
+
+		if err != nil {
+			closeInputs(files)
+			return nil, fmt.Errorf("%v: %w", fileName, err)
+		}
 
-	quit := false
+		files = append(files, file)
+	}
 
-	uiDone := make(chan bool)
+	if !shareCRS {
+		return files, nil
+	}
 
-	go lasProcessing.CLIStatus(status, &quit, uiDone)
+	crs := voxels.FileCRS(files[0])
 
-	output := lasProcessing.ConcurrentProcess(file, chunks, processor, config.concurrency, status)
+	for _, file := range files[1:] {
+		if !crs.Equal(voxels.FileCRS(file)) {
+			closeInputs(files)
+			return nil, fmt.Errorf("%v has a different CRS to %v, inputs must share a CRS to be voxelized together", file.FileName(), files[0].FileName())
+		}
+	}
 
-	quit = true
+	return files, nil
+}
 
-	<- uiDone
+// closes the input files
+func closeInputs(files []*lidarioMod.LasFile) {
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+// gets a name for the inputs in messages
+func inputsName(config executionArgs) string {
+	if len(config.fileNames) == 1 {
+		return config.fileNames[0]
+	}
 
-	return output
+	return fmt.Sprint(len(config.fileNames)) + " files"
 }
 
-// post processes the resulting voxels
-func postProcessing[I any, O any](voxels I, pipeline lasProcessing.PostProcessingPipeline[I, O], config executionArgs) O {
-	pipelineStatus := &lasProcessing.PipelineStatus{}
+// parses the voxel grid arguments
+func parseGrid(voxelSize float64, indexing string, anchor string) (voxels.VoxelGrid, error) {
+	grid := voxels.VoxelGrid{VoxelSize: voxelSize}
 
-	quit := false
+	if voxelSize <= 0 {
+		return grid, fmt.Errorf("voxel size must be positive, got %v", voxelSize)
+	}
 
-	uiDone := make(chan bool)
+	switch indexing {
+	case "floor":
+		grid.Indexing = voxels.FloorIndexing
+	case "truncate":
+		grid.Indexing = voxels.TruncatedIndexing
+	default:
+		return grid, fmt.Errorf("unknown indexing mode %q, must be floor or truncate", indexing)
+	}
+
+	parts := strings.Split(anchor, ",")
 
-	go lasProcessing.PostProcessingStatus(pipelineStatus, &quit, uiDone)
+	if len(parts) != 3 {
+		return grid, fmt.Errorf("anchor %q must be of the form x,y,z", anchor)
+	}
 
-	err := lasProcessing.ProcessWithPipeline(voxels, pipeline, pipelineStatus)
+	values := make([]float64, 3)
 
-	quit = true
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
 
-	<- uiDone
+		if err != nil {
+			return grid, fmt.Errorf("anchor %q must be of the form x,y,z", anchor)
+		}
+
+		values[i] = value
+	}
+
+	grid.Anchor = voxels.Anchor{X: values[0], Y: values[1], Z: values[2]}
 
-	return err
+	return grid, nil
 }
 
-/// selects a post processing pipeline to use for density voxels
-func chooseDensityVoxelPipeline(config executionArgs) lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, error] {
-	var finalPipeline lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, error]
-	
-	var voxelPipeline lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet] = 
-		&voxels.VoxelCondenser{Density: config.density}
+// parses a comma separated list of classifications
+func parseClasses(list string) (map[int]bool, error) {
+	classes := make(map[int]bool)
+
+	for _, part := range strings.Split(list, ",") {
+		class, err := strconv.Atoi(strings.TrimSpace(part))
 
-	outputMinimums := config.minimumImagePath != ""
+		if err != nil || class < 0 || class > 255 {
+			return nil, fmt.Errorf("classification %q must be a number from 0 to 255", part)
+		}
 
-	minimumPipeline := &voxels.MinimumHeightFinder{
-		OuptutMinimums: outputMinimums,
-		OutputFile: config.minimumImagePath,
+		classes[class] = true
 	}
 
-	if config.normalize {
-		heightPipeline := lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet, *voxels.MinimumHeights](
-			voxelPipeline, minimumPipeline)
-	
-		voxelPipeline = lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.MinimumHeights, *voxels.VoxelSet](
-			heightPipeline, &voxels.LazyNormalizer{})
-	} else if outputMinimums {
-		heightPipeline := lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet, *voxels.MinimumHeights](
-			voxelPipeline, minimumPipeline)
-	
-		voxelPipeline = lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.MinimumHeights, *voxels.VoxelSet](
-			heightPipeline, &voxels.MinimumDegrouper{})
+	return classes, nil
+}
+
+// parses a comma separated list of returns
+func parseReturns(list string) (*lasProcessing.ReturnFilter, error) {
+	filter := &lasProcessing.ReturnFilter{Numbers: make(map[int]bool)}
+
+	for _, part := range strings.Split(list, ",") {
+		switch part = strings.TrimSpace(part); part {
+		case "first":
+			filter.First = true
+		case "last":
+			filter.Last = true
+		case "single":
+			filter.Single = true
+		case "multiple":
+			filter.Multiple = true
+		default:
+			number, err := strconv.Atoi(part)
+
+			if err != nil || number < 1 {
+				return nil, fmt.Errorf("return %q must be a return number, first, last, single or multiple", part)
+			}
+
+			filter.Numbers[number] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// parses a comma separated list of point flags to drop
+func parseFlags(list string) (*lasProcessing.FlagFilter, error) {
+	filter := &lasProcessing.FlagFilter{}
+
+	for _, part := range strings.Split(list, ",") {
+		switch strings.TrimSpace(part) {
+		case "withheld":
+			filter.Withheld = true
+		case "synthetic":
+			filter.Synthetic = true
+		case "keypoint":
+			filter.KeyPoint = true
+		case "overlap":
+			filter.Overlap = true
+		default:
+			return nil, fmt.Errorf("unknown point flag %q, must be withheld, synthetic, keypoint or overlap", part)
+		}
 	}
 
-	finalPipeline = lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet, error](
-		voxelPipeline, &voxels.VoxelFileWriter{FileName: config.destName})
+	return filter, nil
+}
+
+// parses the key to split points by, as source, class, return, user-data, channel or gps-time:<seconds>
+func parseSplitKey(value string) (lasProcessing.SplitKey, error) {
+	name, window, windowed := strings.Cut(value, ":")
 
-	if config.gradient {
-		gradientPipline := lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet, *voxels.HeightGradient](
-			voxelPipeline, &voxels.GradientProcessor{})
+	if windowed && name != "gps-time" {
+		return nil, fmt.Errorf("only gps-time splits take a window, not %q", value)
+	}
 
-		finalPipeline = lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.HeightGradient, error](
-			gradientPipline, &voxels.GradientFileWriter{FileName: config.destName})
-	} else if config.measurements {
-		measurementPipeline := lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet, *voxels.Measurements](
-			voxelPipeline, &voxels.MeasurementFinder{})
+	switch name {
+	case "source":
+		return &lasProcessing.SourceKey{}, nil
+	case "class":
+		return &lasProcessing.ClassKey{}, nil
+	case "return":
+		return &lasProcessing.ReturnKey{}, nil
+	case "user-data":
+		return &lasProcessing.UserDataKey{}, nil
+	case "channel":
+		return &lasProcessing.ChannelKey{}, nil
+	case "gps-time":
+		seconds, err := strconv.ParseFloat(window, 64)
+
+		if !windowed || err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("gps-time split %q must be of the form gps-time:<seconds>, with a positive window", value)
+		}
 
-		finalPipeline = lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.Measurements, error](
-			measurementPipeline, &voxels.MeasurementsFileWriter{FileName: config.destName})
+		return &lasProcessing.GPSTimeKey{Window: seconds}, nil
 	}
 
-	return finalPipeline
+	return nil, fmt.Errorf("unknown split %q, must be source, class, return, user-data, channel or gps-time:<seconds>", value)
 }
 
-// processes some density voxels and outputs an error
-func processDensityVoxels(file *lidarioMod.LasFile, config executionArgs) error {
-		// main processing
+// parses a min,max range, where an empty side has no limit
+func parseRange(name string, value string) (float64, float64, error) {
+	parts := strings.Split(value, ",")
 
-		processor := voxels.DensityVoxelSetProcessor{PointDensity: config.density, VoxelSize: config.voxelSize}
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%s %q must be of the form min,max", name, value)
+	}
 
-		output := mainProcessing[voxels.DensityVoxelSet](file, &processor, config)
-	
-		// post processing
-	
-		pipeline := chooseDensityVoxelPipeline(config)
-	
-		return postProcessing(output, pipeline, config)
+	limits := []float64{math.Inf(-1), math.Inf(1)}
+
+	for i, part := range parts {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		limit, err := strconv.ParseFloat(part, 64)
+
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s %q must be of the form min,max", name, value)
+		}
+
+		limits[i] = limit
+	}
+
+	return limits[0], limits[1], nil
 }
 
-// makes pipelines for processing density voxel sets from different sources
-func makeSourcesPipelines(sets []*voxels.DensityVoxelSet, config executionArgs) ([]executionArgs, []lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, error]) {
-	configs := make([]executionArgs, 0)
+// parses the point filters, empty arguments add no filter
+func parsePointFilters(classes string, dropClasses string, returns string, dropFlags string, maxScanAngle float64, intensity string, gpsTime string, zRange string, extraRanges []string) (lasProcessing.PointFilters, error) {
+	filters := lasProcessing.PointFilters{}
 
-	for i, _ := range sets {
-		copy := config
-		copy.destName = fmt.Sprint(i) + "-" + copy.destName
-		if copy.minimumImagePath != "" {
-			copy.minimumImagePath = fmt.Sprint(i) + "-" + copy.minimumImagePath
+	if classes != "" {
+		kept, err := parseClasses(classes)
+
+		if err != nil {
+			return nil, err
 		}
-		configs = append(configs, copy)
+
+		filters = append(filters, &lasProcessing.ClassFilter{Classes: kept})
 	}
 
-	pipelines := make([]lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, error], 0)
+	if dropClasses != "" {
+		dropped, err := parseClasses(dropClasses)
 
-	for _, pipelineConfig := range configs {
-		pipelines = append(pipelines, chooseDensityVoxelPipeline(pipelineConfig))
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, &lasProcessing.ClassFilter{Classes: dropped, Exclude: true})
+	}
+
+	if returns != "" {
+		filter, err := parseReturns(returns)
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if dropFlags != "" {
+		filter, err := parseFlags(dropFlags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if maxScanAngle >= 0 {
+		filters = append(filters, &lasProcessing.ScanAngleFilter{MaxAngle: maxScanAngle})
+	}
+
+	if intensity != "" {
+		min, max, err := parseRange("intensity", intensity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, &lasProcessing.IntensityFilter{Min: min, Max: max})
+	}
+
+	if gpsTime != "" {
+		min, max, err := parseRange("gps time", gpsTime)
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, &lasProcessing.GPSTimeFilter{Min: min, Max: max})
+	}
+
+	if zRange != "" {
+		min, max, err := parseRange("z", zRange)
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, &lasProcessing.ZFilter{Min: min, Max: max})
+	}
+
+	for _, extraRange := range extraRanges {
+		separator := strings.LastIndex(extraRange, "=")
+
+		if separator < 1 {
+			return nil, fmt.Errorf("extra bytes range %q must be of the form name=min,max", extraRange)
+		}
+
+		name := extraRange[:separator]
+
+		min, max, err := parseRange(name, extraRange[separator + 1:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, &lasProcessing.ExtraBytesFilter{Name: name, Min: min, Max: max})
 	}
 
-	return configs, pipelines
+	return filters, nil
 }
 
-// processes voxels by source and outputs an error
-func processSources(file *lidarioMod.LasFile, config executionArgs) {
-	// main processing
-	
-	processor := voxels.PointSourceProcessor{PointDensity: config.density, VoxelSize: config.voxelSize}
+// parses a minx,miny,maxx,maxy box
+func parseBox(value string) (*lasProcessing.BoxFilter, error) {
+	parts := strings.Split(value, ",")
 
-	output := mainProcessing[voxels.PointSourceDensityVoxelSet](file, &processor, config)
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("box %q must be of the form minx,miny,maxx,maxy", value)
+	}
 
-	// split into sets
-	
-	splitPipeline := voxels.PointSourceSplitter{}
+	limits := make([]float64, 4)
 
-	sets := postProcessing[*voxels.PointSourceDensityVoxelSet, []*voxels.DensityVoxelSet](output, &splitPipeline, config)
+	for i, part := range parts {
+		limit, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
 
-	// concurrent post processing
+		if err != nil {
+			return nil, fmt.Errorf("box %q must be of the form minx,miny,maxx,maxy", value)
+		}
 
-	configs, pipelines := makeSourcesPipelines(sets, config)
+		limits[i] = limit
+	}
+
+	if limits[0] > limits[2] || limits[1] > limits[3] {
+		return nil, fmt.Errorf("box %q has its min above its max", value)
+	}
+
+	return &lasProcessing.BoxFilter{MinX: limits[0], MinY: limits[1], MaxX: limits[2], MaxY: limits[3]}, nil
+}
+
+// restricts a configuration to the points a clip filter keeps, and to the columns covering its bounds when voxelizing
+func clipTo(config executionArgs, filter lasProcessing.PointFilter, minX float64, minY float64, maxX float64, maxY float64) executionArgs {
+	clipped := config
 
-	for i, pipeline := range pipelines {
-		set := sets[i]
-		pipelineConfig := configs[i]
-		println("Processing source " + fmt.Sprint(i))
+	// copied so that configurations clipped to different features don't share filters
+	clipped.pointFilters = append(append(lasProcessing.PointFilters{}, config.pointFilters...), filter)
+
+	// there are only columns to restrict when voxelizing
+	if config.grid.VoxelSize <= 0 {
+		return clipped
+	}
 
-		postProcessing(set, pipeline, pipelineConfig)
+	region := config.grid.BoundsRegion(minX, minY, maxX, maxY)
 
-		println("Completed processing source " + fmt.Sprint(i))
+	if config.region != nil {
+		region = region.Intersect(*config.region)
 	}
 
+	clipped.region = &region
+
+	return clipped
 }
 
-// Main function
-func main() {
+// checks that every file stores the extra bytes attributes of the specified names as numbers
+func checkExtraAttributes(files []*lidarioMod.LasFile, names []string) error {
+	for _, name := range names {
+		for _, file := range files {
+			attribute := file.PointLayout().ExtraAttribute(name)
 
-	config := parseArgs()
+			if attribute == nil {
+				return fmt.Errorf("%s has no extra bytes attribute %q", file.FileName(), name)
+			}
 
-	file, err := lidarioMod.NewLasFile(config.fileName, "rh")
+			if !attribute.Scalar() {
+				return fmt.Errorf("extra bytes attribute %q of %s is not a number (%s)", name, file.FileName(), attribute.TypeName)
+			}
+		}
+	}
 
-	if err != nil {
-		println("Error accessing LAS file")
-		os.Exit(1)
+	return nil
+}
+
+// checks that every file stores the fields the point filters read
+func checkPointFilters(files []*lidarioMod.LasFile, filters lasProcessing.PointFilters) error {
+	for _, filter := range filters {
+		switch filter := filter.(type) {
+		case *lasProcessing.GPSTimeFilter:
+			for _, file := range files {
+				if !lasProcessing.HasGPSTime(file) {
+					return fmt.Errorf("%s has no GPS time to filter by (point format %d)", file.FileName(), file.Header.PointFormatID)
+				}
+			}
+		case *lasProcessing.ExtraBytesFilter:
+			if err := checkExtraAttributes(files, []string{filter.Name}); err != nil {
+				return err
+			}
+		}
 	}
 
-	if config.splitSources {
-		processSources(file, config)
-	} else {
-		err = processDensityVoxels(file, config)
+	return nil
+}
+
+// whether a file is an interactive terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+
+	return err == nil && info.Mode() & os.ModeCharDevice != 0
+}
+
+// parses the progress reporting arguments
+func parseProgress(mode string, interval time.Duration) (lasProcessing.ProgressReporter, error) {
+	if mode == "auto" {
+		mode = "plain"
+		if isTerminal(os.Stdout) {
+			mode = "ansi"
+		}
 	}
 
-	// processing finished
-	
-	if err != nil {
-		println("Error writing to csv")
-		os.Exit(1)
+	if interval <= 0 {
+		return nil, fmt.Errorf("progress interval must be positive")
+	}
+
+	switch mode {
+	case "ansi":
+		return &lasProcessing.ANSIReporter{}, nil
+	case "plain":
+		return &lasProcessing.PlainReporter{Output: os.Stdout, Interval: interval}, nil
+	case "json":
+		return &lasProcessing.JSONReporter{Output: os.Stdout, Interval: interval}, nil
+	case "none":
+		return &lasProcessing.SilentReporter{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown progress mode %q, must be auto, ansi, plain, json or none", mode)
+}
+
+// performs the main processing of the LAS file
+func mainProcessing[O any](ctx context.Context, files []*lidarioMod.LasFile, processor lasProcessing.LASProcessor[O], config executionArgs) (*O, error) {
+	file := files[0]
+
+	chunks := lasProcessing.ChunkFile(file, config.chunkNumber)
+
+	// several files are processed as one dataset, on a grid covering all of them
+	if len(files) > 1 {
+		file = lasProcessing.MosaicFile(files)
+		chunks = lasProcessing.ChunkFiles(files, config.chunkNumber)
+	}
+
+	return processChunks(ctx, file, chunks, processor, config)
+}
+
+// processes chunks of the inputs, reporting progress, where file has the header of the inputs
+func processChunks[O any](ctx context.Context, file *lidarioMod.LasFile, chunks []*lasProcessing.LASChunk, processor lasProcessing.LASProcessor[O], config executionArgs) (*O, error) {
+	status := lasProcessing.NewConcurrentStatus()
+
+	uiDone := make(chan bool)
+
+	go config.progress.Processing(status, uiDone)
+
+	output, err := lasProcessing.ConcurrentProcess(ctx, file, chunks, processor, config.concurrency, status)
+
+	<- uiDone
+
+	return output, err
+}
+
+// post processes the resulting voxels
+func postProcessing[I any, O any](ctx context.Context, voxels I, pipeline lasProcessing.PostProcessingPipeline[I, O], config executionArgs) (O, error) {
+	pipelineStatus := &lasProcessing.PipelineStatus{}
+
+	uiDone := make(chan bool)
+
+	go config.progress.PostProcessing(pipelineStatus, uiDone)
+
+	output, err := lasProcessing.ProcessWithPipeline(ctx, voxels, pipeline, pipelineStatus)
+
+	<- uiDone
+
+	return output, err
+}
+
+// whether a file name is for a TIFF
+func isTiff(fileName string) bool {
+	extension := strings.ToLower(filepath.Ext(fileName))
+
+	return extension == ".tif" || extension == ".tiff"
+}
+
+// selects the post processing stage finding the heights voxels are measured from (nil if heights are not needed)
+func chooseHeightStage(config executionArgs) lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, *voxels.MinimumHeights] {
+	return heightStageWritingTo(config, config.minimumImagePath)
+}
+
+// selects the post processing stage finding the heights voxels are measured from, writing the heights to an image
+// if there is a path (nil if heights are not needed)
+func heightStageWritingTo(config executionArgs, imagePath string) lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, *voxels.MinimumHeights] {
+	if config.ground {
+		groundFilter := config.groundFilter
+		groundFilter.OutputFile = imagePath
+
+		return &groundFilter
+	} else if config.normalize || config.minimumImagePath != "" {
+		return &voxels.MinimumHeightFinder{
+			OuptutMinimums: imagePath != "",
+			OutputFile: imagePath,
+		}
+	}
+
+	return nil
+}
+
+// selects the post processing stage from heights back to voxels
+func chooseHeightsToVoxels(config executionArgs) lasProcessing.PostProcessingPipeline[*voxels.MinimumHeights, *voxels.VoxelSet] {
+	if config.normalize {
+		return &voxels.LazyNormalizer{}
+	}
+
+	return &voxels.MinimumDegrouper{}
+}
+
+// selects a writer for measurements to a file
+func chooseMeasurementWriter(config executionArgs, fileName string) lasProcessing.PostProcessingPipeline[*voxels.Measurements, string] {
+	if isTiff(fileName) {
+		return &voxels.MeasurementsTiffWriter{FileName: fileName, CRS: config.georeferencing.CRS}
 	}
 
-	println("Complete")
-}
\ No newline at end of file
+	return &voxels.MeasurementsFileWriter{FileName: fileName, Georeferencing: config.georeferencing}
+}
+
+// Last stages of each output of post processing, which write the output to its file, or collect it over tiles
+type outputStages struct {
+
+	// crops voxels before they are written or turned into a profile, nil to keep every voxel
+	cropVoxels lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, *voxels.VoxelSet]
+
+	// finishes the voxels of the output at an index
+	voxelStage func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string]
+
+	// finishes the profile of the output at an index
+	gradientStage func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.HeightGradient, string]
+
+	// finishes the measurements of the output at an index
+	measurementStage func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.Measurements, string]
+
+	// collects the heights instead of writing them to the minimum image, nil to write the image
+	heights lasProcessing.PostProcessingPipeline[*voxels.MinimumHeights, *voxels.MinimumHeights]
+
+}
+
+// gets the stages writing each output to its file
+func writerStages(config executionArgs) outputStages {
+	return outputStages{
+		voxelStage: func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string] {
+			return &voxels.VoxelFileWriter{FileName: output.fileName, Attributes: config.attributes, ExtraAttributes: config.extraAttributes,
+				Georeferencing: config.georeferencing}
+		},
+		gradientStage: func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.HeightGradient, string] {
+			return &voxels.GradientFileWriter{FileName: output.fileName, Georeferencing: config.georeferencing}
+		},
+		measurementStage: func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.Measurements, string] {
+			return chooseMeasurementWriter(config, output.fileName)
+		}}
+}
+
+// crops voxels before a branch, if the stages crop voxels
+func(stages *outputStages) cropped(branch lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string]) lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string] {
+	if stages.cropVoxels == nil {
+		return branch
+	}
+
+	return lasProcessing.ChainPipeline[*voxels.VoxelSet, *voxels.VoxelSet, string](stages.cropVoxels, branch)
+}
+
+// selects a post processing pipeline from voxels to the output at an index
+func chooseVoxelBranch(stages outputStages, index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string] {
+	switch output.kind {
+	case gradientOutput:
+		return stages.cropped(lasProcessing.ChainPipeline[*voxels.VoxelSet, *voxels.HeightGradient, string](
+			&voxels.GradientProcessor{}, stages.gradientStage(index, output)))
+	case measurementOutput:
+		return lasProcessing.ChainPipeline[*voxels.VoxelSet, *voxels.Measurements, string](
+			&voxels.MeasurementFinder{}, stages.measurementStage(index, output))
+	default:
+		return stages.cropped(stages.voxelStage(index, output))
+	}
+}
+
+// selects a post processing pipeline from heights to the output at an index
+func chooseHeightsBranch(config executionArgs, stages outputStages, index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.MinimumHeights, string] {
+	if output.kind == measurementOutput && config.ground {
+		// measure against the ground itself, rather than the lowest voxel of each column
+		return lasProcessing.ChainPipeline[*voxels.MinimumHeights, *voxels.Measurements, string](
+			&voxels.GroundMeasurementFinder{}, stages.measurementStage(index, output))
+	}
+
+	return lasProcessing.ChainPipeline[*voxels.MinimumHeights, *voxels.VoxelSet, string](
+		chooseHeightsToVoxels(config), chooseVoxelBranch(stages, index, output))
+}
+
+// Joins the names of the files written by the branches of a pipeline
+type joinedNames struct {
+
+}
+
+// joins the names
+func(joined *joinedNames) Process(ctx context.Context, names []string, status *lasProcessing.PipelineStatus) (string, error) {
+	return strings.Join(names, ", "), nil
+}
+
+// combines the branches writing each output into one pipeline, fanning out if there are several
+func combineBranches[I any](config executionArgs, branches []lasProcessing.PostProcessingPipeline[I, string]) lasProcessing.PostProcessingPipeline[I, string] {
+	if len(branches) == 1 {
+		return branches[0]
+	}
+
+	return lasProcessing.ChainPipeline[I, []string, string](
+		lasProcessing.FanOut(config.concurrentOutputs, branches...), &joinedNames{})
+}
+
+// selects a post processing pipeline to use for density voxels, condensing them once for every output
+func chooseDensityVoxelPipeline(config executionArgs) lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, string] {
+	return densityVoxelPipelineTo(config, writerStages(config))
+}
+
+// makes a post processing pipeline for density voxels ending in the specified stages for each output, condensing
+// the voxels and finding their heights once for every output
+func densityVoxelPipelineTo(config executionArgs, stages outputStages) lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, string] {
+	condenser := &voxels.VoxelCondenser{Density: config.density}
+
+	heightStage := chooseHeightStage(config)
+
+	if stages.heights != nil {
+		heightStage = heightStageWritingTo(config, "")
+	}
+
+	if heightStage == nil {
+		branches := make([]lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string], 0)
+
+		for i, output := range config.outputFiles() {
+			branches = append(branches, chooseVoxelBranch(stages, i, output))
+		}
+
+		return lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet, string](
+			condenser, combineBranches(config, branches))
+	}
+
+	// heights are found once and shared by every output
+	branches := make([]lasProcessing.PostProcessingPipeline[*voxels.MinimumHeights, string], 0)
+
+	for i, output := range config.outputFiles() {
+		branches = append(branches, chooseHeightsBranch(config, stages, i, output))
+	}
+
+	heightPipeline := lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet, *voxels.MinimumHeights](
+		condenser, heightStage)
+
+	if stages.heights != nil {
+		heightPipeline = lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.MinimumHeights, *voxels.MinimumHeights](
+			heightPipeline, stages.heights)
+	}
+
+	return lasProcessing.ChainPipeline[*voxels.DensityVoxelSet, *voxels.MinimumHeights, string](
+		heightPipeline, combineBranches(config, branches))
+}
+
+// makes a processor for density voxels, limited to the columns in a region if it is not nil
+func densityVoxelProcessor(config executionArgs, region *voxels.Region) lasProcessing.LASProcessor[voxels.DensityVoxelSet] {
+	processor := voxels.DensityVoxelSetProcessor{PointDensity: config.density, VoxelGrid: config.grid, Region: region,
+		PointFilters: config.pointFilters}
+
+	// ground classification is tracked with the other attributes
+	if config.attributes || config.groundClass {
+		return &voxels.AttributeVoxelSetProcessor{DensityVoxelSetProcessor: processor, ExtraAttributes: config.extraAttributes}
+	}
+
+	return &processor
+}
+
+// processes some density voxels and outputs an error
+func processDensityVoxels(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error {
+		// main processing
+
+		processor := densityVoxelProcessor(config, config.region)
+
+		if config.region != nil {
+			files = filesInRegion(files, config.grid, *config.region)
+
+			if len(files) == 0 {
+				return fmt.Errorf("no input covers the clipped area")
+			}
+		}
+
+		output, err := mainProcessing[voxels.DensityVoxelSet](ctx, files, processor, config)
+
+		if err != nil {
+			return fmt.Errorf("processing %v: %w", inputsName(config), err)
+		}
+	
+		// post processing
+	
+		pipeline, err := densityVoxelPipeline(config)
+
+		if err != nil {
+			return err
+		}
+	
+		_, err = postProcessing(ctx, output, pipeline, config)
+
+		return err
+}
+
+// gets the files with points in a region
+func filesInRegion(files []*lidarioMod.LasFile, grid voxels.VoxelGrid, region voxels.Region) []*lidarioMod.LasFile {
+	inRegion := make([]*lidarioMod.LasFile, 0)
+
+	for _, file := range files {
+		overlap := region.Intersect(grid.FileRegion(&file.Header))
+
+		if file.Header.NumberPoints > 0 && !overlap.Empty() {
+			inRegion = append(inRegion, file)
+		}
+	}
+
+	return inRegion
+}
+
+// Adds the output of a tile to the outputs of the tiles before it
+type tileCollector[T any] struct {
+
+	// adds the output of a tile
+	add func(output T)
+
+}
+
+// adds the output of a tile, outputting no file name as outputs are written once every tile is processed
+func(collector *tileCollector[T]) Process(ctx context.Context, output T, status *lasProcessing.PipelineStatus) (string, error) {
+	collector.add(output)
+
+	return "", nil
+}
+
+// Adds the heights of the core columns of a tile to the heights of every tile, passing the heights on
+type tileHeightCollector struct {
+
+	// columns of the tile without its buffer
+	core voxels.Region
+
+	// heights of every tile
+	heights map[voxels.XYPair]int
+
+}
+
+// adds the heights of the core columns of a tile
+func(collector *tileHeightCollector) Process(ctx context.Context, heights *voxels.MinimumHeights, status *lasProcessing.PipelineStatus) (*voxels.MinimumHeights, error) {
+	for xy, height := range heights.Heights {
+		if collector.core.Contains(xy.X, xy.Y) {
+			collector.heights[xy] = height
+		}
+	}
+
+	return heights, nil
+}
+
+// indexes the chunks of the inputs by the bounds of their points, so that tiles only read the chunks with points
+// in them. Only inputs overlapping more than one tile are read to index them, the others are read by one tile anyway.
+func indexTileChunks(ctx context.Context, files []*lidarioMod.LasFile, tiles []voxels.Tile, config executionArgs) (*lasProcessing.ChunkIndex, error) {
+	index := lasProcessing.NewChunkIndex(files)
+
+	spanning := make(map[*lidarioMod.LasFile]bool)
+
+	for _, file := range files {
+		overlapping := 0
+
+		for _, tile := range tiles {
+			overlap := tile.Buffered.Intersect(config.grid.FileRegion(&file.Header))
+
+			if !tile.Core.Empty() && !overlap.Empty() {
+				overlapping += 1
+			}
+		}
+
+		spanning[file] = overlapping > 1
+	}
+
+	chunks := make([]*lasProcessing.LASChunk, 0)
+
+	for _, bounds := range index.Chunks {
+		if spanning[bounds.Chunk.File] {
+			chunks = append(chunks, bounds.Chunk)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return index, nil
+	}
+
+	println("Indexing " + fmt.Sprint(len(chunks)) + " chunks")
+
+	bounds, err := processChunks[[]lasProcessing.ChunkBounds](ctx, lasProcessing.MosaicFile(files), chunks,
+		&lasProcessing.ChunkBoundsProcessor{}, config)
+
+	if err != nil {
+		return nil, fmt.Errorf("indexing %v: %w", inputsName(config), err)
+	}
+
+	index.Narrow(*bounds)
+
+	return index, nil
+}
+
+// processes density voxels tile by tile, stitching the output of each tile together, and outputs an error
+func processTiles(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error {
+	mosaic := lasProcessing.MosaicFile(files)
+
+	tiles := config.grid.Tiles(&mosaic.Header, config.tileSize, config.tileBuffer)
+
+	if config.region != nil && len(filesInRegion(files, config.grid, *config.region)) == 0 {
+		return fmt.Errorf("no input covers the clipped area")
+	}
+
+	// only the clipped columns of each tile are processed
+	if config.region != nil {
+		for i, tile := range tiles {
+			tiles[i].Core, tiles[i].Buffered = tile.Core.Intersect(*config.region), tile.Buffered.Intersect(*config.region)
+		}
+	}
+
+	index, err := indexTileChunks(ctx, files, tiles, config)
+
+	if err != nil {
+		return err
+	}
+
+	outputs := config.outputFiles()
+
+	// column and height outputs are small enough to collect over every tile
+	measurements := make([]*voxels.Measurements, len(outputs))
+
+	gradients := make([]*voxels.HeightGradient, len(outputs))
+
+	for j := range outputs {
+		measurements[j], gradients[j] = voxels.EmptyMeasurements(config.grid), voxels.EmptyHeightGradient(config.grid)
+	}
+
+	// voxels are appended to their files after the first tile
+	appending := false
+
+	// heights of every tile, for the minimum image
+	heights := make(map[voxels.XYPair]int)
+
+	for i, tile := range tiles {
+		if tile.Core.Empty() {
+			continue
+		}
+
+		tileFiles := filesInRegion(files, config.grid, tile.Buffered)
+
+		if len(tileFiles) == 0 {
+			continue
+		}
+
+		println("Processing tile " + fmt.Sprint(i + 1) + " / " + fmt.Sprint(len(tiles)))
+
+		buffered := tile.Buffered
+
+		// only the chunks with points in the tile are read
+		chunks := index.Select(func(bounds *lasProcessing.ChunkBounds) bool {
+			overlap := buffered.Intersect(config.grid.BoundsRegion(bounds.MinX, bounds.MinY, bounds.MaxX, bounds.MaxY))
+
+			return !overlap.Empty()
+		}, config.chunkNumber)
+
+		output, err := processChunks[voxels.DensityVoxelSet](ctx, lasProcessing.MosaicFile(tileFiles), chunks,
+			densityVoxelProcessor(config, &buffered), config)
+
+		if err != nil {
+			return fmt.Errorf("processing tile %v of %v: %w", i, inputsName(config), err)
+		}
+
+		// the buffer is processed like the rest of the tile, then cropped off
+		stages := outputStages{
+			cropVoxels: &voxels.VoxelCropper{Region: tile.Core},
+			voxelStage: func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string] {
+				return &voxels.VoxelFileWriter{FileName: output.fileName, Attributes: config.attributes,
+					ExtraAttributes: config.extraAttributes, Georeferencing: config.georeferencing, Append: appending}
+			},
+			gradientStage: func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.HeightGradient, string] {
+				return &tileCollector[*voxels.HeightGradient]{add: gradients[index].Add}
+			},
+			measurementStage: func(index int, output outputFile) lasProcessing.PostProcessingPipeline[*voxels.Measurements, string] {
+				return lasProcessing.ChainPipeline[*voxels.Measurements, *voxels.Measurements, string](
+					&voxels.MeasurementsCropper{Region: tile.Core}, &tileCollector[*voxels.Measurements]{add: measurements[index].Add})
+			}}
+
+		// the heights are written to one image once every tile is processed
+		if config.minimumImagePath != "" {
+			stages.heights = &tileHeightCollector{core: tile.Core, heights: heights}
+		}
+
+		// the points of the tile are read once and post processed for every output
+		_, err = postProcessing(ctx, output, densityVoxelPipelineTo(config, stages), config)
+
+		if err != nil {
+			return fmt.Errorf("tile %v: %w", i, err)
+		}
+
+		appending = true
+	}
+
+	if config.minimumImagePath != "" {
+		extent := densityVoxelProcessor(config, config.region).EmptyOutput(mosaic)
+
+		minimums := &voxels.MinimumHeights{Heights: heights, Voxels: &voxels.VoxelSet{XMin: extent.XMin, YMin: extent.YMin,
+			ZMin: extent.ZMin, XVoxels: extent.XVoxels, YVoxels: extent.YVoxels, ZVoxels: extent.ZVoxels, Grid: extent.Grid}}
+
+		_, err = postProcessing[*voxels.MinimumHeights, string](ctx, minimums, &voxels.MinimumsImageWriter{FileName: config.minimumImagePath}, config)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for j, outputFile := range outputs {
+		var err error
+
+		if outputFile.kind == gradientOutput {
+			_, err = postProcessing[*voxels.HeightGradient, string](ctx, gradients[j],
+				&voxels.GradientFileWriter{FileName: outputFile.fileName, Georeferencing: config.georeferencing}, config)
+		} else if outputFile.kind == measurementOutput {
+			_, err = postProcessing(ctx, measurements[j], chooseMeasurementWriter(config, outputFile.fileName), config)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prefixes the file name of a path
+func prefixedName(prefix string, path string) string {
+	return filepath.Join(filepath.Dir(path), prefix + "-" + filepath.Base(path))
+}
+
+// names the output of each clip feature by an attribute, or by its position if it does not have the attribute
+func featureNames(features []lasProcessing.ClipFeature, attribute string) ([]string, error) {
+	names := make([]string, len(features))
+
+	used := make(map[string]int)
+
+	for i, feature := range features {
+		name := feature.Attribute(attribute)
+
+		if name == "" {
+			name = fmt.Sprint(i + 1)
+		}
+
+		// names are used in file names
+		name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+
+		if previous, contains := used[name]; contains {
+			return nil, fmt.Errorf("features %d and %d are both named %q", previous + 1, i + 1, name)
+		}
+
+		used[name] = i
+		names[i] = name
+	}
+
+	return names, nil
+}
+
+// processes density voxels clipped to each feature separately, writing an output for each, and outputs an error
+func processFeatures(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error {
+	names, err := featureNames(config.clipFeatures, config.clipEach)
+
+	if err != nil {
+		return err
+	}
+
+	for i, feature := range config.clipFeatures {
+		filter := lasProcessing.NewPolygonFilter(feature.Polygons)
+
+		minX, minY, maxX, maxY := filter.Bounds()
+
+		featureConfig := clipTo(config, filter, minX, minY, maxX, maxY)
+
+		featureConfig.prefixOutputs(names[i])
+
+		featureFiles := filesInRegion(files, config.grid, config.grid.BoundsRegion(minX, minY, maxX, maxY))
+
+		if len(featureFiles) == 0 {
+			println("Skipping feature " + names[i] + ", no input covers it")
+			continue
+		}
+
+		println("Processing feature " + names[i])
+
+		if config.tileSize > 0 {
+			err = processTiles(ctx, featureFiles, featureConfig)
+		} else {
+			err = processDensityVoxels(ctx, featureFiles, featureConfig)
+		}
+
+		if err != nil {
+			return fmt.Errorf("feature %v: %w", names[i], err)
+		}
+	}
+
+	return nil
+}
+
+// makes pipelines for processing the density voxel sets of each group of a split, naming their outputs by group
+func makeSplitPipelines(sets []*voxels.KeyedDensityVoxelSet, config executionArgs) ([]executionArgs, []lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, string], error) {
+	configs := make([]executionArgs, 0)
+
+	for _, set := range sets {
+		copy := config
+		copy.prefixOutputs(set.Label)
+		configs = append(configs, copy)
+	}
+
+	pipelines := make([]lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, string], 0)
+
+	for _, pipelineConfig := range configs {
+		pipeline, err := densityVoxelPipeline(pipelineConfig)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pipelines = append(pipelines, pipeline)
+	}
+
+	return configs, pipelines, nil
+}
+
+// File written for a group
+type manifestOutput struct {
+
+	// what the file holds, voxels, metrics, profile, pipeline or minimums
+	Kind string `json:"kind"`
+
+	// name of the file
+	File string `json:"file"`
+
+}
+
+// Outputs written for a group of a split
+type manifestGroup struct {
+
+	// key of the points in the group
+	Key int `json:"key"`
+
+	// name of the group, prefixed to its outputs
+	Label string `json:"label"`
+
+	// number of points in the group
+	Points int `json:"points"`
+
+	// number of voxels with points in the group, filled or not
+	Voxels int `json:"voxels"`
+
+	// files written for the group
+	Outputs []manifestOutput `json:"outputs"`
+
+}
+
+// Outputs written for every group of a split
+type manifest struct {
+
+	// name of the key points were split by
+	SplitBy string `json:"split_by"`
+
+	// groups in order of key
+	Groups []manifestGroup `json:"groups"`
+
+}
+
+// names the manifest of the outputs of each group after the output, unless it is set
+func(config *executionArgs) manifestName() string {
+	if config.manifestPath != "" {
+		return config.manifestPath
+	}
+
+	return strings.TrimSuffix(config.destName, filepath.Ext(config.destName)) + "-split.json"
+}
+
+// describes the outputs written for a group
+func describeGroup(set *voxels.KeyedDensityVoxelSet, config executionArgs) manifestGroup {
+	group := manifestGroup{Key: set.Key, Label: set.Label, Voxels: set.Voxels.Voxels.Len(), Outputs: make([]manifestOutput, 0)}
+
+	set.Voxels.Voxels.Range(func(coordinate voxels.Coordinate, density int) bool {
+		group.Points += density
+		return true
+	})
+
+	if config.pipeline != nil {
+		group.Outputs = append(group.Outputs, manifestOutput{Kind: "pipeline", File: config.destName})
+	} else {
+		for _, output := range config.outputFiles() {
+			group.Outputs = append(group.Outputs, manifestOutput{Kind: output.kind.String(), File: output.fileName})
+		}
+	}
+
+	if config.minimumImagePath != "" {
+		group.Outputs = append(group.Outputs, manifestOutput{Kind: "minimums", File: config.minimumImagePath})
+	}
+
+	return group
+}
+
+// writes the manifest of the outputs written for each group
+func writeManifest(fileName string, contents manifest) error {
+	encoded, err := json.MarshalIndent(contents, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, append(encoded, '\n'), 0644)
+}
+
+// processes voxels split into groups by a key of their points, post processing several groups at a time,
+// and outputs an error
+func processSplit(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error {
+	// main processing
+	
+	// ground classification is tracked with the other attributes
+	processor := voxels.SplitProcessor{PointDensity: config.density, VoxelGrid: config.grid, PointFilters: config.pointFilters,
+		Key: config.splitKey, Attributes: config.attributes || config.groundClass, ExtraAttributes: config.extraAttributes}
+
+	output, err := mainProcessing[voxels.SplitDensityVoxelSet](ctx, files, &processor, config)
+
+	if err != nil {
+		return fmt.Errorf("processing %v: %w", inputsName(config), err)
+	}
+
+	// split into sets
+	
+	splitPipeline := voxels.KeySplitter{}
+
+	sets, err := postProcessing[*voxels.SplitDensityVoxelSet, []*voxels.KeyedDensityVoxelSet](ctx, output, &splitPipeline, config)
+
+	if err != nil {
+		return err
+	}
+
+	// concurrent post processing
+
+	configs, pipelines, err := makeSplitPipelines(sets, config)
+
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(sets))
+
+	inputs := make([]*voxels.DensityVoxelSet, len(sets))
+
+	for i, set := range sets {
+		names[i], inputs[i] = config.splitKey.Name() + " " + set.Label, set.Voxels
+	}
+
+	println("Processing " + fmt.Sprint(len(sets)) + " groups by " + config.splitKey.Name())
+
+	_, err = postProcessing(ctx, inputs, lasProcessing.ProcessEach(config.splitConcurrency, names, pipelines), config)
+
+	if err != nil {
+		return err
+	}
+
+	groups := make([]manifestGroup, len(sets))
+
+	for i, set := range sets {
+		groups[i] = describeGroup(set, configs[i])
+	}
+
+	return writeManifest(config.manifestName(), manifest{SplitBy: config.splitKey.Name(), Groups: groups})
+}
+
+// processes voxels into the output of the command, and outputs an error
+func processVoxels(ctx context.Context, files []*lidarioMod.LasFile, config executionArgs) error {
+	if config.splitKey != nil {
+		return processSplit(ctx, files, config)
+	} else if config.clipFeatures != nil {
+		return processFeatures(ctx, files, config)
+	} else if config.tileSize > 0 {
+		return processTiles(ctx, files, config)
+	}
+
+	return processDensityVoxels(ctx, files, config)
+}
+
+// Main function
+func main() {
+
+	command, config := parseCommand(os.Args[1:])
+
+	// cancel on interrupt or after the timeout
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	if config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+	}
+
+	files, err := openInputs(config.fileNames, !command.separateInputs)
+
+	if err != nil {
+		stop()
+		println("Error accessing LAS file: " + err.Error())
+		os.Exit(1)
+	}
+
+	err = checkPointFilters(files, config.pointFilters)
+
+	if err == nil {
+		err = checkExtraAttributes(files, config.extraAttributes)
+	}
+
+	if err != nil {
+		closeInputs(files)
+		stop()
+		println("Error: " + err.Error())
+		os.Exit(1)
+	}
+
+	config.georeferencing.CRS = voxels.FileCRS(files[0])
+
+	err = command.run(ctx, files, config)
+
+	// processing finished, release the files before reporting
+
+	closeInputs(files)
+
+	stop()
+	
+	if errors.Is(err, context.DeadlineExceeded) {
+		println("Timed out after " + config.timeout.String())
+		os.Exit(1)
+	}
+
+	if errors.Is(err, context.Canceled) {
+		println("Cancelled")
+		os.Exit(1)
+	}
+
+	if err != nil {
+		println("Error: " + err.Error())
+		os.Exit(1)
+	}
+
+	if command.reportCompletion {
+		println("Complete")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// LAS file of the lidarioMod test data, of 1000 points over 100 x 100 units
+const testPoints = "lidarioMod/testdata/points.las"
+
+// runs a command like the command line does, without reporting progress
+func runCommand(t *testing.T, args ...string) {
+	t.Helper()
+
+	command, config := parseCommand(append([]string{args[0], "-progress", "none"}, args[1:]...))
+
+	files, err := openInputs(config.fileNames, !command.separateInputs)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer closeInputs(files)
+
+	if err := command.run(context.Background(), files, config); err != nil {
+		t.Fatalf("%v: %v", strings.Join(args, " "), err)
+	}
+}
+
+// reads the lines of a CSV file in order
+func readSortedLines(t *testing.T, fileName string) []string {
+	t.Helper()
+
+	contents, err := os.ReadFile(fileName)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+
+	sort.Strings(lines)
+
+	return lines
+}
+
+// reads the pixels of a PNG image
+func readImage(t *testing.T, fileName string) ([]uint8, int, int) {
+	t.Helper()
+
+	file, err := os.Open(fileName)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	decoded, err := png.Decode(file)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := decoded.Bounds()
+
+	pixels := make([]uint8, 0, bounds.Dx() * bounds.Dy() * 4)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := decoded.At(x, y).RGBA()
+			pixels = append(pixels, uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8))
+		}
+	}
+
+	return pixels, bounds.Dx(), bounds.Dy()
+}
+
+func TestTiledMinimumImageIsStitched(t *testing.T) {
+	for _, heights := range [][]string{{}, {"-normalize"}, {"-ground"}} {
+		// the test points leave most columns empty, and the ground fills them from the nearest ground within
+		// each tile, so only the extent of the ground image matches processing every tile at once
+		exact := len(heights) == 0 || heights[0] != "-ground"
+
+		// images in a directory, which the name of each tile's image used to be prefixed onto
+		directory := filepath.Join(t.TempDir(), "out")
+
+		if err := os.Mkdir(directory, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		whole, tiled := filepath.Join(directory, "whole.png"), filepath.Join(directory, "tiled.png")
+
+		args := append([]string{"voxelize", "-density", "1", "-voxel", "2"}, heights...)
+
+		runCommand(t, append(args, "-minimum-output", whole, "-output", filepath.Join(directory, "whole.csv"), testPoints)...)
+
+		runCommand(t, append(args, "-tile", "15", "-tile-buffer", "10", "-minimum-output", tiled,
+			"-output", filepath.Join(directory, "tiled.csv"), testPoints)...)
+
+		entries, err := os.ReadDir(directory)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != 4 {
+			t.Fatalf("%v: wrote %d files, expected the voxels and one image of each run", heights, len(entries))
+		}
+
+		wholePixels, width, height := readImage(t, whole)
+
+		tiledPixels, tiledWidth, tiledHeight := readImage(t, tiled)
+
+		if width != tiledWidth || height != tiledHeight {
+			t.Fatalf("%v: tiled image is %dx%d, expected %dx%d", heights, tiledWidth, tiledHeight, width, height)
+		}
+
+		if exact && !reflect.DeepEqual(wholePixels, tiledPixels) {
+			t.Fatalf("%v: tiled image differs from the image of processing every tile at once", heights)
+		}
+
+		if exact && !reflect.DeepEqual(readSortedLines(t, filepath.Join(directory, "whole.csv")), readSortedLines(t, filepath.Join(directory, "tiled.csv"))) {
+			t.Fatalf("%v: tiled voxels differ from the voxels of processing every tile at once", heights)
+		}
+	}
+}
+
+func TestCheckExtraAttributes(t *testing.T) {
+	files, err := openInputs([]string{"lidarioMod/testdata/extrabytes.las", "lidarioMod/testdata/extrabytes.laz"}, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer closeInputs(files)
+
+	if err := checkExtraAttributes(files, []string{"Reflectance", "Confidence"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkExtraAttributes(files, []string{"Reflectance", "Range"}); err == nil || !strings.Contains(err.Error(), "Range") {
+		t.Fatalf("checking an attribute the files do not store gave %v, expected an error naming it", err)
+	}
+
+	// points.las has no extra bytes
+	points, err := openInputs([]string{testPoints}, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer closeInputs(points)
+
+	if err := checkExtraAttributes(points, []string{"Reflectance"}); err == nil {
+		t.Fatal("checking an attribute of a file without extra bytes succeeded")
+	}
+}
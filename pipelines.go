@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/voxels"
+)
+
+// Parameters of the condense stage
+type condenseParameters struct {
+
+	// point density in a voxel to be filled
+	Density int `json:"density"`
+
+}
+
+// Parameters of the minimums stage
+type minimumsParameters struct {
+
+	// file to output the minimums image to, empty for no image
+	Image string `json:"image"`
+
+}
+
+// Parameters of the ground stage
+type groundParameters struct {
+
+	// largest filter window, in the units of the voxel size
+	MaxWindow float64 `json:"max_window"`
+
+	// expected terrain slope
+	Slope float64 `json:"slope"`
+
+	// initial height above the filtered surface to be non ground
+	Threshold float64 `json:"threshold"`
+
+	// largest height above the filtered surface to be non ground
+	MaxThreshold float64 `json:"max_threshold"`
+
+	// whether to use points classified as ground when present
+	GroundClass bool `json:"ground_class"`
+
+	// file to output the ground image to, empty for no image
+	Image string `json:"image"`
+
+}
+
+// Parameters of the stages that write files
+type writerParameters struct {
+
+	// whether to write the attributes of each voxel, only for voxels
+	Attributes bool `json:"attributes"`
+
+	// extra bytes attributes to average over each voxel with the other attributes, only for voxels
+	ExtraAttributes []string `json:"extra_attributes"`
+
+	// whether to write positions in the CRS of the input instead of voxel indices
+	World bool `json:"world"`
+
+	// whether to write a JSON sidecar
+	Sidecar bool `json:"sidecar"`
+
+}
+
+// Stages without parameters
+type noParameters struct {}
+
+// Attributes of points that stages of a pipeline need tracked while voxelizing
+type attributeTracking struct {
+
+	// whether to track the attributes of each voxel
+	attributes bool
+
+	// whether to track points classified as ground
+	groundClass bool
+
+	// extra bytes attributes to track, in the order they are tracked
+	extraAttributes []string
+
+}
+
+// gets the attributes a configuration tracks
+func trackingOf(config executionArgs) attributeTracking {
+	return attributeTracking{attributes: config.attributes, groundClass: config.groundClass,
+		extraAttributes: append([]string(nil), config.extraAttributes...)}
+}
+
+// adds extra bytes attributes to those tracked, keeping the order of those already tracked
+func(tracking *attributeTracking) addExtraAttributes(names []string) {
+	for _, name := range names {
+		tracked := false
+
+		for _, existing := range tracking.extraAttributes {
+			tracked = tracked || existing == name
+		}
+
+		if !tracked {
+			tracking.extraAttributes = append(tracking.extraAttributes, name)
+		}
+	}
+}
+
+// turns on tracking of the attributes in a configuration
+func(tracking attributeTracking) apply(config *executionArgs) {
+	config.attributes = tracking.attributes || len(tracking.extraAttributes) > 0
+	config.groundClass = tracking.groundClass
+	config.extraAttributes = tracking.extraAttributes
+}
+
+// makes the registry of stages pipelines can be configured from, defaulting their parameters to the flags
+// of the configuration. Stages add the attributes they need tracked while voxelizing to the tracking, and writers
+// of voxels find their extra bytes attributes among those tracked when they are built.
+func stageRegistry(config executionArgs, tracking *attributeTracking) *lasProcessing.StageRegistry {
+	registry := lasProcessing.NewStageRegistry()
+
+	// extra bytes attributes default in the stage, as JSON decodes lists into the array of the default
+	writer := writerParameters{Attributes: config.attributes, World: config.georeferencing.WorldCoordinates,
+		Sidecar: config.georeferencing.Sidecar}
+
+	georeferencing := func(parameters writerParameters) voxels.Georeferencing {
+		georeferencing := config.georeferencing
+		georeferencing.WorldCoordinates, georeferencing.Sidecar = parameters.World, parameters.Sidecar
+		return georeferencing
+	}
+
+	lasProcessing.RegisterStage(registry, "condense", condenseParameters{Density: config.density},
+		func(parameters condenseParameters) (lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet], error) {
+			if parameters.Density < 1 {
+				return nil, fmt.Errorf("density must be at least 1")
+			}
+
+			return &voxels.VoxelCondenser{Density: parameters.Density}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "minimums", minimumsParameters{Image: config.minimumImagePath},
+		func(parameters minimumsParameters) (lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, *voxels.MinimumHeights], error) {
+			return &voxels.MinimumHeightFinder{OuptutMinimums: parameters.Image != "", OutputFile: parameters.Image}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "ground", groundParameters{MaxWindow: config.groundFilter.MaxWindow,
+		Slope: config.groundFilter.Slope, Threshold: config.groundFilter.InitialThreshold, MaxThreshold: config.groundFilter.MaxThreshold,
+		GroundClass: config.groundClass, Image: config.minimumImagePath},
+		func(parameters groundParameters) (lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, *voxels.MinimumHeights], error) {
+			// ground classification is tracked with the other attributes
+			tracking.groundClass = tracking.groundClass || parameters.GroundClass
+
+			return &voxels.GroundFilter{MaxWindow: parameters.MaxWindow, Slope: parameters.Slope,
+				InitialThreshold: parameters.Threshold, MaxThreshold: parameters.MaxThreshold,
+				UseGroundClass: parameters.GroundClass, OutputFile: parameters.Image}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "normalize", noParameters{},
+		func(parameters noParameters) (lasProcessing.PostProcessingPipeline[*voxels.MinimumHeights, *voxels.VoxelSet], error) {
+			return &voxels.LazyNormalizer{}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "degroup", noParameters{},
+		func(parameters noParameters) (lasProcessing.PostProcessingPipeline[*voxels.MinimumHeights, *voxels.VoxelSet], error) {
+			return &voxels.MinimumDegrouper{}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "gradient", noParameters{},
+		func(parameters noParameters) (lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, *voxels.HeightGradient], error) {
+			return &voxels.GradientProcessor{}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "measurements", noParameters{},
+		func(parameters noParameters) (lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, *voxels.Measurements], error) {
+			return &voxels.MeasurementFinder{}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "ground_measurements", noParameters{},
+		func(parameters noParameters) (lasProcessing.PostProcessingPipeline[*voxels.MinimumHeights, *voxels.Measurements], error) {
+			return &voxels.GroundMeasurementFinder{}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "write_voxels", writer,
+		func(parameters writerParameters) (lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string], error) {
+			if parameters.ExtraAttributes == nil {
+				parameters.ExtraAttributes = config.extraAttributes
+			}
+
+			// extra bytes attributes are tracked with the other attributes
+			parameters.Attributes = parameters.Attributes || len(parameters.ExtraAttributes) > 0
+
+			tracking.attributes = tracking.attributes || parameters.Attributes
+
+			tracking.addExtraAttributes(parameters.ExtraAttributes)
+
+			return &voxels.VoxelFileWriter{FileName: config.destName, Attributes: parameters.Attributes,
+				ExtraAttributes: parameters.ExtraAttributes, TrackedExtraAttributes: tracking.extraAttributes,
+				Georeferencing: georeferencing(parameters)}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "write_gradient", writer,
+		func(parameters writerParameters) (lasProcessing.PostProcessingPipeline[*voxels.HeightGradient, string], error) {
+			return &voxels.GradientFileWriter{FileName: config.destName, Georeferencing: georeferencing(parameters)}, nil
+		})
+
+	lasProcessing.RegisterStage(registry, "write_measurements", writer,
+		func(parameters writerParameters) (lasProcessing.PostProcessingPipeline[*voxels.Measurements, string], error) {
+			if isTiff(config.destName) {
+				return &voxels.MeasurementsTiffWriter{FileName: config.destName, CRS: config.georeferencing.CRS}, nil
+			}
+
+			return &voxels.MeasurementsFileWriter{FileName: config.destName, Georeferencing: georeferencing(parameters)}, nil
+		})
+
+	return registry
+}
+
+// builds the configured post processing pipeline from density voxels to the file it writes, with the attributes
+// it needs tracked while voxelizing, the union of those of the configuration and of each stage
+func buildConfiguredPipeline(config executionArgs) (lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, string], attributeTracking, error) {
+	tracking := trackingOf(config)
+
+	// a writer only knows the attributes tracked for the stages before it, so the stages are built again
+	// once every attribute is known
+	if _, err := stageRegistry(config, &tracking).Build(config.pipeline); err != nil {
+		return nil, tracking, err
+	}
+
+	stage, err := stageRegistry(config, &tracking).Build(config.pipeline)
+
+	if err != nil {
+		return nil, tracking, err
+	}
+
+	pipeline, err := lasProcessing.TypedPipeline[*voxels.DensityVoxelSet, string](stage)
+
+	return pipeline, tracking, err
+}
+
+// gets the post processing pipeline for density voxels, from the pipeline configuration if there is one
+func densityVoxelPipeline(config executionArgs) (lasProcessing.PostProcessingPipeline[*voxels.DensityVoxelSet, string], error) {
+	if config.pipeline == nil {
+		return chooseDensityVoxelPipeline(config), nil
+	}
+
+	// the configuration already tracks what the stages need, applied when the command was parsed
+	pipeline, _, err := buildConfiguredPipeline(config)
+
+	return pipeline, err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/voxels"
+)
+
+func TestCondenseStageUsesDensityParameter(t *testing.T) {
+	config := executionArgs{density: 1, grid: voxels.VoxelGrid{VoxelSize: 1}}
+
+	pipelineConfig := &lasProcessing.PipelineConfig{Stages: []lasProcessing.StageConfig{
+		{Stage: "condense", Parameters: json.RawMessage(`{"density": 3}`)}}}
+
+	stage, err := stageRegistry(config, &attributeTracking{}).Build(pipelineConfig)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline, err := lasProcessing.TypedPipeline[*voxels.DensityVoxelSet, *voxels.VoxelSet](stage)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// voxels of 1 to 4 points, voxelized with the flag density of 1
+	densities := voxels.NewVoxelStorage[int](voxels.Coordinate{})
+
+	for i := 1; i <= 4; i++ {
+		densities.Set(voxels.Coordinate{X: i}, i)
+	}
+
+	densityVoxels := &voxels.DensityVoxelSet{PointDensity: config.density, XVoxels: 5, YVoxels: 1, ZVoxels: 1,
+		Grid: config.grid, Voxels: densities}
+
+	condensed, err := pipeline.Process(context.Background(), densityVoxels, &lasProcessing.PipelineStatus{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filled := condensed.Voxels.Len(); filled != 2 {
+		t.Fatalf("%d voxels filled, expected the 2 with at least 3 points", filled)
+	}
+}
+
+func TestRunTracksAttributesOfEveryStage(t *testing.T) {
+	pipelineFile := filepath.Join(t.TempDir(), "pipeline.json")
+
+	pipeline := `{"stages": [{"stage": "condense"}, {"stage": "ground", "parameters": {"ground_class": true}},
+		{"stage": "normalize"}, {"stage": "write_voxels", "parameters": {"extra_attributes": ["B"]}}]}`
+
+	if err := os.WriteFile(pipelineFile, []byte(pipeline), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	_, config := parseCommand([]string{"run", "-pipeline", pipelineFile, "-output", "voxels.csv", testPoints})
+
+	if !config.attributes || !config.groundClass {
+		t.Fatalf("tracking attributes %v and ground class %v, expected both", config.attributes, config.groundClass)
+	}
+
+	if !reflect.DeepEqual(config.extraAttributes, []string{"B"}) {
+		t.Fatalf("tracking extra attributes %v, expected those of the writer", config.extraAttributes)
+	}
+}
+
+func TestWriteVoxelsStagesWriteTheirOwnExtraAttributes(t *testing.T) {
+	directory := t.TempDir()
+
+	config := executionArgs{extraAttributes: []string{"A"}}
+
+	tracking := trackingOf(config)
+
+	registry := stageRegistry(config, &tracking)
+
+	// two writers of different attributes, each built like the last stage of a pipeline
+	writers := make([]lasProcessing.PostProcessingPipeline[*voxels.VoxelSet, string], 0)
+
+	for _, extras := range []string{`["B"]`, `["C", "A"]`} {
+		config.destName = filepath.Join(directory, "voxels" + extras + ".csv")
+
+		stage, err := stageRegistry(config, &tracking).Build(&lasProcessing.PipelineConfig{Stages: []lasProcessing.StageConfig{
+			{Stage: "write_voxels", Parameters: json.RawMessage(`{"extra_attributes": ` + extras + `}`)}}})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writer, err := lasProcessing.TypedPipeline[*voxels.VoxelSet, string](stage)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writers = append(writers, writer)
+	}
+
+	if _, err := registry.Build(&lasProcessing.PipelineConfig{Stages: []lasProcessing.StageConfig{{Stage: "write_voxels"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !tracking.attributes || !reflect.DeepEqual(tracking.extraAttributes, []string{"A", "B", "C"}) {
+		t.Fatalf("tracking attributes %v of extra attributes %v, expected the union of every stage", tracking.attributes, tracking.extraAttributes)
+	}
+
+	// a voxel with means of 1, 2 and 3 for A, B and C
+	filled := voxels.NewVoxelStorage[voxels.Filled](voxels.Coordinate{})
+
+	filled.Add(voxels.Coordinate{})
+
+	attributes := voxels.NewVoxelStorage[*voxels.VoxelAttributes](voxels.Coordinate{})
+
+	attributes.Set(voxels.Coordinate{}, &voxels.VoxelAttributes{Points: 1, Classifications: map[int]int{2: 1},
+		ExtraSums: []float64{1, 2, 3}, ExtraCounts: []int{1, 1, 1}})
+
+	voxelSet := &voxels.VoxelSet{Voxels: filled, Attributes: attributes, Grid: voxels.VoxelGrid{VoxelSize: 1}}
+
+	expected := []struct{ column string; values string }{{",mean_b", ",2.000"}, {",mean_c,mean_a", ",3.000,1.000"}}
+
+	for i, writer := range writers {
+		fileName, err := writer.Process(context.Background(), voxelSet, &lasProcessing.PipelineStatus{})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := readSortedLines(t, fileName)
+
+		if len(lines) != 2 || !strings.HasSuffix(lines[1], expected[i].column) || !strings.HasSuffix(lines[0], expected[i].values) {
+			t.Fatalf("writer %d wrote %q, expected columns ending %q of values ending %q", i, lines, expected[i].column, expected[i].values)
+		}
+	}
+}
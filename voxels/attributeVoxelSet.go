@@ -0,0 +1,282 @@
+package voxels
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Point attributes aggregated over a single voxel
+type VoxelAttributes struct {
+
+	// Number of points in the voxel
+	Points int
+
+	// Sum of the intensity of all points
+	IntensitySum int
+
+	// Maximum intensity of any point
+	IntensityMax int
+
+	// Sum of the red value of all points
+	RedSum int
+
+	// Sum of the green value of all points
+	GreenSum int
+
+	// Sum of the blue value of all points
+	BlueSum int
+
+	// Number of points with each classification
+	Classifications map[int]int
+
+	// Number of first returns
+	FirstReturns int
+
+	// Number of last returns
+	LastReturns int
+
+	// Sum of the values of each tracked extra bytes attribute
+	ExtraSums []float64
+
+	// Number of points with a value of each tracked extra bytes attribute
+	ExtraCounts []int
+
+}
+
+// Creates empty voxel attributes, tracking the specified number of extra bytes attributes
+func createVoxelAttributes(extras int) *VoxelAttributes {
+	return &VoxelAttributes{Classifications: make(map[int]int), ExtraSums: make([]float64, extras), ExtraCounts: make([]int, extras)}
+}
+
+// Adds a point to the attributes
+func(attributes *VoxelAttributes) addPoint(intensity int, r int, g int, b int, classification int, returnNumber int, numberOfReturns int) {
+	attributes.Points += 1
+
+	attributes.IntensitySum += intensity
+
+	if intensity > attributes.IntensityMax {
+		attributes.IntensityMax = intensity
+	}
+
+	attributes.RedSum += r
+	attributes.GreenSum += g
+	attributes.BlueSum += b
+
+	attributes.Classifications[classification] += 1
+
+	// some writers leave the returns of formats 0 - 5 unset, which is neither a first nor a last return
+	if numberOfReturns > 0 && returnNumber == 1 {
+		attributes.FirstReturns += 1
+	}
+
+	if numberOfReturns > 0 && returnNumber == numberOfReturns {
+		attributes.LastReturns += 1
+	}
+}
+
+// Adds the points of other attributes to these attributes
+func(attributes *VoxelAttributes) merge(incoming *VoxelAttributes) {
+	attributes.Points += incoming.Points
+
+	attributes.IntensitySum += incoming.IntensitySum
+
+	if incoming.IntensityMax > attributes.IntensityMax {
+		attributes.IntensityMax = incoming.IntensityMax
+	}
+
+	attributes.RedSum += incoming.RedSum
+	attributes.GreenSum += incoming.GreenSum
+	attributes.BlueSum += incoming.BlueSum
+
+	for classification, count := range incoming.Classifications {
+		attributes.Classifications[classification] += count
+	}
+
+	attributes.FirstReturns += incoming.FirstReturns
+	attributes.LastReturns += incoming.LastReturns
+
+	for i := range incoming.ExtraSums {
+		attributes.ExtraSums[i] += incoming.ExtraSums[i]
+		attributes.ExtraCounts[i] += incoming.ExtraCounts[i]
+	}
+}
+
+// Gets the mean value of a tracked extra bytes attribute, and whether any point has a value
+func(attributes *VoxelAttributes) MeanExtra(index int) (float64, bool) {
+	if attributes.ExtraCounts[index] == 0 {
+		return 0, false
+	}
+
+	return attributes.ExtraSums[index] / float64(attributes.ExtraCounts[index]), true
+}
+
+// Gets the mean intensity of the points in the voxel
+func(attributes *VoxelAttributes) MeanIntensity() float64 {
+	if attributes.Points == 0 {
+		return 0
+	}
+
+	return float64(attributes.IntensitySum) / float64(attributes.Points)
+}
+
+// Gets the mean red, green and blue values of the points in the voxel
+func(attributes *VoxelAttributes) MeanRGB() (float64, float64, float64) {
+	if attributes.Points == 0 {
+		return 0, 0, 0
+	}
+
+	points := float64(attributes.Points)
+
+	return float64(attributes.RedSum) / points, float64(attributes.GreenSum) / points, float64(attributes.BlueSum) / points
+}
+
+// Gets the classification histogram as space separated class:count pairs, ordered by class
+func(attributes *VoxelAttributes) ClassificationString() string {
+	classes := make([]int, 0, len(attributes.Classifications))
+
+	for classification := range attributes.Classifications {
+		classes = append(classes, classification)
+	}
+
+	sort.Ints(classes)
+
+	pairs := make([]string, len(classes))
+
+	for i, classification := range classes {
+		pairs[i] = fmt.Sprint(classification) + ":" + fmt.Sprint(attributes.Classifications[classification])
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// Processes LAS files into DensityVoxelSets that also track point attributes for each voxel
+type AttributeVoxelSetProcessor struct {
+
+	// Processor for the point densities
+	DensityVoxelSetProcessor
+
+	// Names of extra bytes attributes to track, which every file must store
+	ExtraAttributes []string
+
+}
+
+// Processes a chunk of a LAS file into a DensityVoxelSet with attributes
+func(processor *AttributeVoxelSetProcessor) Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *lasProcessing.LASChunk, status *lasProcessing.ChunkProgress) (*DensityVoxelSet, error) {
+
+	status.Set(0.0)
+
+	min, _ := processor.Extent(&inputFile.Header)
+
+	voxels := &DensityVoxelSet{Voxels: NewVoxelStorage[int](min), Attributes: NewVoxelStorage[*VoxelAttributes](min)}
+
+	rawBytes, err := chunk.ReadOnFile(inputFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	extras, err := findExtraAttributes(inputFile, processor.ExtraAttributes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := chunk.Start; i < chunk.End; i++ {
+		if err := lasProcessing.CheckCancelled(ctx, i - chunk.Start); err != nil {
+			return nil, err
+		}
+
+		x, y, z := lasProcessing.ReadPointData(inputFile, chunk, rawBytes, i)
+
+		coordinate := processor.PointToCoordinate(x, y, z)
+
+		status.Set(float64(i - chunk.Start) / float64(chunk.End - chunk.Start))
+
+		if !processor.inRegion(coordinate) || !processor.PointFilters.Keep(inputFile, chunk, rawBytes, i) {
+			continue
+		}
+
+		density, _ := voxels.Voxels.Get(coordinate)
+
+		voxels.Voxels.Set(coordinate, density + 1)
+
+		addPointAttributes(voxels.Attributes, coordinate, extras, inputFile, chunk, rawBytes, i)
+	}
+
+	status.Set(1.0)
+
+	return voxels, nil
+}
+
+// Gets an empty DensityVoxelSet with attributes
+func(processor *AttributeVoxelSetProcessor) EmptyOutput(inputFile *lidarioMod.LasFile) *DensityVoxelSet {
+	voxels := processor.DensityVoxelSetProcessor.EmptyOutput(inputFile)
+
+	voxels.Attributes = NewVoxelStorage[*VoxelAttributes](Coordinate{X: voxels.XMin, Y: voxels.YMin, Z: voxels.ZMin})
+
+	return voxels
+}
+
+// Combines two DensityVoxelSets with attributes
+func(processor *AttributeVoxelSetProcessor) CombineOutput(base *DensityVoxelSet, incoming *DensityVoxelSet) *DensityVoxelSet {
+	base = processor.DensityVoxelSetProcessor.CombineOutput(base, incoming)
+
+	mergeAttributes(base.Attributes, incoming.Attributes)
+
+	return base
+}
+
+// Finds the extra bytes attributes of a file by name, every one of which the file must store
+func findExtraAttributes(inputFile *lidarioMod.LasFile, names []string) ([]*lidarioMod.ExtraBytesAttribute, error) {
+	extras := make([]*lidarioMod.ExtraBytesAttribute, len(names))
+
+	for i, name := range names {
+		if extras[i] = inputFile.PointLayout().ExtraAttribute(name); extras[i] == nil {
+			return nil, fmt.Errorf("no extra bytes attribute %q", name)
+		}
+	}
+
+	return extras, nil
+}
+
+// Adds the attributes of a point to the attributes of the voxel it is in
+func addPointAttributes(storage VoxelStorage[*VoxelAttributes], coordinate Coordinate, extras []*lidarioMod.ExtraBytesAttribute, inputFile *lidarioMod.LasFile, chunk *lasProcessing.LASChunk, rawBytes []byte, i int) {
+	attributes, contains := storage.Get(coordinate)
+
+	if !contains {
+		attributes = createVoxelAttributes(len(extras))
+		storage.Set(coordinate, attributes)
+	}
+
+	intensity := lasProcessing.ReadIntensity(inputFile, chunk, rawBytes, i)
+	r, g, b := lasProcessing.ReadRGB(inputFile, chunk, rawBytes, i)
+	classification := lasProcessing.ReadClassification(inputFile, chunk, rawBytes, i)
+	returnNumber, numberOfReturns := lasProcessing.ReadReturns(inputFile, chunk, rawBytes, i)
+
+	attributes.addPoint(intensity, r, g, b, classification, returnNumber, numberOfReturns)
+
+	for j, extra := range extras {
+		if value, ok := lasProcessing.ReadExtraBytes(inputFile, chunk, rawBytes, i, extra); ok {
+			attributes.ExtraSums[j] += value
+			attributes.ExtraCounts[j] += 1
+		}
+	}
+}
+
+// Merges the attributes of incoming voxels into the attributes of base voxels
+func mergeAttributes(base VoxelStorage[*VoxelAttributes], incoming VoxelStorage[*VoxelAttributes]) {
+	incoming.Range(func(coordinate Coordinate, attributes *VoxelAttributes) bool {
+		baseAttributes, contains := base.Get(coordinate)
+		if contains {
+			baseAttributes.merge(attributes)
+		} else {
+			base.Set(coordinate, attributes)
+		}
+		return true
+	})
+}
@@ -0,0 +1,92 @@
+package voxels
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+)
+
+// extrabytes.las stores for point i a Reflectance of ((i*37)%2000-1000) scaled by 0.01, and a Confidence of i%100
+// that every seventh point has no value of
+func TestAttributeVoxelSetProcessorMeansExtraBytes(t *testing.T) {
+	for _, name := range []string{"extrabytes.las", "extrabytes.laz"} {
+		file := openTestData(t, name)
+
+		grid := VoxelGrid{VoxelSize: 10}
+
+		voxels := processTestPoints[DensityVoxelSet](t, file, &AttributeVoxelSetProcessor{DensityVoxelSetProcessor: DensityVoxelSetProcessor{
+			PointDensity: 1, VoxelGrid: grid}, ExtraAttributes: []string{"Confidence", "Reflectance"}})
+
+		// sums and counts of the values of each voxel, confidence then reflectance
+		sums, counts := make(map[Coordinate][2]float64), make(map[Coordinate][2]int)
+
+		chunk := lasProcessing.ChunkFile(file, 1)[0]
+
+		rawBytes, err := chunk.ReadOnFile(file)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < file.Header.NumberPoints; i++ {
+			coordinate := grid.PointToCoordinate(lasProcessing.ReadPointData(file, chunk, rawBytes, i))
+
+			sum, count := sums[coordinate], counts[coordinate]
+
+			if i % 7 != 0 {
+				sum[0], count[0] = sum[0] + float64(i % 100), count[0] + 1
+			}
+
+			sum[1], count[1] = sum[1] + float64((i * 37) % 2000 - 1000) * 0.01, count[1] + 1
+
+			sums[coordinate], counts[coordinate] = sum, count
+		}
+
+		if voxels.Attributes.Len() != len(sums) {
+			t.Fatalf("%s: %d voxels have attributes, expected %d", name, voxels.Attributes.Len(), len(sums))
+		}
+
+		withoutConfidence := 0
+
+		voxels.Attributes.Range(func(coordinate Coordinate, attributes *VoxelAttributes) bool {
+			for j := 0; j < 2; j++ {
+				mean, ok := attributes.MeanExtra(j)
+
+				if ok != (counts[coordinate][j] > 0) {
+					t.Fatalf("%s: voxel %+v having a mean of attribute %d is %v, expected %v", name, coordinate, j, ok, counts[coordinate][j] > 0)
+				}
+
+				if ok && math.Abs(mean - sums[coordinate][j] / float64(counts[coordinate][j])) > 1e-9 {
+					t.Fatalf("%s: voxel %+v has a mean of attribute %d of %v, expected %v", name, coordinate, j, mean,
+						sums[coordinate][j] / float64(counts[coordinate][j]))
+				}
+			}
+
+			if counts[coordinate][0] == 0 {
+				withoutConfidence += 1
+			}
+
+			return true
+		})
+
+		// every point of some voxels has no confidence
+		if withoutConfidence == 0 {
+			t.Fatalf("%s: every voxel has a point with a confidence", name)
+		}
+	}
+}
+
+func TestAttributeVoxelSetProcessorRejectsUnknownExtraBytes(t *testing.T) {
+	file := openTestData(t, "extrabytes.las")
+
+	processor := &AttributeVoxelSetProcessor{DensityVoxelSetProcessor: DensityVoxelSetProcessor{PointDensity: 1,
+		VoxelGrid: VoxelGrid{VoxelSize: 10}}, ExtraAttributes: []string{"Reflectance", "Range"}}
+
+	chunk := lasProcessing.ChunkFile(file, 1)[0]
+
+	if _, err := processor.Process(context.Background(), file, chunk, &lasProcessing.ChunkProgress{}); err == nil {
+		t.Fatal("voxelized an extra bytes attribute the file does not store")
+	}
+}
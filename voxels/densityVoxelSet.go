@@ -1,11 +1,9 @@
 package voxels
 
 import (
-	"math"
-
+	"context"
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 // Type of a collection of voxels
@@ -41,8 +39,14 @@ type DensityVoxelSet struct {
 	// Min number of voxels in the z direction
 	ZMin int
 
+	// Grid the voxels are indexed on
+	Grid VoxelGrid
+
 	// Set of voxels point densities
-	Voxels map[Coordinate]int
+	Voxels VoxelStorage[int]
+
+	// Point attributes of each voxel, nil if attributes are not tracked
+	Attributes VoxelStorage[*VoxelAttributes]
 }
 
 // Processes LAS files into VoxelSets
@@ -51,87 +55,86 @@ type DensityVoxelSetProcessor struct {
 	// Point density required for a voxel
 	PointDensity int
 
-	// Voxel size for this processor
-	VoxelSize float64
+	// Grid to voxelize onto
+	VoxelGrid
+
+	// Columns to voxelize, nil to voxelize every point
+	Region *Region
+
+	// Points to voxelize, every point if there are no filters
+	PointFilters lasProcessing.PointFilters
 
 }
 
+// Whether a voxel is in the columns being voxelized
+func(processor *DensityVoxelSetProcessor) inRegion(coordinate Coordinate) bool {
+	return processor.Region == nil || processor.Region.Contains(coordinate.X, coordinate.Y)
+}
+
 // Processes a chunk of a LAS file into a VoxelSet
-func(processor *DensityVoxelSetProcessor) Process(inputFile *lidarioMod.LasFile, chunk *lasProcessing.LASChunk, output chan<- *DensityVoxelSet, status *float64) {
-	
-	*status = 0.0
+func(processor *DensityVoxelSetProcessor) Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *lasProcessing.LASChunk, status *lasProcessing.ChunkProgress) (*DensityVoxelSet, error) {
 	
-	minX, minY, minZ := inputFile.Header.MinX, inputFile.Header.MinY, inputFile.Header.MinZ
+	status.Set(0.0)
+
+	min, _ := processor.Extent(&inputFile.Header)
 
-	voxels := &DensityVoxelSet{Voxels: make(map[Coordinate]int)}
+	voxels := &DensityVoxelSet{Voxels: NewVoxelStorage[int](min)}
 	
-	rawBytes := chunk.ReadOnFile(inputFile)
+	rawBytes, err := chunk.ReadOnFile(inputFile)
+
+	if err != nil {
+		return nil, err
+	}
 
 	for i := chunk.Start; i < chunk.End; i++ {
+		if err := lasProcessing.CheckCancelled(ctx, i - chunk.Start); err != nil {
+			return nil, err
+		}
+
 		x, y, z := lasProcessing.ReadPointData(inputFile, chunk, rawBytes, i)
 		
-		coordinate := PointToCoordinate(x, minX, y, minY, z, minZ, processor.VoxelSize, false)
-
-		*status = float64(i - chunk.Start) / float64(chunk.End - chunk.Start)
+		coordinate := processor.PointToCoordinate(x, y, z)
 
-		val, contains := voxels.Voxels[coordinate]
+		status.Set(float64(i - chunk.Start) / float64(chunk.End - chunk.Start))
 
-		if contains {
-			voxels.Voxels[coordinate] = val + 1
-		} else {
-			voxels.Voxels[coordinate] = 1
+		if !processor.inRegion(coordinate) || !processor.PointFilters.Keep(inputFile, chunk, rawBytes, i) {
+			continue
 		}
+
+		val, _ := voxels.Voxels.Get(coordinate)
+
+		voxels.Voxels.Set(coordinate, val + 1)
 	}
 
-	*status = 1.0
+	status.Set(1.0)
 
-	output <- voxels
+	return voxels, nil
 }
 
 // Gets an empty VoxelSet
 func(processor *DensityVoxelSetProcessor) EmptyOutput(inputFile *lidarioMod.LasFile) *DensityVoxelSet {
 	
-	header := inputFile.Header
-
-	xSizeRaw, ySizeRaw, zSizeRaw := header.MaxX - header.MinX, header.MaxY - header.MinY, header.MaxZ - header.MinZ
-
-	xRemainder, yRemainder, zRemainder := math.Mod(xSizeRaw, processor.VoxelSize), math.Mod(ySizeRaw, processor.VoxelSize), math.Mod(zSizeRaw, processor.VoxelSize)
-
-	xVoxels, yVoxels, zVoxels := int(xSizeRaw / processor.VoxelSize), int(ySizeRaw / processor.VoxelSize), int(zSizeRaw / processor.VoxelSize)
-
-	if (xRemainder != 0) {
-		xVoxels += 1
-	}
+	min, count := processor.Extent(&inputFile.Header)
 
-	if (yRemainder != 0) {
-		yVoxels += 1
+	if processor.Region != nil {
+		region := processor.Region.Intersect(processor.FileRegion(&inputFile.Header))
+		min.X, min.Y, count.X, count.Y = region.XMin, region.YMin, region.XVoxels, region.YVoxels
 	}
 
-	if (zRemainder != 0) {
-		zVoxels += 1
-	}
-
-	xSize, ySize, zSize := float64(xVoxels) * processor.VoxelSize, float64(yVoxels) * processor.VoxelSize, float64(zVoxels) * processor.VoxelSize
-
-	minX, minY, minZ := inputFile.Header.MinX, inputFile.Header.MinY, inputFile.Header.MinZ
+	xSize, ySize, zSize := float64(count.X) * processor.VoxelSize, float64(count.Y) * processor.VoxelSize, float64(count.Z) * processor.VoxelSize
 
-	minXVoxel, minYVoxel, minZVoxel := int(minX / processor.VoxelSize), int(minY / processor.VoxelSize), int(minZ / processor.VoxelSize)
+	voxels := NewVoxelStorage[int](min)
 
-	voxels := make(map[Coordinate]int)
-
-	return &DensityVoxelSet{XSize: xSize, YSize: ySize, ZSize: zSize, XVoxels: xVoxels, YVoxels: yVoxels, ZVoxels: zVoxels, Voxels: voxels, PointDensity: processor.PointDensity, XMin: minXVoxel, YMin: minYVoxel, ZMin: minZVoxel}
+	return &DensityVoxelSet{XSize: xSize, YSize: ySize, ZSize: zSize, XVoxels: count.X, YVoxels: count.Y, ZVoxels: count.Z, Voxels: voxels, PointDensity: processor.PointDensity, XMin: min.X, YMin: min.Y, ZMin: min.Z, Grid: processor.VoxelGrid}
 }
 
 // Combines two VoxelSets
 func(processor *DensityVoxelSetProcessor) CombineOutput(base *DensityVoxelSet, incoming *DensityVoxelSet) *DensityVoxelSet {
-	for coordinate, density := range incoming.Voxels {
-		baseDensity, contains := base.Voxels[coordinate]
-		if contains {
-			base.Voxels[coordinate] = baseDensity + density
-		} else {
-			base.Voxels[coordinate] = density
-		}
-	}
+	incoming.Voxels.Range(func(coordinate Coordinate, density int) bool {
+		baseDensity, _ := base.Voxels.Get(coordinate)
+		base.Voxels.Set(coordinate, baseDensity + density)
+		return true
+	})
 	return base
 }
 
@@ -142,23 +145,64 @@ type VoxelCondenser struct {
 }
 
 // Turns voxel density into voxels
-func(condenser *VoxelCondenser) Process(densityVoxels *DensityVoxelSet, status *lasProcessing.PipelineStatus) *VoxelSet {
-	voxelSet := mapset.NewThreadUnsafeSet[Coordinate]()
+func(condenser *VoxelCondenser) Process(ctx context.Context, densityVoxels *DensityVoxelSet, status *lasProcessing.PipelineStatus) (*VoxelSet, error) {
+	origin := Coordinate{X: densityVoxels.XMin, Y: densityVoxels.YMin, Z: densityVoxels.ZMin}
+
+	voxelSet := NewVoxelStorage[Filled](origin)
+
+	var attributes VoxelStorage[*VoxelAttributes]
+
+	var classifiedGround ColumnStorage[int]
+
+	if densityVoxels.Attributes != nil {
+		attributes = NewVoxelStorage[*VoxelAttributes](origin)
+		classifiedGround = NewColumnStorage[int](XYPair{X: origin.X, Y: origin.Y})
+	}
 
-	total := len(densityVoxels.Voxels)
+	total := densityVoxels.Voxels.Len()
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Condensing", Progress: 0.0}
+	status.Set("Condensing", 0.0)
 
-	for voxel, density := range densityVoxels.Voxels {
+	var err error
 
-		if density >= densityVoxels.PointDensity {
+	densityVoxels.Voxels.Range(func(voxel Coordinate, density int) bool {
+
+		var voxelAttributes *VoxelAttributes
+
+		if attributes != nil {
+			voxelAttributes, _ = densityVoxels.Attributes.Get(voxel)
+		}
+
+		if density >= condenser.Density {
 			voxelSet.Add(voxel)
+
+			if attributes != nil {
+				attributes.Set(voxel, voxelAttributes)
+			}
+		}
+
+		// ground points are often too sparse to fill a voxel, so track them regardless of density
+		if classifiedGround != nil && voxelAttributes.Classifications[groundClass] > 0 {
+			xy := XYPair{X: voxel.X, Y: voxel.Y}
+			height, contains := classifiedGround.Get(xy)
+			if !contains || voxel.Z < height {
+				classifiedGround.Set(xy, voxel.Z)
+			}
 		}
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Condensing", Progress: float64(current) / float64(total)}
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Condensing", float64(current) / float64(total))
+		return true
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	output := &VoxelSet{XSize: densityVoxels.XSize, 
@@ -170,7 +214,10 @@ func(condenser *VoxelCondenser) Process(densityVoxels *DensityVoxelSet, status *
 		XMin: densityVoxels.XMin,
 		YMin: densityVoxels.YMin,
 		ZMin: densityVoxels.ZMin,
-		Voxels: voxelSet}
+		Grid: densityVoxels.Grid,
+		Voxels: voxelSet,
+		Attributes: attributes,
+		ClassifiedGround: classifiedGround}
 
-	return output
+	return output, nil
 }
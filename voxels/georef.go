@@ -0,0 +1,352 @@
+package voxels
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// GeoKeys holding the EPSG code of a projected or geographic CRS
+const (
+	projectedCSTypeGeoKey = 3072
+	geographicTypeGeoKey = 2048
+)
+
+// Coordinate reference system of a LAS file
+type CRS struct {
+
+	// OGC WKT of the CRS, empty if the file has none
+	WKT string
+
+	// GeoKeys of the CRS, nil if the file has none
+	GeoKeys *lidarioMod.GeoKeys
+
+}
+
+// Gets the CRS of a LAS file
+func FileCRS(file *lidarioMod.LasFile) CRS {
+	return CRS{WKT: file.WKT(), GeoKeys: file.GeoKeys()}
+}
+
+// Whether two CRSs are described the same way
+func(crs *CRS) Equal(other CRS) bool {
+	if crs.WKT != other.WKT || (crs.GeoKeys == nil) != (other.GeoKeys == nil) {
+		return false
+	}
+
+	if crs.GeoKeys == nil {
+		return true
+	}
+
+	return reflect.DeepEqual(crs.GeoKeys.GeoKeyDirectory, other.GeoKeys.GeoKeyDirectory) &&
+		reflect.DeepEqual(crs.GeoKeys.GeoDoubleParams, other.GeoKeys.GeoDoubleParams) &&
+		crs.GeoKeys.GeoASCIIParams == other.GeoKeys.GeoASCIIParams
+}
+
+// Gets the EPSG code of the CRS from its GeoKeys, 0 if unknown
+func(crs *CRS) EPSG() int {
+	if crs.GeoKeys == nil || len(crs.GeoKeys.GeoKeyDirectory) < 4 {
+		return 0
+	}
+
+	directory := crs.GeoKeys.GeoKeyDirectory
+
+	code := 0
+
+	for i := 1; i <= int(directory[3]) && 4 * i + 3 < len(directory); i++ {
+		key, location, value := directory[4 * i], directory[4 * i + 1], directory[4 * i + 3]
+
+		// only values stored in the directory itself are codes, 32767 is user defined
+		if location != 0 || value == 0 || value == 32767 {
+			continue
+		}
+
+		if key == projectedCSTypeGeoKey {
+			return int(value)
+		}
+
+		if key == geographicTypeGeoKey {
+			code = int(value)
+		}
+	}
+
+	return code
+}
+
+// How writers relate voxels to the CRS of the source
+type Georeferencing struct {
+
+	// Whether to write voxel centres in the CRS of the source, and heights in its units, instead of voxel indices
+	WorldCoordinates bool
+
+	// Whether to write a JSON sidecar describing the grid and CRS next to the output
+	Sidecar bool
+
+	// CRS of the source
+	CRS CRS
+
+}
+
+// Gets the name of the sidecar for an output file
+func SidecarName(fileName string) string {
+	return fileName + ".json"
+}
+
+// Voxel extent of an output, in voxel indices
+type sidecarExtent struct {
+
+	// Min number of voxels in the x direction
+	XMin int `json:"x_min"`
+
+	// Min number of voxels in the y direction
+	YMin int `json:"y_min"`
+
+	// Min number of voxels in the z direction
+	ZMin int `json:"z_min"`
+
+	// Number of voxels in the X direction
+	XVoxels int `json:"x_voxels"`
+
+	// Number of voxels in the Y direction
+	YVoxels int `json:"y_voxels"`
+
+	// Number of voxels in the Z direction
+	ZVoxels int `json:"z_voxels"`
+
+}
+
+// Extent in the CRS of a source
+type Bounds struct {
+
+	// Lowest X position
+	MinX float64 `json:"min_x"`
+
+	// Lowest Y position
+	MinY float64 `json:"min_y"`
+
+	// Lowest Z position
+	MinZ float64 `json:"min_z"`
+
+	// Highest X position
+	MaxX float64 `json:"max_x"`
+
+	// Highest Y position
+	MaxY float64 `json:"max_y"`
+
+	// Highest Z position
+	MaxZ float64 `json:"max_z"`
+
+}
+
+// GeoKeys of the source, as stored in its GeoTIFF VLRs
+type sidecarGeoKeys struct {
+
+	// GeoKey directory
+	Directory []uint16 `json:"directory"`
+
+	// Double parameters referenced by the directory
+	DoubleParams []float64 `json:"double_params,omitempty"`
+
+	// ASCII parameters referenced by the directory
+	ASCIIParams string `json:"ascii_params,omitempty"`
+
+}
+
+// CRS of the source
+type sidecarCRS struct {
+
+	// EPSG code, omitted if unknown
+	EPSG int `json:"epsg,omitempty"`
+
+	// OGC WKT, omitted if the source has none
+	WKT string `json:"wkt,omitempty"`
+
+	// GeoKeys, omitted if the source has none
+	GeoKeys *sidecarGeoKeys `json:"geokeys,omitempty"`
+
+}
+
+// Description of the grid and CRS of an output, written next to it as JSON
+type sidecar struct {
+
+	// File the sidecar describes
+	File string `json:"file"`
+
+	// Side length of a voxel, in the units of the CRS
+	VoxelSize float64 `json:"voxel_size"`
+
+	// Corner of voxel (0, 0, 0), in the CRS
+	Origin [3]float64 `json:"origin"`
+
+	// Indexing mode, floor or truncate
+	Indexing string `json:"indexing"`
+
+	// Whether the output holds voxel indices (index) or positions in the CRS (world)
+	Coordinates string `json:"coordinates"`
+
+	// Whether z is the height above the ground rather than an elevation
+	Normalized bool `json:"normalized"`
+
+	// Number of axes the output covers, 2 for column outputs, which have no z extent, and 1 for height gradients, which only have a z extent
+	Dimensions int `json:"dimensions"`
+
+	// Extent in voxel indices
+	Extent sidecarExtent `json:"extent"`
+
+	// Extent in the CRS
+	Bounds Bounds `json:"bounds"`
+
+	// CRS of the source, omitted if unknown
+	CRS *sidecarCRS `json:"crs,omitempty"`
+
+}
+
+// Reads the voxel extent of an existing sidecar
+func readSidecarExtent(fileName string) (Coordinate, Coordinate, error) {
+	contents, err := os.ReadFile(SidecarName(fileName))
+
+	if err != nil {
+		return Coordinate{}, Coordinate{}, err
+	}
+
+	var description sidecar
+
+	if err = json.Unmarshal(contents, &description); err != nil {
+		return Coordinate{}, Coordinate{}, err
+	}
+
+	extent := description.Extent
+
+	return Coordinate{X: extent.XMin, Y: extent.YMin, Z: extent.ZMin}, Coordinate{X: extent.XVoxels, Y: extent.YVoxels, Z: extent.ZVoxels}, nil
+}
+
+// Gets the smallest extent covering two extents, ignoring empty extents
+func unionExtent(min Coordinate, count Coordinate, otherMin Coordinate, otherCount Coordinate) (Coordinate, Coordinate) {
+	if otherCount.X <= 0 || otherCount.Y <= 0 || otherCount.Z <= 0 {
+		return min, count
+	}
+
+	if count.X <= 0 || count.Y <= 0 || count.Z <= 0 {
+		return otherMin, otherCount
+	}
+
+	union := Coordinate{X: minInt(min.X, otherMin.X), Y: minInt(min.Y, otherMin.Y), Z: minInt(min.Z, otherMin.Z)}
+
+	return union, Coordinate{
+		X: maxInt(min.X + count.X, otherMin.X + otherCount.X) - union.X,
+		Y: maxInt(min.Y + count.Y, otherMin.Y + otherCount.Y) - union.Y,
+		Z: maxInt(min.Z + count.Z, otherMin.Z + otherCount.Z) - union.Z}
+}
+
+// Writes a sidecar for an output covering the specified voxels, extending the extent of an existing sidecar if merging
+func(georeferencing *Georeferencing) writeSidecar(fileName string, grid VoxelGrid, min Coordinate, count Coordinate, dimensions int, normalized bool, merge bool) error {
+	if !georeferencing.Sidecar {
+		return nil
+	}
+
+	if merge {
+		// a missing or unreadable sidecar is replaced
+		if existingMin, existingCount, err := readSidecarExtent(fileName); err == nil {
+			min, count = unionExtent(min, count, existingMin, existingCount)
+		}
+	}
+
+	description := sidecar{
+		File: filepath.Base(fileName),
+		VoxelSize: grid.VoxelSize,
+		Origin: [3]float64{grid.Anchor.X, grid.Anchor.Y, grid.Anchor.Z},
+		Indexing: "floor",
+		Coordinates: "index",
+		Normalized: normalized,
+		Dimensions: dimensions,
+		Extent: sidecarExtent{XMin: min.X, YMin: min.Y, ZMin: min.Z, XVoxels: count.X, YVoxels: count.Y, ZVoxels: count.Z}}
+
+	if grid.Indexing == TruncatedIndexing {
+		description.Indexing = "truncate"
+	}
+
+	if georeferencing.WorldCoordinates {
+		description.Coordinates = "world"
+	}
+
+	description.Bounds.MinX, description.Bounds.MinY, description.Bounds.MinZ = grid.VoxelCorner(min)
+	description.Bounds.MaxX, description.Bounds.MaxY, description.Bounds.MaxZ = grid.VoxelCorner(
+		Coordinate{X: min.X + count.X, Y: min.Y + count.Y, Z: min.Z + count.Z})
+
+	if normalized {
+		// heights above the ground are not offset by the anchor
+		description.Bounds.MinZ -= grid.Anchor.Z
+		description.Bounds.MaxZ -= grid.Anchor.Z
+	}
+
+	if dimensions == 2 {
+		description.Bounds.MinZ, description.Bounds.MaxZ = 0, 0
+	}
+
+	if dimensions == 1 {
+		description.Bounds.MinX, description.Bounds.MaxX = 0, 0
+		description.Bounds.MinY, description.Bounds.MaxY = 0, 0
+	}
+
+	crs := georeferencing.CRS
+
+	if crs.WKT != "" || crs.GeoKeys != nil {
+		description.CRS = &sidecarCRS{EPSG: crs.EPSG(), WKT: crs.WKT}
+
+		if crs.GeoKeys != nil {
+			description.CRS.GeoKeys = &sidecarGeoKeys{
+				Directory: crs.GeoKeys.GeoKeyDirectory,
+				DoubleParams: crs.GeoKeys.GeoDoubleParams,
+				ASCIIParams: crs.GeoKeys.GeoASCIIParams}
+		}
+	}
+
+	contents, err := json.MarshalIndent(description, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(SidecarName(fileName), append(contents, '\n'), 0644)
+}
+
+// Gets the number of decimal places needed to write a value exactly, up to 6
+func decimalPlaces(value float64) int {
+	for places := 0; places < 6; places++ {
+		scaled := value * math.Pow10(places)
+		if math.Abs(scaled - math.Round(scaled)) < 1e-6 {
+			return places
+		}
+	}
+
+	return 6
+}
+
+// Formats positions on a grid in the CRS, with only as many decimal places as voxel centres need
+type positionFormatter struct {
+
+	// Decimal places to write
+	places int
+
+}
+
+// Creates a formatter for the positions of the centres of voxels on a grid
+func createPositionFormatter(grid VoxelGrid) positionFormatter {
+	places := decimalPlaces(grid.VoxelSize / 2)
+
+	for _, anchor := range []float64{grid.Anchor.X, grid.Anchor.Y, grid.Anchor.Z} {
+		places = maxInt(places, decimalPlaces(anchor))
+	}
+
+	return positionFormatter{places: places}
+}
+
+// Formats a position
+func(formatter positionFormatter) format(value float64) string {
+	return strconv.FormatFloat(value, 'f', formatter.places, 64)
+}
@@ -0,0 +1,336 @@
+package voxels
+
+import (
+	"context"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Value written to raster cells with no measurement
+const tiffNoData = -9999
+
+// Number of raster rows in each TIFF strip
+const tiffRowsPerStrip = 64
+
+// TIFF field types
+const (
+	tiffShort uint16 = 3
+	tiffLong uint16 = 4
+	tiffASCII uint16 = 2
+	tiffDouble uint16 = 12
+)
+
+// GeoKey for whether raster coordinates refer to pixel areas or points
+const gtRasterTypeGeoKey = 1025
+
+// A single entry of a TIFF image file directory
+type tiffEntry struct {
+
+	// Tag of the entry
+	tag uint16
+
+	// Field type of the entry
+	fieldType uint16
+
+	// Number of values in the entry
+	count uint32
+
+	// Little endian values of the entry
+	data []byte
+
+}
+
+// Creates an entry of SHORT values
+func shortEntry(tag uint16, values ...uint16) tiffEntry {
+	data := make([]byte, 2 * len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint16(data[2 * i:], value)
+	}
+	return tiffEntry{tag: tag, fieldType: tiffShort, count: uint32(len(values)), data: data}
+}
+
+// Creates an entry of LONG values
+func longEntry(tag uint16, values ...uint32) tiffEntry {
+	data := make([]byte, 4 * len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(data[4 * i:], value)
+	}
+	return tiffEntry{tag: tag, fieldType: tiffLong, count: uint32(len(values)), data: data}
+}
+
+// Creates an entry of DOUBLE values
+func doubleEntry(tag uint16, values ...float64) tiffEntry {
+	data := make([]byte, 8 * len(values))
+	for i, value := range values {
+		binary.LittleEndian.PutUint64(data[8 * i:], math.Float64bits(value))
+	}
+	return tiffEntry{tag: tag, fieldType: tiffDouble, count: uint32(len(values)), data: data}
+}
+
+// Creates a null terminated ASCII entry
+func asciiEntry(tag uint16, value string) tiffEntry {
+	data := append([]byte(value), 0)
+	return tiffEntry{tag: tag, fieldType: tiffASCII, count: uint32(len(data)), data: data}
+}
+
+// Builds the GeoKey directory for a raster, keeping the CRS of the source GeoKeys and marking pixels as areas
+func rasterKeyDirectory(geoKeys *lidarioMod.GeoKeys) []uint16 {
+	keys := make(map[uint16][3]uint16)
+
+	if geoKeys != nil && len(geoKeys.GeoKeyDirectory) >= 4 {
+		numKeys := int(geoKeys.GeoKeyDirectory[3])
+		for i := 1; i <= numKeys && 4 * i + 3 < len(geoKeys.GeoKeyDirectory); i++ {
+			entry := geoKeys.GeoKeyDirectory[4 * i:4 * i + 4]
+			keys[entry[0]] = [3]uint16{entry[1], entry[2], entry[3]}
+		}
+	}
+
+	// 1 is RasterPixelIsArea, matching the tiepoint at the corner of the first pixel
+	keys[gtRasterTypeGeoKey] = [3]uint16{0, 1, 1}
+
+	ids := make([]int, 0, len(keys))
+
+	for id := range keys {
+		ids = append(ids, int(id))
+	}
+
+	sort.Ints(ids)
+
+	directory := []uint16{1, 1, 0, uint16(len(ids))}
+
+	for _, id := range ids {
+		key := keys[uint16(id)]
+		directory = append(directory, uint16(id), key[0], key[1], key[2])
+	}
+
+	return directory
+}
+
+// Writes measurements to a GeoTIFF, one float32 band per measurement
+type MeasurementsTiffWriter struct {
+
+	// the name of the file to write to
+	FileName string
+
+	// the CRS of the measurements, written as GeoKeys, or as OGC WKT in a GDAL .aux.xml sidecar if it has no GeoKeys
+	CRS CRS
+
+}
+
+// Writes a set of measurements to a GeoTIFF, outputting the name of the file
+func(writer *MeasurementsTiffWriter) Process(ctx context.Context, measurements *Measurements, status *lasProcessing.PipelineStatus) (string, error) {
+
+	status.Set("Rasterizing", 0.0)
+
+	width, height := measurements.XVoxels, measurements.YVoxels
+
+	bands := []map[XYPair]int{
+		measurements.UnderstoryHeight,
+		measurements.CanopyBaseHeight,
+		measurements.FuelStrataGap,
+		measurements.CanopyHeight}
+
+	names := measurements.names()
+
+	// the fuel strata gap is a length rather than a height
+	gapBand := 2
+
+	bandSize := 4 * width * height
+
+	// leave room for the directory after the pixels
+	if int64(len(bands)) * int64(bandSize) > math.MaxUint32 - (1 << 20) {
+		return "", errors.New("measurements are too large for a TIFF, use a larger voxel size")
+	}
+
+	pixels := make([]byte, len(bands) * bandSize)
+
+	for band := range bands {
+		for i := 0; i < width * height; i++ {
+			binary.LittleEndian.PutUint32(pixels[band * bandSize + 4 * i:], math.Float32bits(tiffNoData))
+		}
+	}
+
+	total := len(measurements.CanopyHeight)
+
+	current := 0
+
+	for coords := range measurements.CanopyHeight {
+		column, row := coords.X - measurements.XMin, measurements.YVoxels - 1 - (coords.Y - measurements.YMin)
+
+		if column >= 0 && column < width && row >= 0 && row < height {
+			for band, values := range bands {
+				// measurements are in voxels, rasters are in the units of the CRS
+				value := float32(measurements.worldHeight(values[coords]))
+
+				if band == gapBand {
+					value = float32(float64(values[coords]) * measurements.Grid.VoxelSize)
+				}
+
+				binary.LittleEndian.PutUint32(pixels[band * bandSize + 4 * (row * width + column):], math.Float32bits(value))
+			}
+		}
+
+		current += 1
+
+		if err := lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return "", err
+		}
+		status.Set("Rasterizing", float64(current) / float64(total))
+	}
+
+	status.Set("Writing", 0.0)
+
+	// strips of every band, band by band
+	stripsPerBand := (height + tiffRowsPerStrip - 1) / tiffRowsPerStrip
+
+	stripOffsets := make([]uint32, 0, len(bands) * stripsPerBand)
+
+	stripCounts := make([]uint32, 0, len(bands) * stripsPerBand)
+
+	for band := range bands {
+		for strip := 0; strip < stripsPerBand; strip++ {
+			rows := tiffRowsPerStrip
+			if (strip + 1) * tiffRowsPerStrip > height {
+				rows = height - strip * tiffRowsPerStrip
+			}
+			stripOffsets = append(stripOffsets, uint32(8 + band * bandSize + strip * tiffRowsPerStrip * width * 4))
+			stripCounts = append(stripCounts, uint32(rows * width * 4))
+		}
+	}
+
+	// the tiepoint maps the top left of the raster to the top left corner of its top left voxel
+	left, _, _ := measurements.Grid.VoxelCorner(Coordinate{X: measurements.XMin})
+	_, top, _ := measurements.Grid.VoxelCorner(Coordinate{Y: measurements.YMin + measurements.YVoxels})
+
+	metadata := "<GDALMetadata>"
+	for band, name := range names {
+		metadata += "<Item name=\"DESCRIPTION\" sample=\"" + fmt.Sprint(band) + "\" role=\"description\">" + name + "</Item>"
+	}
+	metadata += "</GDALMetadata>"
+
+	bitsPerSample := make([]uint16, len(bands))
+	sampleFormat := make([]uint16, len(bands))
+	extraSamples := make([]uint16, len(bands) - 1)
+	for i := range bands {
+		bitsPerSample[i] = 32
+		sampleFormat[i] = 3 // floating point
+	}
+
+	entries := []tiffEntry{
+		longEntry(256, uint32(width)),
+		longEntry(257, uint32(height)),
+		shortEntry(258, bitsPerSample...),
+		shortEntry(259, 1), // no compression
+		shortEntry(262, 1), // black is zero
+		longEntry(273, stripOffsets...),
+		shortEntry(277, uint16(len(bands))),
+		longEntry(278, tiffRowsPerStrip),
+		longEntry(279, stripCounts...),
+		shortEntry(284, 2), // bands stored separately
+		shortEntry(338, extraSamples...),
+		shortEntry(339, sampleFormat...),
+		doubleEntry(33550, measurements.Grid.VoxelSize, measurements.Grid.VoxelSize, 0),
+		doubleEntry(33922, 0, 0, 0, left, top, 0),
+		shortEntry(34735, rasterKeyDirectory(writer.CRS.GeoKeys)...),
+		asciiEntry(42112, metadata),
+		asciiEntry(42113, "-9999")}
+
+	geoKeys := writer.CRS.GeoKeys
+
+	if geoKeys != nil && len(geoKeys.GeoDoubleParams) > 0 {
+		entries = append(entries, doubleEntry(34736, geoKeys.GeoDoubleParams...))
+	}
+
+	if geoKeys != nil && len(geoKeys.GeoASCIIParams) > 0 {
+		entries = append(entries, asciiEntry(34737, geoKeys.GeoASCIIParams))
+	}
+
+	sort.Slice(entries, func(i int, j int) bool { return entries[i].tag < entries[j].tag })
+
+	// values too large for an entry follow the pixels, then the directory itself
+	valuesOffset := 8 + len(pixels)
+
+	values := make([]byte, 0)
+
+	directory := make([]byte, 2 + 12 * len(entries) + 4)
+
+	binary.LittleEndian.PutUint16(directory, uint16(len(entries)))
+
+	for i, entry := range entries {
+		field := directory[2 + 12 * i:]
+		binary.LittleEndian.PutUint16(field[0:], entry.tag)
+		binary.LittleEndian.PutUint16(field[2:], entry.fieldType)
+		binary.LittleEndian.PutUint32(field[4:], entry.count)
+
+		if len(entry.data) <= 4 {
+			copy(field[8:12], entry.data)
+		} else {
+			binary.LittleEndian.PutUint32(field[8:], uint32(valuesOffset + len(values)))
+			values = append(values, entry.data...)
+			if len(values) % 2 == 1 {
+				values = append(values, 0)
+			}
+		}
+	}
+
+	header := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+
+	binary.LittleEndian.PutUint32(header[4:], uint32(valuesOffset + len(values)))
+
+	file, err := os.Create(writer.FileName)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	output := bufio.NewWriter(file)
+
+	for _, part := range [][]byte{header, pixels, values, directory} {
+		if _, err = output.Write(part); err != nil {
+			return "", err
+		}
+	}
+
+	if err = output.Flush(); err != nil {
+		return "", err
+	}
+
+	// LAS 1.4 files may describe their CRS only in WKT, which GDAL reads from the auxiliary file
+	if geoKeys == nil && writer.CRS.WKT != "" {
+		if err = writeAuxiliarySRS(writer.FileName, writer.CRS.WKT); err != nil {
+			return "", err
+		}
+	}
+
+	status.Set("Writing", 1.0)
+
+	return writer.FileName, nil
+}
+
+// Gets the name of the GDAL auxiliary file of a raster
+func AuxiliaryName(fileName string) string {
+	return fileName + ".aux.xml"
+}
+
+// Writes the CRS of a raster as OGC WKT to its GDAL auxiliary file
+func writeAuxiliarySRS(fileName string, wkt string) error {
+	var srs bytes.Buffer
+
+	if err := xml.EscapeText(&srs, []byte(wkt)); err != nil {
+		return err
+	}
+
+	return os.WriteFile(AuxiliaryName(fileName), []byte("<PAMDataset>\n  <SRS>" + srs.String() + "</SRS>\n</PAMDataset>\n"), 0666)
+}
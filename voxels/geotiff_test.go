@@ -0,0 +1,154 @@
+package voxels
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Reads the values of each tag of the first directory of a little endian TIFF
+func readTiffTags(t *testing.T, fileName string) ([]byte, map[uint16][]byte) {
+	contents, err := os.ReadFile(fileName)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents[:4]) != "II*\x00" {
+		t.Fatalf("%s is not a little endian TIFF", fileName)
+	}
+
+	sizes := map[uint16]int{tiffShort: 2, tiffLong: 4, tiffASCII: 1, tiffDouble: 8}
+
+	directory := contents[binary.LittleEndian.Uint32(contents[4:]):]
+
+	tags := make(map[uint16][]byte)
+
+	for i := 0; i < int(binary.LittleEndian.Uint16(directory)); i++ {
+		field := directory[2 + 12 * i:]
+
+		length := sizes[binary.LittleEndian.Uint16(field[2:])] * int(binary.LittleEndian.Uint32(field[4:]))
+
+		if length <= 4 {
+			tags[binary.LittleEndian.Uint16(field)] = field[8:8 + length]
+		} else {
+			offset := binary.LittleEndian.Uint32(field[8:])
+			tags[binary.LittleEndian.Uint16(field)] = contents[offset:int(offset) + length]
+		}
+	}
+
+	return contents, tags
+}
+
+// Decodes the SHORT values of a tag
+func tiffShorts(data []byte) []uint16 {
+	values := make([]uint16, len(data) / 2)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint16(data[2 * i:])
+	}
+	return values
+}
+
+// Decodes the DOUBLE values of a tag
+func tiffDoubles(data []byte) []float64 {
+	values := make([]float64, len(data) / 8)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8 * i:]))
+	}
+	return values
+}
+
+// Measurements of 2 x 2 voxels of 2 units, with the canopy in one voxel 5 voxels up
+func testMeasurements() *Measurements {
+	column := XYPair{X: 1, Y: 3}
+
+	return &Measurements{CanopyHeight: map[XYPair]int{column: 5}, UnderstoryHeight: map[XYPair]int{column: 1},
+		CanopyBaseHeight: map[XYPair]int{column: 3}, FuelStrataGap: map[XYPair]int{column: 2},
+		XVoxels: 2, YVoxels: 2, XMin: 1, YMin: 3, Grid: VoxelGrid{VoxelSize: 2, Anchor: Anchor{X: 100, Y: 200, Z: 10}}}
+}
+
+// Writes measurements to a GeoTIFF in a temporary directory
+func writeTestTiff(t *testing.T, crs CRS) string {
+	writer := &MeasurementsTiffWriter{FileName: filepath.Join(t.TempDir(), "metrics.tif"), CRS: crs}
+
+	fileName, err := writer.Process(context.Background(), testMeasurements(), &lasProcessing.PipelineStatus{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+func TestMeasurementsTiffWriterTags(t *testing.T) {
+	// a projected CRS of EPSG 32617
+	geoKeys := &lidarioMod.GeoKeys{GeoKeyDirectory: []uint16{1, 1, 0, 1, 3072, 0, 1, 32617}}
+
+	fileName := writeTestTiff(t, CRS{GeoKeys: geoKeys})
+
+	contents, tags := readTiffTags(t, fileName)
+
+	if scale := tiffDoubles(tags[33550]); !reflect.DeepEqual(scale, []float64{2, 2, 0}) {
+		t.Fatalf("ModelPixelScale is %v, expected the voxel size", scale)
+	}
+
+	// the top left of the raster is the corner of voxel 1 in X and of the top of voxel 4 in Y
+	if tiepoint := tiffDoubles(tags[33922]); !reflect.DeepEqual(tiepoint, []float64{0, 0, 0, 102, 210, 0}) {
+		t.Fatalf("ModelTiepoint is %v, expected the top left corner of the voxels", tiepoint)
+	}
+
+	expectedKeys := []uint16{1, 1, 0, 2, gtRasterTypeGeoKey, 0, 1, 1, 3072, 0, 1, 32617}
+
+	if keys := tiffShorts(tags[34735]); !reflect.DeepEqual(keys, expectedKeys) {
+		t.Fatalf("GeoKeyDirectory is %v, expected %v", keys, expectedKeys)
+	}
+
+	if noData := string(tags[42113]); noData != "-9999\x00" {
+		t.Fatalf("GDAL_NODATA is %q, expected -9999", noData)
+	}
+
+	// the canopy is in the bottom left pixel of the last band, as an elevation
+	offsets := tags[273]
+
+	canopy := binary.LittleEndian.Uint32(offsets[len(offsets) - 4:]) + 4 * 2
+
+	if value := math.Float32frombits(binary.LittleEndian.Uint32(contents[canopy:])); value != 20 {
+		t.Fatalf("canopy elevation is %v, expected 20", value)
+	}
+
+	if empty := math.Float32frombits(binary.LittleEndian.Uint32(contents[canopy + 4:])); empty != tiffNoData {
+		t.Fatalf("empty pixel is %v, expected %v", empty, tiffNoData)
+	}
+
+	if _, err := os.Stat(AuxiliaryName(fileName)); err == nil {
+		t.Fatalf("wrote an auxiliary file for a CRS in GeoKeys")
+	}
+}
+
+func TestMeasurementsTiffWriterWritesWKTWithoutGeoKeys(t *testing.T) {
+	fileName := writeTestTiff(t, CRS{WKT: `PROJCS["WGS 84 / UTM zone 17N & more"]`})
+
+	_, tags := readTiffTags(t, fileName)
+
+	if keys := tiffShorts(tags[34735]); !reflect.DeepEqual(keys, []uint16{1, 1, 0, 1, gtRasterTypeGeoKey, 0, 1, 1}) {
+		t.Fatalf("GeoKeyDirectory is %v, expected only the raster type", keys)
+	}
+
+	auxiliary, err := os.ReadFile(AuxiliaryName(fileName))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(auxiliary), "<SRS>PROJCS[&#34;WGS 84 / UTM zone 17N &amp; more&#34;]</SRS>") {
+		t.Fatalf("auxiliary file %q does not hold the WKT", auxiliary)
+	}
+}
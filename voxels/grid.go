@@ -0,0 +1,94 @@
+package voxels
+
+import (
+	"math"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Method used to turn a position into a voxel index
+type IndexingMode int
+
+const (
+
+	// Truncates towards the anchor, so voxels either side of it share index 0 (legacy behaviour)
+	TruncatedIndexing IndexingMode = iota
+
+	// Rounds down, so every voxel covers exactly one voxel size
+	FloorIndexing
+)
+
+// Point in world coordinates that voxel grids are aligned to
+type Anchor struct {
+
+	// X coordinate
+	X float64
+
+	// Y coordinate
+	Y float64
+
+	// Z coordinate
+	Z float64
+
+}
+
+// A grid of voxels over world coordinates. Grids with the same voxel size,
+// anchor and floor indexing line up exactly, regardless of the data they cover.
+type VoxelGrid struct {
+
+	// Side length of a voxel
+	VoxelSize float64
+
+	// The corner of voxel (0, 0, 0)
+	Anchor Anchor
+
+	// How positions are converted to voxel indices
+	Indexing IndexingMode
+
+}
+
+// Gets the voxel index of a position along one axis
+func(grid *VoxelGrid) index(position float64, anchor float64) int {
+	delta := (position - anchor) / grid.VoxelSize
+
+	if grid.Indexing == FloorIndexing {
+		return int(math.Floor(delta))
+	}
+
+	return int(delta)
+}
+
+// Converts a point to the Coordinate of the voxel containing it
+func(grid *VoxelGrid) PointToCoordinate(x float64, y float64, z float64) Coordinate {
+	return Coordinate{
+		X: grid.index(x, grid.Anchor.X),
+		Y: grid.index(y, grid.Anchor.Y),
+		Z: grid.index(z, grid.Anchor.Z)}
+}
+
+// Gets the world coordinates of the lowest corner of a voxel. Corners are a whole number of voxels from the anchor
+// in both indexing modes, so with truncated indexing the voxels below the anchor, which hold positions up to a
+// voxel size further down, have corners and centres a voxel size above their positions.
+func(grid *VoxelGrid) VoxelCorner(coordinate Coordinate) (float64, float64, float64) {
+	return grid.Anchor.X + float64(coordinate.X) * grid.VoxelSize,
+		grid.Anchor.Y + float64(coordinate.Y) * grid.VoxelSize,
+		grid.Anchor.Z + float64(coordinate.Z) * grid.VoxelSize
+}
+
+// Gets the world coordinates of the centre of a voxel
+func(grid *VoxelGrid) VoxelCentre(coordinate Coordinate) (float64, float64, float64) {
+	x, y, z := grid.VoxelCorner(coordinate)
+
+	return x + grid.VoxelSize / 2, y + grid.VoxelSize / 2, z + grid.VoxelSize / 2
+}
+
+// Gets the lowest voxel and the number of voxels along each axis needed to cover the bounds of a LAS file
+func(grid *VoxelGrid) Extent(header *lidarioMod.LasHeader) (Coordinate, Coordinate) {
+	min := grid.PointToCoordinate(header.MinX, header.MinY, header.MinZ)
+
+	max := grid.PointToCoordinate(header.MaxX, header.MaxY, header.MaxZ)
+
+	count := Coordinate{X: max.X - min.X + 1, Y: max.Y - min.Y + 1, Z: max.Z - min.Z + 1}
+
+	return min, count
+}
@@ -0,0 +1,114 @@
+package voxels
+
+import (
+	"testing"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Grids of 2 unit voxels anchored away from the origin, with the anchor below zero in Y
+func testGrids() []VoxelGrid {
+	anchor := Anchor{X: 100, Y: -50, Z: 0.5}
+
+	return []VoxelGrid{{VoxelSize: 2, Anchor: anchor, Indexing: FloorIndexing}, {VoxelSize: 2, Anchor: anchor, Indexing: TruncatedIndexing}}
+}
+
+func TestPointToCoordinate(t *testing.T) {
+	// indices of positions offset from the anchor on every axis, with floor then truncated indexing
+	cases := []struct{ offset float64; floor int; truncated int }{
+		{0, 0, 0},
+		{1.9, 0, 0},
+		// exact voxel boundaries belong to the voxel above them, or the voxel nearer the anchor when truncating
+		{2, 1, 1},
+		{4, 2, 2},
+		{-2, -1, -1},
+		{-4, -2, -2},
+		// positions just below the anchor are in voxel -1, or voxel 0 when truncating
+		{-0.01, -1, 0},
+		{-1.99, -1, 0},
+		{-2.01, -2, -1},
+		{-5, -3, -2},
+	}
+
+	for _, grid := range testGrids() {
+		for _, c := range cases {
+			expected := c.floor
+
+			if grid.Indexing == TruncatedIndexing {
+				expected = c.truncated
+			}
+
+			coordinate := grid.PointToCoordinate(grid.Anchor.X + c.offset, grid.Anchor.Y + c.offset, grid.Anchor.Z + c.offset)
+
+			if coordinate != (Coordinate{X: expected, Y: expected, Z: expected}) {
+				t.Fatalf("indexing %d: %v from the anchor is in voxel %+v, expected %d on every axis", grid.Indexing, c.offset, coordinate, expected)
+			}
+		}
+	}
+}
+
+func TestVoxelCornerAndCentre(t *testing.T) {
+	for _, grid := range testGrids() {
+		for _, index := range []int{0, 1, 3, -1, -3} {
+			coordinate := Coordinate{X: index, Y: index, Z: index}
+
+			// corners are a whole number of voxels from the anchor in both modes
+			expected := float64(index) * grid.VoxelSize
+
+			x, y, z := grid.VoxelCorner(coordinate)
+
+			if x != grid.Anchor.X + expected || y != grid.Anchor.Y + expected || z != grid.Anchor.Z + expected {
+				t.Fatalf("indexing %d: voxel %d has its corner at %v, %v, %v, expected %v from the anchor", grid.Indexing, index, x, y, z, expected)
+			}
+
+			x, y, z = grid.VoxelCentre(coordinate)
+
+			if x != grid.Anchor.X + expected + 1 || y != grid.Anchor.Y + expected + 1 || z != grid.Anchor.Z + expected + 1 {
+				t.Fatalf("indexing %d: voxel %d has its centre at %v, %v, %v, expected %v from the anchor", grid.Indexing, index, x, y, z, expected + 1)
+			}
+
+			// truncated voxels below the anchor hold the positions a voxel size below their centre
+			if grid.Indexing == TruncatedIndexing && index < 0 {
+				x, y, z = x - grid.VoxelSize, y - grid.VoxelSize, z - grid.VoxelSize
+			}
+
+			if centre := grid.PointToCoordinate(x, y, z); centre != coordinate {
+				t.Fatalf("indexing %d: the centre of voxel %d holds positions of voxel %+v", grid.Indexing, index, centre)
+			}
+		}
+	}
+}
+
+// Points on the same grid are in voxels of the same corners, whatever the anchor, when floor indexing
+func TestFloorGridsLineUpAcrossAnchors(t *testing.T) {
+	near, far := VoxelGrid{VoxelSize: 2, Indexing: FloorIndexing}, VoxelGrid{VoxelSize: 2, Anchor: Anchor{X: -1000, Y: 1000, Z: 10}, Indexing: FloorIndexing}
+
+	for _, position := range []float64{-7.5, -2, -0.5, 0, 3.99, 4, 11} {
+		nearX, nearY, nearZ := near.VoxelCorner(near.PointToCoordinate(position, position, position))
+
+		farX, farY, farZ := far.VoxelCorner(far.PointToCoordinate(position, position, position))
+
+		if nearX != farX || nearY != farY || nearZ != farZ {
+			t.Fatalf("%v is in a voxel at %v, %v, %v and at %v, %v, %v on grids anchored apart", position, nearX, nearY, nearZ, farX, farY, farZ)
+		}
+	}
+}
+
+func TestExtentAcrossTheAnchor(t *testing.T) {
+	for _, grid := range testGrids() {
+		// bounds from 3 below the anchor to exactly 4 above it on every axis
+		header := &lidarioMod.LasHeader{MinX: grid.Anchor.X - 3, MinY: grid.Anchor.Y - 3, MinZ: grid.Anchor.Z - 3,
+			MaxX: grid.Anchor.X + 4, MaxY: grid.Anchor.Y + 4, MaxZ: grid.Anchor.Z + 4}
+
+		// voxels -2 to 2, or -1 to 2 when truncating
+		expectedMin, expectedCount := Coordinate{X: -2, Y: -2, Z: -2}, Coordinate{X: 5, Y: 5, Z: 5}
+
+		if grid.Indexing == TruncatedIndexing {
+			expectedMin, expectedCount = Coordinate{X: -1, Y: -1, Z: -1}, Coordinate{X: 4, Y: 4, Z: 4}
+		}
+
+		if min, count := grid.Extent(header); min != expectedMin || count != expectedCount {
+			t.Fatalf("indexing %d: extent is %+v voxels from %+v, expected %+v from %+v", grid.Indexing, count, min, expectedCount, expectedMin)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package voxels
+
+import (
+	"context"
+	"math"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+)
+
+// LAS classification for ground points
+const groundClass = 2
+
+// Value of a cell in a heightGrid with no height
+const emptyHeight = math.MaxInt32
+
+// A dense grid of heights over the XY plane
+type heightGrid struct {
+
+	// X coordinate of the first column
+	xMin int
+
+	// Y coordinate of the first row
+	yMin int
+
+	// Number of columns
+	width int
+
+	// Number of rows
+	height int
+
+	// Heights in row major order, emptyHeight where there is no height
+	values []int32
+
+}
+
+// Gets the smaller of two ints
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Gets the larger of two ints
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Creates a grid just large enough to hold the specified heights
+func createHeightGrid(heights ColumnStorage[int]) *heightGrid {
+	xMin, yMin, xMax, yMax := math.MaxInt, math.MaxInt, math.MinInt, math.MinInt
+
+	heights.Range(func(xy XYPair, _ int) bool {
+		xMin, xMax = minInt(xMin, xy.X), maxInt(xMax, xy.X)
+		yMin, yMax = minInt(yMin, xy.Y), maxInt(yMax, xy.Y)
+		return true
+	})
+
+	if heights.Len() == 0 {
+		return &heightGrid{}
+	}
+
+	grid := &heightGrid{xMin: xMin, yMin: yMin, width: xMax - xMin + 1, height: yMax - yMin + 1}
+
+	grid.values = make([]int32, grid.width * grid.height)
+
+	for i := range grid.values {
+		grid.values[i] = emptyHeight
+	}
+
+	heights.Range(func(xy XYPair, height int) bool {
+		grid.values[grid.index(xy)] = int32(height)
+		return true
+	})
+
+	return grid
+}
+
+// Gets the index of a coordinate in the grid values
+func(grid *heightGrid) index(xy XYPair) int {
+	return (xy.Y - grid.yMin) * grid.width + xy.X - grid.xMin
+}
+
+// Creates an empty grid of the same size
+func(grid *heightGrid) emptyCopy() *heightGrid {
+	return &heightGrid{xMin: grid.xMin, yMin: grid.yMin, width: grid.width, height: grid.height, values: make([]int32, len(grid.values))}
+}
+
+// Fills every empty cell with the height of the nearest (by steps) filled cell
+func(grid *heightGrid) fillNearest() {
+	queue := make([]int, 0, len(grid.values))
+
+	for i, value := range grid.values {
+		if value != emptyHeight {
+			queue = append(queue, i)
+		}
+	}
+
+	for next := 0; next < len(queue); next++ {
+		i := queue[next]
+		x, y := i % grid.width, i / grid.width
+
+		neighbours := [4]int{-1, -1, -1, -1}
+
+		if x > 0 {
+			neighbours[0] = i - 1
+		}
+
+		if x < grid.width - 1 {
+			neighbours[1] = i + 1
+		}
+
+		if y > 0 {
+			neighbours[2] = i - grid.width
+		}
+
+		if y < grid.height - 1 {
+			neighbours[3] = i + grid.width
+		}
+
+		for _, neighbour := range neighbours {
+			if neighbour >= 0 && grid.values[neighbour] == emptyHeight {
+				grid.values[neighbour] = grid.values[i]
+				queue = append(queue, neighbour)
+			}
+		}
+	}
+}
+
+// Running min or max over lines of a grid, taking constant time per cell whatever the window size
+// with the van Herk/Gil-Werman algorithm
+type lineFilter struct {
+
+	// Odd window size
+	window int
+
+	// Whether to take the max (dilation) rather than the min (erosion)
+	dilate bool
+
+	// Line padded by half a window of values that never win on either side
+	padded []int32
+
+	// Best value from the start of each block of a window to each cell
+	forward []int32
+
+	// Best value from each cell to the end of its block
+	backward []int32
+
+}
+
+// Creates a filter of the specified odd window size for lines of up to the specified length
+func newLineFilter(window int, dilate bool, length int) *lineFilter {
+	padded := length + window - 1
+
+	return &lineFilter{window: window, dilate: dilate, padded: make([]int32, padded), forward: make([]int32, padded),
+		backward: make([]int32, padded)}
+}
+
+// Gets the value that wins between two values
+func(filter *lineFilter) best(a int32, b int32) int32 {
+	if (a > b) == filter.dilate {
+		return a
+	}
+	return b
+}
+
+// Filters the n values from start, stride apart, of the input into the same cells of the output.
+// The window is cut off at the ends of the line.
+func(filter *lineFilter) apply(input []int32, output []int32, start int, stride int, n int) {
+	half := filter.window / 2
+
+	padding := int32(math.MaxInt32)
+
+	if filter.dilate {
+		padding = math.MinInt32
+	}
+
+	length := n + filter.window - 1
+
+	padded, forward, backward := filter.padded[:length], filter.forward[:length], filter.backward[:length]
+
+	for i := range padded {
+		padded[i] = padding
+	}
+
+	for i := 0; i < n; i++ {
+		padded[half + i] = input[start + i * stride]
+	}
+
+	for i, value := range padded {
+		if i % filter.window == 0 {
+			forward[i] = value
+		} else {
+			forward[i] = filter.best(forward[i - 1], value)
+		}
+	}
+
+	for i := length - 1; i >= 0; i-- {
+		if i == length - 1 || (i + 1) % filter.window == 0 {
+			backward[i] = padded[i]
+		} else {
+			backward[i] = filter.best(backward[i + 1], padded[i])
+		}
+	}
+
+	// the window of cell i covers padded cells i to i + window - 1, which span at most two blocks
+	for i := 0; i < n; i++ {
+		output[start + i * stride] = filter.best(backward[i], forward[i + filter.window - 1])
+	}
+}
+
+// Applies a square min (erosion) or max (dilation) filter of the specified odd window size,
+// as a pass along the rows then a pass along the columns
+func(grid *heightGrid) filter(window int, dilate bool) *heightGrid {
+	line := newLineFilter(window, dilate, maxInt(grid.width, grid.height))
+
+	rows := grid.emptyCopy()
+
+	for y := 0; y < grid.height; y++ {
+		line.apply(grid.values, rows.values, y * grid.width, 1, grid.width)
+	}
+
+	output := grid.emptyCopy()
+
+	for x := 0; x < grid.width; x++ {
+		line.apply(rows.values, output.values, x, grid.width, grid.height)
+	}
+
+	return output
+}
+
+// Applies a morphological opening (erosion then dilation) of the specified odd window size
+func(grid *heightGrid) open(window int) *heightGrid {
+	return grid.filter(window, false).filter(window, true)
+}
+
+// Finds the ground height of each column, either with a progressive morphological filter over the lowest
+// voxel in each column (https://doi.org/10.1109/TGRS.2003.810682) or from points classified as ground.
+// Ground heights are output as MinimumHeights, so they can be used for normalization and measurements.
+type GroundFilter struct {
+
+	// Largest filter window, in the same units as the voxel size
+	MaxWindow float64
+
+	// Expected terrain slope (rise over run)
+	Slope float64
+
+	// Height above the filtered surface for a column to be non ground at the smallest window, in the same units as the voxel size
+	InitialThreshold float64
+
+	// Largest height above the filtered surface for a column to be non ground, in the same units as the voxel size
+	MaxThreshold float64
+
+	// Whether to use points classified as ground (class 2) when there are any
+	UseGroundClass bool
+
+	// File to output the ground heights image to, empty to not output an image
+	OutputFile string
+
+}
+
+// Gets the lowest voxel in each column
+func columnMinimums(voxelSet *VoxelSet) map[XYPair]int {
+	minHeights := make(map[XYPair]int)
+
+	voxelSet.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
+		xy := XYPair{X: voxel.X, Y: voxel.Y}
+		min, contains := minHeights[xy]
+		if !contains || voxel.Z < min {
+			minHeights[xy] = voxel.Z
+		}
+		return true
+	})
+
+	return minHeights
+}
+
+// Gets the window sizes for the filter, in voxels
+func(filter *GroundFilter) windows(voxelSize float64) []int {
+	maxWindow := int(filter.MaxWindow / voxelSize)
+
+	if maxWindow % 2 == 0 {
+		maxWindow += 1
+	}
+
+	windows := make([]int, 0)
+
+	for window := 3; window < maxWindow; window = 2 * (window - 1) + 1 {
+		windows = append(windows, window)
+	}
+
+	return append(windows, maxInt(maxWindow, 3))
+}
+
+// Finds ground heights with a progressive morphological filter
+func(filter *GroundFilter) morphologicalGround(ctx context.Context, minimums map[XYPair]int, voxelSize float64, status *lasProcessing.PipelineStatus) (map[XYPair]int, error) {
+	original := createHeightGrid(MapColumns[int](minimums))
+
+	original.fillNearest()
+
+	// cells are ground until they stand too far above an opened surface
+	isGround := make([]bool, len(original.values))
+
+	for i := range isGround {
+		isGround[i] = true
+	}
+
+	maxThreshold := filter.MaxThreshold / voxelSize
+
+	// opening only removes objects above the ground, so remove low outliers by closing
+	closed := original.filter(5, true).filter(5, false)
+
+	surface := original.emptyCopy()
+
+	for cell, value := range original.values {
+		if float64(closed.values[cell] - value) > maxThreshold {
+			isGround[cell] = false
+			surface.values[cell] = closed.values[cell]
+		} else {
+			surface.values[cell] = value
+		}
+	}
+
+	windows := filter.windows(voxelSize)
+
+	for i, window := range windows {
+		status.Set("Ground", float64(i) / float64(len(windows)))
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		threshold := filter.InitialThreshold / voxelSize
+
+		if i > 0 {
+			threshold += filter.Slope * float64(window - windows[i - 1])
+		}
+
+		// differences of a single voxel are within quantization
+		threshold = math.Max(math.Min(threshold, maxThreshold), 1)
+
+		opened := surface.open(window)
+
+		for cell, value := range original.values {
+			if float64(value - opened.values[cell]) > threshold {
+				isGround[cell] = false
+			}
+		}
+
+		surface = opened
+	}
+
+	// interpolate the ground under non ground columns from the nearest ground columns
+	ground := original.emptyCopy()
+
+	for cell, value := range original.values {
+		if isGround[cell] {
+			ground.values[cell] = value
+		} else {
+			ground.values[cell] = emptyHeight
+		}
+	}
+
+	ground.fillNearest()
+
+	heights := make(map[XYPair]int)
+
+	for xy, min := range minimums {
+		height := int(ground.values[ground.index(xy)])
+
+		if height == emptyHeight {
+			height = min
+		}
+
+		heights[xy] = height
+	}
+
+	status.Set("Ground", 1.0)
+
+	return heights, nil
+}
+
+// Finds ground heights from the lowest voxel containing ground class points in each column
+func classifiedGround(minimums map[XYPair]int, classified ColumnStorage[int]) map[XYPair]int {
+	ground := createHeightGrid(classified)
+
+	ground.fillNearest()
+
+	heights := make(map[XYPair]int)
+
+	for xy, min := range minimums {
+		if xy.X < ground.xMin || xy.X >= ground.xMin + ground.width || xy.Y < ground.yMin || xy.Y >= ground.yMin + ground.height {
+			heights[xy] = min
+			continue
+		}
+
+		heights[xy] = int(ground.values[ground.index(xy)])
+	}
+
+	return heights
+}
+
+// finds ground heights
+func(filter *GroundFilter) Process(ctx context.Context, voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) (*MinimumHeights, error) {
+
+	status.Set("Ground", 0.0)
+
+	minimums := columnMinimums(voxelSet)
+
+	var heights map[XYPair]int
+
+	if filter.UseGroundClass && voxelSet.ClassifiedGround != nil && voxelSet.ClassifiedGround.Len() > 0 {
+		heights = classifiedGround(minimums, voxelSet.ClassifiedGround)
+	} else {
+		var err error
+
+		heights, err = filter.morphologicalGround(ctx, minimums, voxelSet.Grid.VoxelSize, status)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ground := &MinimumHeights{Voxels: voxelSet, Heights: heights}
+
+	if filter.OutputFile != "" {
+		status.Set("Write ground", 0.0)
+
+		err := writeMinimumHeights(ctx, filter.OutputFile, ground, status, voxelSet)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ground, nil
+}
+
+// Finds measurements about each column relative to the ground heights, ignoring voxels below the ground
+type GroundMeasurementFinder struct {
+
+}
+
+// finds measurements relative to the ground
+func(finder *GroundMeasurementFinder) Process(ctx context.Context, ground *MinimumHeights, status *lasProcessing.PipelineStatus) (*Measurements, error) {
+
+	// map xy to column of voxels above the ground
+	columns := make(map[XYPair]*Column)
+
+	total := ground.Voxels.Voxels.Len()
+
+	current := 0
+
+	status.Set("Columns", 0.0)
+
+	var err error
+
+	ground.Voxels.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
+		xy := XYPair{X: voxel.X, Y: voxel.Y}
+		height := voxel.Z - ground.Heights[xy]
+
+		current += 1
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Columns", float64(current) / float64(total))
+
+		if height < 0 {
+			return true
+		}
+
+		column, contains := columns[xy]
+		if contains {
+			column.addVoxel(height)
+		} else {
+			column = createColumn(height)
+			column.GroundHeight = 0
+			columns[xy] = column
+		}
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	measurements, err := measureColumns(ctx, columns, ground.Voxels, status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// columns were measured from the ground
+	measurements.Normalized = true
+
+	return measurements, nil
+}
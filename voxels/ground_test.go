@@ -0,0 +1,73 @@
+package voxels
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// filters a grid by taking the min or max over the whole window of every cell
+func bruteForceFilter(grid *heightGrid, window int, dilate bool) *heightGrid {
+	half := window / 2
+
+	output := grid.emptyCopy()
+
+	for y := 0; y < grid.height; y++ {
+		for x := 0; x < grid.width; x++ {
+			best := grid.values[y * grid.width + x]
+			for dy := maxInt(0, y - half); dy <= minInt(grid.height - 1, y + half); dy++ {
+				for dx := maxInt(0, x - half); dx <= minInt(grid.width - 1, x + half); dx++ {
+					value := grid.values[dy * grid.width + dx]
+					if (dilate && value > best) || (!dilate && value < best) {
+						best = value
+					}
+				}
+			}
+			output.values[y * grid.width + x] = best
+		}
+	}
+
+	return output
+}
+
+// creates a grid of random heights
+func randomHeightGrid(random *rand.Rand, width int, height int) *heightGrid {
+	heights := make(map[XYPair]int)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			heights[XYPair{X: x - 3, Y: y + 7}] = random.Intn(200) - 100
+		}
+	}
+
+	return createHeightGrid(MapColumns[int](heights))
+}
+
+func TestFilterMatchesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for _, size := range [][2]int{{1, 1}, {1, 9}, {13, 1}, {17, 11}, {40, 23}} {
+		grid := randomHeightGrid(random, size[0], size[1])
+
+		// windows both smaller and larger than the grid
+		for _, window := range []int{1, 3, 5, 9, 31, 101} {
+			for _, dilate := range []bool{false, true} {
+				expected, actual := bruteForceFilter(grid, window, dilate), grid.filter(window, dilate)
+
+				for i := range expected.values {
+					if expected.values[i] != actual.values[i] {
+						t.Fatalf("%dx%d grid, window %d, dilate %v: cell %d is %d, expected %d",
+							size[0], size[1], window, dilate, i, actual.values[i], expected.values[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	grid := randomHeightGrid(rand.New(rand.NewSource(1)), 1000, 1000)
+
+	for i := 0; i < b.N; i++ {
+		grid.filter(201, false)
+	}
+}
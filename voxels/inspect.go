@@ -0,0 +1,281 @@
+package voxels
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Variable length record of a LAS file, without its data
+type RecordInfo struct {
+
+	// User ID of the record
+	UserID string `json:"user_id"`
+
+	// Record ID, meaning set by the user ID
+	RecordID int `json:"record_id"`
+
+	// Length of the record data in bytes
+	Length int `json:"length"`
+
+	// Description of the record
+	Description string `json:"description"`
+
+	// Whether the record is an extended variable length record, stored after the points
+	Extended bool `json:"extended"`
+
+}
+
+// CRS of a LAS file, with its GeoKeys decoded
+type CRSInfo struct {
+
+	// EPSG code, omitted if unknown
+	EPSG int `json:"epsg,omitempty"`
+
+	// OGC WKT, omitted if the file has none
+	WKT string `json:"wkt,omitempty"`
+
+	// Decoded GeoKeys, omitted if the file has none
+	GeoKeys []lidarioMod.GeoKey `json:"geokeys,omitempty"`
+
+}
+
+// Description of a LAS file from its header and VLRs
+type FileInfo struct {
+
+	// Name of the file
+	File string `json:"file"`
+
+	// LAS version, as major.minor
+	Version string `json:"version"`
+
+	// Point data record format
+	PointFormat int `json:"point_format"`
+
+	// Length of a point record in bytes, including extra bytes
+	RecordLength int `json:"record_length"`
+
+	// Whether the file is LAZ compressed
+	Compressed bool `json:"compressed"`
+
+	// Software that wrote the file
+	GeneratingSoftware string `json:"generating_software"`
+
+	// System that collected the points
+	SystemID string `json:"system_id"`
+
+	// Number of points
+	Points int `json:"points"`
+
+	// Number of points by return number, 5 returns for point formats 0-5 and 15 for 6-10
+	PointsByReturn []int `json:"points_by_return"`
+
+	// Scale factors of the x, y and z coordinates
+	Scale [3]float64 `json:"scale"`
+
+	// Offsets of the x, y and z coordinates
+	Offset [3]float64 `json:"offset"`
+
+	// Extent of the points in the CRS
+	Bounds Bounds `json:"bounds"`
+
+	// Points per square unit of the CRS over the XY bounds, 0 if the bounds have no area
+	Density float64 `json:"density"`
+
+	// Average distance between points for the density, 0 if the bounds have no area
+	Spacing float64 `json:"spacing"`
+
+	// Number of extra bytes at the end of each point record
+	ExtraBytes int `json:"extra_bytes"`
+
+	// Attributes described by the Extra Bytes VLR, omitted if the file has none
+	ExtraAttributes []lidarioMod.ExtraBytesAttribute `json:"extra_attributes,omitempty"`
+
+	// Variable length records, followed by any extended variable length records
+	Records []RecordInfo `json:"records"`
+
+	// CRS of the file, omitted if it has none
+	CRS *CRSInfo `json:"crs,omitempty"`
+
+}
+
+// Gets the description of a record
+func recordInfo(vlr lidarioMod.VLR, extended bool) RecordInfo {
+	return RecordInfo{UserID: strings.TrimRight(vlr.UserID, "\x00 "), RecordID: vlr.RecordID,
+		Length: vlr.RecordLengthAfterHeader, Description: strings.TrimRight(vlr.Description, "\x00 "), Extended: extended}
+}
+
+// Describes a LAS file from its header and VLRs, without reading its points. The density
+// is estimated over the XY bounds, so it is lower than the density of the points where
+// they don't cover the whole of their bounds.
+func InspectFile(file *lidarioMod.LasFile) FileInfo {
+	header := &file.Header
+
+	info := FileInfo{
+		File: file.FileName(),
+		Version: fmt.Sprintf("%d.%d", header.VersionMajor, header.VersionMinor),
+		PointFormat: int(header.PointFormatID),
+		RecordLength: header.PointRecordLength,
+		Compressed: file.IsCompressed(),
+		GeneratingSoftware: strings.TrimRight(header.GeneratingSoftware, "\x00 "),
+		SystemID: strings.TrimRight(header.SystemID, "\x00 "),
+		Points: header.NumberPoints,
+		PointsByReturn: append([]int{}, header.ExtendedNumberPointsByReturn[:5]...),
+		Scale: [3]float64{header.XScaleFactor, header.YScaleFactor, header.ZScaleFactor},
+		Offset: [3]float64{header.XOffset, header.YOffset, header.ZOffset},
+		Bounds: Bounds{MinX: header.MinX, MinY: header.MinY, MinZ: header.MinZ, MaxX: header.MaxX, MaxY: header.MaxY, MaxZ: header.MaxZ},
+		Records: make([]RecordInfo, 0, len(file.VlrData) + len(file.EvlrData))}
+
+	if header.PointFormatID >= 6 {
+		info.PointsByReturn = append([]int{}, header.ExtendedNumberPointsByReturn[:]...)
+	}
+
+	if layout := file.PointLayout(); layout != nil {
+		info.ExtraBytes, info.ExtraAttributes = layout.ExtraBytesLength, layout.ExtraAttributes
+	}
+
+	if area := (header.MaxX - header.MinX) * (header.MaxY - header.MinY); area > 0 && header.NumberPoints > 0 {
+		info.Density = float64(header.NumberPoints) / area
+		info.Spacing = 1 / math.Sqrt(info.Density)
+	}
+
+	for _, vlr := range file.VlrData {
+		info.Records = append(info.Records, recordInfo(vlr, false))
+	}
+
+	for _, vlr := range file.EvlrData {
+		info.Records = append(info.Records, recordInfo(vlr, true))
+	}
+
+	crs := FileCRS(file)
+
+	if crs.WKT != "" || crs.GeoKeys != nil {
+		info.CRS = &CRSInfo{EPSG: crs.EPSG(), WKT: crs.WKT}
+
+		if crs.GeoKeys != nil {
+			info.CRS.GeoKeys = crs.GeoKeys.Decode()
+		}
+	}
+
+	return info
+}
+
+// Joins numbers with spaces, without exponents
+func joinNumbers(values []float64) string {
+	parts := make([]string, len(values))
+
+	for i, value := range values {
+		parts[i] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Writes the description as indented text
+func(info *FileInfo) WriteText(output io.Writer) error {
+	var text strings.Builder
+
+	line := func(name string, format string, values ...any) {
+		fmt.Fprintf(&text, "  %-20s " + format + "\n", append([]any{name + ":"}, values...)...)
+	}
+
+	fmt.Fprintln(&text, info.File)
+
+	line("Version", "%s", info.Version)
+	line("Point format", "%d (%d byte records)", info.PointFormat, info.RecordLength)
+
+	if info.ExtraBytes > 0 {
+		line("Extra bytes", "%d", info.ExtraBytes)
+	}
+
+	for _, attribute := range info.ExtraAttributes {
+		description := fmt.Sprintf("%s (%s", attribute.Name, attribute.TypeName)
+
+		if attribute.Scale != 1 || attribute.ValueOffset != 0 {
+			description += fmt.Sprintf(", scale %s offset %s", joinNumbers([]float64{attribute.Scale}), joinNumbers([]float64{attribute.ValueOffset}))
+		}
+
+		if attribute.HasNoData {
+			description += ", no data " + joinNumbers([]float64{attribute.NoData})
+		}
+
+		description += ")"
+
+		if attribute.Description != "" {
+			description += ", " + attribute.Description
+		}
+
+		line("Extra attribute", "%s", description)
+	}
+	line("Compressed", "%v", info.Compressed)
+
+	if info.GeneratingSoftware != "" {
+		line("Software", "%s", info.GeneratingSoftware)
+	}
+
+	if info.SystemID != "" {
+		line("System", "%s", info.SystemID)
+	}
+
+	line("Points", "%d", info.Points)
+
+	// trailing returns without points are left out
+	returns := len(info.PointsByReturn)
+
+	for returns > 1 && info.PointsByReturn[returns - 1] == 0 {
+		returns -= 1
+	}
+
+	line("Points by return", "%s", strings.Trim(fmt.Sprint(info.PointsByReturn[:returns]), "[]"))
+	line("Scale", "%s", joinNumbers(info.Scale[:]))
+	line("Offset", "%s", joinNumbers(info.Offset[:]))
+
+	bounds := info.Bounds
+
+	line("Min", "%.3f %.3f %.3f", bounds.MinX, bounds.MinY, bounds.MinZ)
+	line("Max", "%.3f %.3f %.3f", bounds.MaxX, bounds.MaxY, bounds.MaxZ)
+
+	if info.Density > 0 {
+		line("Density", "%.3f points per square unit (spacing %.3f)", info.Density, info.Spacing)
+	}
+
+	for _, record := range info.Records {
+		kind := "VLR"
+
+		if record.Extended {
+			kind = "EVLR"
+		}
+
+		description := fmt.Sprintf("%s %d, %d bytes", record.UserID, record.RecordID, record.Length)
+
+		if record.Description != "" {
+			description += ", " + record.Description
+		}
+
+		line(kind, "%s", description)
+	}
+
+	if info.CRS == nil {
+		line("CRS", "none")
+	} else {
+		if info.CRS.EPSG != 0 {
+			line("CRS", "EPSG:%d", info.CRS.EPSG)
+		}
+
+		for _, key := range info.CRS.GeoKeys {
+			line("GeoKey", "%s (%d) = %s", key.Name, key.Code, key.Value)
+		}
+
+		if info.CRS.WKT != "" {
+			line("WKT", "%s", info.CRS.WKT)
+		}
+	}
+
+	_, err := io.WriteString(output, text.String())
+
+	return err
+}
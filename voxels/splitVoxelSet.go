@@ -0,0 +1,228 @@
+package voxels
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Collection of voxels divided into groups by a key of their points
+type SplitDensityVoxelSet struct {
+	
+	// Minimum point density to be considered a filled voxel
+	PointDensity int
+
+	// Size of this VoxelSet in the X direction
+	XSize float64
+
+	// Size of this VoxelSet in the Y direction
+	YSize float64
+
+	// Size of this VoxelSet in the Z direction
+	ZSize float64
+
+	// Number of voxels in the X direction
+	XVoxels int
+
+	// Number of voxels in the Y direction
+	YVoxels int
+
+	// Number of voxels in the Z direction
+	ZVoxels int
+
+	// Min number of voxels in the x direction
+	XMin int
+
+	// Min number of voxels in the y direction
+	YMin int
+
+	// Min number of voxels in the z direction
+	ZMin int
+
+	// Grid the voxels are indexed on
+	Grid VoxelGrid
+
+	// Key the points are split by
+	Key lasProcessing.SplitKey
+
+	// Voxel point densities of each group, by key
+	VoxelsByKey map[int]VoxelStorage[int]
+
+	// Point attributes of the voxels of each group, by key, nil if attributes are not tracked
+	AttributesByKey map[int]VoxelStorage[*VoxelAttributes]
+}
+
+// Processes LAS files into VoxelSets divided into groups by a key of their points
+type SplitProcessor struct {
+	
+	// Point density required for a voxel
+	PointDensity int
+
+	// Grid to voxelize onto
+	VoxelGrid
+
+	// Points to voxelize, every point if there are no filters
+	PointFilters lasProcessing.PointFilters
+
+	// Key to split points by
+	Key lasProcessing.SplitKey
+
+	// Whether to track the point attributes of each voxel
+	Attributes bool
+
+	// Names of extra bytes attributes to track, which every file must store
+	ExtraAttributes []string
+
+}
+
+// Gets empty attributes for each group if attributes are tracked, nil otherwise
+func(processor *SplitProcessor) emptyAttributes() map[int]VoxelStorage[*VoxelAttributes] {
+	if !processor.Attributes {
+		return nil
+	}
+
+	return make(map[int]VoxelStorage[*VoxelAttributes])
+}
+
+// Processes a chunk of a LAS file into a VoxelSet
+func(processor *SplitProcessor) Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *lasProcessing.LASChunk, status *lasProcessing.ChunkProgress) (*SplitDensityVoxelSet, error) {
+	
+	status.Set(0.0)
+	
+	min, _ := processor.Extent(&inputFile.Header)
+
+	voxels := &SplitDensityVoxelSet{Key: processor.Key, VoxelsByKey: make(map[int]VoxelStorage[int]), AttributesByKey: processor.emptyAttributes()}
+
+	rawBytes, err := chunk.ReadOnFile(inputFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var extras []*lidarioMod.ExtraBytesAttribute
+
+	if processor.Attributes {
+		if extras, err = findExtraAttributes(inputFile, processor.ExtraAttributes); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := chunk.Start; i < chunk.End; i++ {
+		if err := lasProcessing.CheckCancelled(ctx, i - chunk.Start); err != nil {
+			return nil, err
+		}
+
+		x, y, z := lasProcessing.ReadPointData(inputFile, chunk, rawBytes, i)
+
+		coordinate := processor.PointToCoordinate(x, y, z)
+
+		status.Set(float64(i - chunk.Start) / float64(chunk.End - chunk.Start))
+
+		if !processor.PointFilters.Keep(inputFile, chunk, rawBytes, i) {
+			continue
+		}
+
+		key := processor.Key.Key(inputFile, chunk, rawBytes, i)
+
+		keyVoxels, containsKey := voxels.VoxelsByKey[key]
+
+		if !containsKey {
+			keyVoxels = NewVoxelStorage[int](min)
+			voxels.VoxelsByKey[key] = keyVoxels
+
+			if processor.Attributes {
+				voxels.AttributesByKey[key] = NewVoxelStorage[*VoxelAttributes](min)
+			}
+		}
+
+		val, _ := keyVoxels.Get(coordinate)
+
+		keyVoxels.Set(coordinate, val + 1)
+
+		if processor.Attributes {
+			addPointAttributes(voxels.AttributesByKey[key], coordinate, extras, inputFile, chunk, rawBytes, i)
+		}
+	}
+
+	status.Set(1.0)
+
+	return voxels, nil
+}
+
+// Gets an empty VoxelSet
+func(processor *SplitProcessor) EmptyOutput(inputFile *lidarioMod.LasFile) *SplitDensityVoxelSet {
+	
+	min, count := processor.Extent(&inputFile.Header)
+
+	xSize, ySize, zSize := float64(count.X) * processor.VoxelSize, float64(count.Y) * processor.VoxelSize, float64(count.Z) * processor.VoxelSize
+
+	voxels := make(map[int]VoxelStorage[int])
+
+	return &SplitDensityVoxelSet{XSize: xSize, YSize: ySize, ZSize: zSize, XVoxels: count.X, YVoxels: count.Y, ZVoxels: count.Z, XMin: min.X, YMin: min.Y, ZMin: min.Z, Grid: processor.VoxelGrid, Key: processor.Key, VoxelsByKey: voxels, AttributesByKey: processor.emptyAttributes(), PointDensity: processor.PointDensity}
+}
+
+// Combines two VoxelSets
+func(processor *SplitProcessor) CombineOutput(base *SplitDensityVoxelSet, incoming *SplitDensityVoxelSet) *SplitDensityVoxelSet {
+	for key, voxels := range incoming.VoxelsByKey {
+		baseVoxels, contains := base.VoxelsByKey[key]
+		if contains {
+			voxels.Range(func(coordinate Coordinate, density int) bool {
+				baseDensity, _ := baseVoxels.Get(coordinate)
+				baseVoxels.Set(coordinate, baseDensity + density)
+				return true
+			})
+		} else {
+			base.VoxelsByKey[key] = voxels
+		}
+	}
+
+	for key, attributes := range incoming.AttributesByKey {
+		if baseAttributes, contains := base.AttributesByKey[key]; contains {
+			mergeAttributes(baseAttributes, attributes)
+		} else {
+			base.AttributesByKey[key] = attributes
+		}
+	}
+
+	return base
+}
+
+// Density voxels of the points in one group of a split
+type KeyedDensityVoxelSet struct {
+
+	// Key of the points in the group
+	Key int
+
+	// Name of the group, for naming its outputs
+	Label string
+
+	// Density voxels of the points
+	Voxels *DensityVoxelSet
+
+}
+
+// Splits a SplitDensityVoxelSet into voxel sets
+type KeySplitter struct {
+
+}
+
+// splits into density voxel sets, in order of key
+func(splitter *KeySplitter) Process(ctx context.Context, splitVoxels *SplitDensityVoxelSet, status *lasProcessing.PipelineStatus) ([]*KeyedDensityVoxelSet, error) {
+	sets := make([]*KeyedDensityVoxelSet, 0, len(splitVoxels.VoxelsByKey))
+
+	for key, voxels := range splitVoxels.VoxelsByKey {
+		sets = append(sets, &KeyedDensityVoxelSet{Key: key, Label: splitVoxels.Key.Label(key), Voxels: &DensityVoxelSet{PointDensity: splitVoxels.PointDensity,
+			XVoxels: splitVoxels.XVoxels, YVoxels: splitVoxels.YVoxels, ZVoxels: splitVoxels.ZVoxels,
+			XSize: splitVoxels.XSize, YSize: splitVoxels.YSize, ZSize: splitVoxels.ZSize,
+			XMin: splitVoxels.XMin, YMin: splitVoxels.YMin, ZMin: splitVoxels.ZMin,
+			Grid: splitVoxels.Grid, Voxels: voxels, Attributes: splitVoxels.AttributesByKey[key]}})
+	}
+
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Key < sets[j].Key
+	})
+
+	return sets, nil
+}
@@ -0,0 +1,101 @@
+package voxels
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// Opens the LAS file of the lidarioMod test data, of 1000 points of classes 2, 5 and 7
+func openTestPoints(t *testing.T) *lidarioMod.LasFile {
+	return openTestData(t, "points.las")
+}
+
+// Opens a file of the lidarioMod test data
+func openTestData(t *testing.T, name string) *lidarioMod.LasFile {
+	file, err := lidarioMod.NewLasFile("../lidarioMod/testdata/" + name, "rh")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		file.Close()
+	})
+
+	return file
+}
+
+// Voxelizes a file in several chunks, so that outputs of chunks are combined
+func processTestPoints[T any](t *testing.T, file *lidarioMod.LasFile, processor lasProcessing.LASProcessor[T]) *T {
+	output, err := lasProcessing.SequentialProcess[T](context.Background(), file, lasProcessing.ChunkFile(file, 7), processor, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return output
+}
+
+func TestSplitProcessorTracksAttributesOfEachGroup(t *testing.T) {
+	file := openTestPoints(t)
+
+	grid := VoxelGrid{VoxelSize: 5, Indexing: FloorIndexing}
+
+	split := processTestPoints[SplitDensityVoxelSet](t, file, &SplitProcessor{PointDensity: 1, VoxelGrid: grid,
+		Key: &lasProcessing.ClassKey{}, Attributes: true})
+
+	sets, err := (&KeySplitter{}).Process(context.Background(), split, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sets) != 3 {
+		t.Fatalf("split into %d groups, expected 3", len(sets))
+	}
+
+	// each group matches voxelizing only the points of its class
+	for _, set := range sets {
+		filter := &lasProcessing.ClassFilter{Classes: map[int]bool{set.Key: true}}
+
+		expected := processTestPoints[DensityVoxelSet](t, file, &AttributeVoxelSetProcessor{DensityVoxelSetProcessor: DensityVoxelSetProcessor{
+			PointDensity: 1, VoxelGrid: grid, PointFilters: lasProcessing.PointFilters{filter}}})
+
+		if set.Voxels.Attributes == nil {
+			t.Fatalf("class %d has no attributes", set.Key)
+		}
+
+		if set.Voxels.Attributes.Len() != expected.Attributes.Len() || set.Voxels.Voxels.Len() != expected.Voxels.Len() {
+			t.Fatalf("class %d has %d voxels with %d attributes, expected %d with %d", set.Key, set.Voxels.Voxels.Len(),
+				set.Voxels.Attributes.Len(), expected.Voxels.Len(), expected.Attributes.Len())
+		}
+
+		expected.Attributes.Range(func(coordinate Coordinate, attributes *VoxelAttributes) bool {
+			if actual, _ := set.Voxels.Attributes.Get(coordinate); !reflect.DeepEqual(actual, attributes) {
+				t.Fatalf("class %d voxel %+v has attributes %+v, expected %+v", set.Key, coordinate, actual, attributes)
+			}
+			return true
+		})
+	}
+}
+
+func TestSplitProcessorWithoutAttributes(t *testing.T) {
+	split := processTestPoints[SplitDensityVoxelSet](t, openTestPoints(t), &SplitProcessor{PointDensity: 1,
+		VoxelGrid: VoxelGrid{VoxelSize: 5}, Key: &lasProcessing.ClassKey{}})
+
+	sets, err := (&KeySplitter{}).Process(context.Background(), split, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, set := range sets {
+		if set.Voxels.Attributes != nil {
+			t.Fatalf("class %d tracked attributes that were not asked for", set.Key)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package voxels
+
+// Storage for a value in each voxel of a set of voxels
+type VoxelStorage[V any] interface {
+
+	// Gets the value of a voxel, and whether the voxel is stored
+	Get(coordinate Coordinate) (V, bool)
+
+	// Sets the value of a voxel, storing the voxel if it is not already stored
+	Set(coordinate Coordinate, value V)
+
+	// Stores a voxel with the zero value if it is not already stored
+	Add(coordinate Coordinate)
+
+	// Whether a voxel is stored
+	Contains(coordinate Coordinate) bool
+
+	// Number of voxels stored
+	Len() int
+
+	// Calls visit with each voxel and its value, in no particular order, until visit returns false
+	Range(visit func(coordinate Coordinate, value V) bool)
+
+}
+
+// Value of voxels in storage that only records which voxels are filled
+type Filled struct{}
+
+// Number of bits of a packed key for each axis
+const (
+	packedXBits = 24
+	packedYBits = 24
+	packedZBits = 16
+)
+
+// Voxel storage keyed by voxel coordinates packed into 64 bits relative to an origin, a third of the
+// size of a Coordinate key. Voxels within 2^24 voxels of the origin horizontally and 2^16 voxels above
+// it are packed, any others are kept in a map keyed by Coordinate.
+type PackedVoxels[V any] struct {
+
+	// Voxel the packed coordinates are relative to
+	origin Coordinate
+
+	// Values of packed voxels
+	values map[uint64]V
+
+	// Values of voxels too far from the origin to pack, nil until there are any
+	overflow map[Coordinate]V
+
+}
+
+// Creates storage for voxels at or above the specified origin
+func NewVoxelStorage[V any](origin Coordinate) VoxelStorage[V] {
+	return &PackedVoxels[V]{origin: origin, values: make(map[uint64]V)}
+}
+
+// Packs a coordinate into a key, if it is close enough to the origin
+func(storage *PackedVoxels[V]) pack(coordinate Coordinate) (uint64, bool) {
+	x, y, z := coordinate.X - storage.origin.X, coordinate.Y - storage.origin.Y, coordinate.Z - storage.origin.Z
+
+	if x < 0 || x >= 1 << packedXBits || y < 0 || y >= 1 << packedYBits || z < 0 || z >= 1 << packedZBits {
+		return 0, false
+	}
+
+	return uint64(x) << (packedYBits + packedZBits) | uint64(y) << packedZBits | uint64(z), true
+}
+
+// Unpacks a key into a coordinate
+func(storage *PackedVoxels[V]) unpack(key uint64) Coordinate {
+	return Coordinate{
+		X: storage.origin.X + int(key >> (packedYBits + packedZBits)),
+		Y: storage.origin.Y + int(key >> packedZBits & (1 << packedYBits - 1)),
+		Z: storage.origin.Z + int(key & (1 << packedZBits - 1))}
+}
+
+// Gets the value of a voxel, and whether the voxel is stored
+func(storage *PackedVoxels[V]) Get(coordinate Coordinate) (V, bool) {
+	if key, packed := storage.pack(coordinate); packed {
+		value, contains := storage.values[key]
+		return value, contains
+	}
+
+	value, contains := storage.overflow[coordinate]
+
+	return value, contains
+}
+
+// Sets the value of a voxel, storing the voxel if it is not already stored
+func(storage *PackedVoxels[V]) Set(coordinate Coordinate, value V) {
+	if key, packed := storage.pack(coordinate); packed {
+		storage.values[key] = value
+		return
+	}
+
+	if storage.overflow == nil {
+		storage.overflow = make(map[Coordinate]V)
+	}
+
+	storage.overflow[coordinate] = value
+}
+
+// Stores a voxel with the zero value if it is not already stored
+func(storage *PackedVoxels[V]) Add(coordinate Coordinate) {
+	if !storage.Contains(coordinate) {
+		var zero V
+		storage.Set(coordinate, zero)
+	}
+}
+
+// Whether a voxel is stored
+func(storage *PackedVoxels[V]) Contains(coordinate Coordinate) bool {
+	_, contains := storage.Get(coordinate)
+
+	return contains
+}
+
+// Number of voxels stored
+func(storage *PackedVoxels[V]) Len() int {
+	return len(storage.values) + len(storage.overflow)
+}
+
+// Calls visit with each voxel and its value until visit returns false
+func(storage *PackedVoxels[V]) Range(visit func(coordinate Coordinate, value V) bool) {
+	for key, value := range storage.values {
+		if !visit(storage.unpack(key), value) {
+			return
+		}
+	}
+
+	for coordinate, value := range storage.overflow {
+		if !visit(coordinate, value) {
+			return
+		}
+	}
+}
+
+// Voxel storage keyed by Coordinate, as voxels were stored before PackedVoxels
+type MapVoxels[V any] map[Coordinate]V
+
+// Gets the value of a voxel, and whether the voxel is stored
+func(storage MapVoxels[V]) Get(coordinate Coordinate) (V, bool) {
+	value, contains := storage[coordinate]
+
+	return value, contains
+}
+
+// Sets the value of a voxel, storing the voxel if it is not already stored
+func(storage MapVoxels[V]) Set(coordinate Coordinate, value V) {
+	storage[coordinate] = value
+}
+
+// Stores a voxel with the zero value if it is not already stored
+func(storage MapVoxels[V]) Add(coordinate Coordinate) {
+	if _, contains := storage[coordinate]; !contains {
+		var zero V
+		storage[coordinate] = zero
+	}
+}
+
+// Whether a voxel is stored
+func(storage MapVoxels[V]) Contains(coordinate Coordinate) bool {
+	_, contains := storage[coordinate]
+
+	return contains
+}
+
+// Number of voxels stored
+func(storage MapVoxels[V]) Len() int {
+	return len(storage)
+}
+
+// Calls visit with each voxel and its value until visit returns false
+func(storage MapVoxels[V]) Range(visit func(coordinate Coordinate, value V) bool) {
+	for coordinate, value := range storage {
+		if !visit(coordinate, value) {
+			return
+		}
+	}
+}
+
+// Storage for a value in each column of voxels
+type ColumnStorage[V any] interface {
+
+	// Gets the value of a column, and whether the column is stored
+	Get(xy XYPair) (V, bool)
+
+	// Sets the value of a column, storing the column if it is not already stored
+	Set(xy XYPair, value V)
+
+	// Number of columns stored
+	Len() int
+
+	// Calls visit with each column and its value, in no particular order, until visit returns false
+	Range(visit func(xy XYPair, value V) bool)
+
+}
+
+// Number of bits of a packed column key for each axis
+const packedColumnBits = 32
+
+// Column storage keyed by column coordinates packed into 64 bits relative to an origin, half the size
+// of an XYPair key. Columns within 2^32 columns of the origin are packed, any others are kept in a map
+// keyed by XYPair.
+type PackedColumns[V any] struct {
+
+	// Column the packed coordinates are relative to
+	origin XYPair
+
+	// Values of packed columns
+	values map[uint64]V
+
+	// Values of columns too far from the origin to pack, nil until there are any
+	overflow map[XYPair]V
+
+}
+
+// Creates storage for columns at or above the specified origin
+func NewColumnStorage[V any](origin XYPair) ColumnStorage[V] {
+	return &PackedColumns[V]{origin: origin, values: make(map[uint64]V)}
+}
+
+// Packs a column into a key, if it is close enough to the origin
+func(storage *PackedColumns[V]) pack(xy XYPair) (uint64, bool) {
+	x, y := xy.X - storage.origin.X, xy.Y - storage.origin.Y
+
+	if x < 0 || x >= 1 << packedColumnBits || y < 0 || y >= 1 << packedColumnBits {
+		return 0, false
+	}
+
+	return uint64(x) << packedColumnBits | uint64(y), true
+}
+
+// Unpacks a key into a column
+func(storage *PackedColumns[V]) unpack(key uint64) XYPair {
+	return XYPair{
+		X: storage.origin.X + int(key >> packedColumnBits),
+		Y: storage.origin.Y + int(key & (1 << packedColumnBits - 1))}
+}
+
+// Gets the value of a column, and whether the column is stored
+func(storage *PackedColumns[V]) Get(xy XYPair) (V, bool) {
+	if key, packed := storage.pack(xy); packed {
+		value, contains := storage.values[key]
+		return value, contains
+	}
+
+	value, contains := storage.overflow[xy]
+
+	return value, contains
+}
+
+// Sets the value of a column, storing the column if it is not already stored
+func(storage *PackedColumns[V]) Set(xy XYPair, value V) {
+	if key, packed := storage.pack(xy); packed {
+		storage.values[key] = value
+		return
+	}
+
+	if storage.overflow == nil {
+		storage.overflow = make(map[XYPair]V)
+	}
+
+	storage.overflow[xy] = value
+}
+
+// Number of columns stored
+func(storage *PackedColumns[V]) Len() int {
+	return len(storage.values) + len(storage.overflow)
+}
+
+// Calls visit with each column and its value until visit returns false
+func(storage *PackedColumns[V]) Range(visit func(xy XYPair, value V) bool) {
+	for key, value := range storage.values {
+		if !visit(storage.unpack(key), value) {
+			return
+		}
+	}
+
+	for xy, value := range storage.overflow {
+		if !visit(xy, value) {
+			return
+		}
+	}
+}
+
+// Column storage keyed by XYPair, as columns were stored before PackedColumns
+type MapColumns[V any] map[XYPair]V
+
+// Gets the value of a column, and whether the column is stored
+func(storage MapColumns[V]) Get(xy XYPair) (V, bool) {
+	value, contains := storage[xy]
+
+	return value, contains
+}
+
+// Sets the value of a column, storing the column if it is not already stored
+func(storage MapColumns[V]) Set(xy XYPair, value V) {
+	storage[xy] = value
+}
+
+// Number of columns stored
+func(storage MapColumns[V]) Len() int {
+	return len(storage)
+}
+
+// Calls visit with each column and its value until visit returns false
+func(storage MapColumns[V]) Range(visit func(xy XYPair, value V) bool) {
+	for xy, value := range storage {
+		if !visit(xy, value) {
+			return
+		}
+	}
+}
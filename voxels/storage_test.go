@@ -0,0 +1,312 @@
+package voxels
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Origin of the storage under test, negative so that packing is relative to it
+var testOrigin = Coordinate{X: -1000, Y: -2000, Z: -50}
+
+// Voxels at and either side of the limits of each packed field, with whether they are packed
+var packingCases = []struct {
+	coordinate Coordinate
+	packed bool
+}{
+	{testOrigin, true},
+	{Coordinate{X: -1, Y: -1, Z: -1}, true},
+	{Coordinate{X: 0, Y: 0, Z: 0}, true},
+	{Coordinate{X: testOrigin.X + 1 << packedXBits - 1, Y: testOrigin.Y + 1 << packedYBits - 1, Z: testOrigin.Z + 1 << packedZBits - 1}, true},
+	{Coordinate{X: testOrigin.X + 1 << packedXBits - 1, Y: testOrigin.Y, Z: testOrigin.Z}, true},
+	{Coordinate{X: testOrigin.X, Y: testOrigin.Y + 1 << packedYBits - 1, Z: testOrigin.Z}, true},
+	{Coordinate{X: testOrigin.X, Y: testOrigin.Y, Z: testOrigin.Z + 1 << packedZBits - 1}, true},
+	{Coordinate{X: testOrigin.X + 1 << packedXBits, Y: testOrigin.Y, Z: testOrigin.Z}, false},
+	{Coordinate{X: testOrigin.X, Y: testOrigin.Y + 1 << packedYBits, Z: testOrigin.Z}, false},
+	{Coordinate{X: testOrigin.X, Y: testOrigin.Y, Z: testOrigin.Z + 1 << packedZBits}, false},
+	{Coordinate{X: testOrigin.X - 1, Y: testOrigin.Y, Z: testOrigin.Z}, false},
+	{Coordinate{X: testOrigin.X, Y: testOrigin.Y - 1, Z: testOrigin.Z}, false},
+	{Coordinate{X: testOrigin.X, Y: testOrigin.Y, Z: testOrigin.Z - 1}, false},
+	{Coordinate{X: -1 << 40, Y: 1 << 40, Z: -1 << 20}, false}}
+
+func TestPackedVoxelsPacking(t *testing.T) {
+	storage := NewVoxelStorage[int](testOrigin).(*PackedVoxels[int])
+
+	for i, test := range packingCases {
+		key, packed := storage.pack(test.coordinate)
+
+		if packed != test.packed {
+			t.Fatalf("%+v packed is %v, expected %v", test.coordinate, packed, test.packed)
+		}
+
+		if packed && storage.unpack(key) != test.coordinate {
+			t.Fatalf("%+v unpacked to %+v", test.coordinate, storage.unpack(key))
+		}
+
+		storage.Set(test.coordinate, i)
+	}
+
+	overflowing := 0
+
+	for i, test := range packingCases {
+		if !test.packed {
+			overflowing += 1
+		}
+
+		if value, contains := storage.Get(test.coordinate); !contains || value != i {
+			t.Fatalf("%+v is %d (stored %v), expected %d", test.coordinate, value, contains, i)
+		}
+	}
+
+	if len(storage.overflow) != overflowing || storage.Len() != len(packingCases) {
+		t.Fatalf("%d of %d voxels overflowed, expected %d of %d", len(storage.overflow), storage.Len(), overflowing, len(packingCases))
+	}
+
+	visited := make(map[Coordinate]int)
+
+	storage.Range(func(coordinate Coordinate, value int) bool {
+		visited[coordinate] = value
+		return true
+	})
+
+	for i, test := range packingCases {
+		if value, contains := visited[test.coordinate]; !contains || value != i {
+			t.Fatalf("range visited %+v with %d (visited %v), expected %d", test.coordinate, value, contains, i)
+		}
+	}
+
+	if len(visited) != len(packingCases) {
+		t.Fatalf("range visited %d voxels, expected %d", len(visited), len(packingCases))
+	}
+}
+
+func TestPackedVoxelsMatchMapVoxels(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	packed, unpacked := NewVoxelStorage[int](testOrigin), MapVoxels[int]{}
+
+	// mostly packed voxels, with some either side of the origin and far above it
+	for i := 0; i < 10000; i++ {
+		coordinate := Coordinate{X: testOrigin.X + random.Intn(300) - 20, Y: testOrigin.Y + random.Intn(300) - 20,
+			Z: testOrigin.Z + random.Intn(1 << packedZBits + 100) - 20}
+
+		switch random.Intn(3) {
+		case 0:
+			packed.Add(coordinate)
+			unpacked.Add(coordinate)
+		default:
+			packed.Set(coordinate, i)
+			unpacked.Set(coordinate, i)
+		}
+	}
+
+	if packed.Len() != unpacked.Len() {
+		t.Fatalf("%d packed voxels, expected %d", packed.Len(), unpacked.Len())
+	}
+
+	unpacked.Range(func(coordinate Coordinate, expected int) bool {
+		if value, contains := packed.Get(coordinate); !contains || value != expected {
+			t.Fatalf("%+v is %d (stored %v), expected %d", coordinate, value, contains, expected)
+		}
+		return true
+	})
+
+	packed.Range(func(coordinate Coordinate, value int) bool {
+		if !unpacked.Contains(coordinate) {
+			t.Fatalf("range visited %+v, which was never stored", coordinate)
+		}
+		return true
+	})
+
+	stopped := 0
+
+	packed.Range(func(coordinate Coordinate, value int) bool {
+		stopped += 1
+		return stopped < 10
+	})
+
+	if stopped != 10 {
+		t.Fatalf("range visited %d voxels after being stopped at 10", stopped)
+	}
+}
+
+func TestPackedColumnsPacking(t *testing.T) {
+	origin := XYPair{X: testOrigin.X, Y: testOrigin.Y}
+
+	storage := NewColumnStorage[int](origin).(*PackedColumns[int])
+
+	cases := []struct {
+		xy XYPair
+		packed bool
+	}{
+		{origin, true},
+		{XYPair{X: -1, Y: -1}, true},
+		{XYPair{X: origin.X + 1 << packedColumnBits - 1, Y: origin.Y + 1 << packedColumnBits - 1}, true},
+		{XYPair{X: origin.X + 1 << packedColumnBits, Y: origin.Y}, false},
+		{XYPair{X: origin.X, Y: origin.Y + 1 << packedColumnBits}, false},
+		{XYPair{X: origin.X - 1, Y: origin.Y}, false},
+		{XYPair{X: origin.X, Y: origin.Y - 1}, false}}
+
+	for i, test := range cases {
+		key, packed := storage.pack(test.xy)
+
+		if packed != test.packed {
+			t.Fatalf("%+v packed is %v, expected %v", test.xy, packed, test.packed)
+		}
+
+		if packed && storage.unpack(key) != test.xy {
+			t.Fatalf("%+v unpacked to %+v", test.xy, storage.unpack(key))
+		}
+
+		storage.Set(test.xy, i)
+	}
+
+	if len(storage.overflow) != 4 || storage.Len() != len(cases) {
+		t.Fatalf("%d of %d columns overflowed, expected 4 of %d", len(storage.overflow), storage.Len(), len(cases))
+	}
+
+	visited := 0
+
+	storage.Range(func(xy XYPair, value int) bool {
+		if stored, _ := storage.Get(xy); stored != value || cases[value].xy != xy {
+			t.Fatalf("range visited %+v with %d", xy, value)
+		}
+		visited += 1
+		return true
+	})
+
+	if visited != len(cases) {
+		t.Fatalf("range visited %d columns, expected %d", visited, len(cases))
+	}
+}
+
+// Number of voxels in each benchmark
+const benchmarkVoxels = 1 << 20
+
+// Gets coordinates of voxels spread over a 256 x 256 x 64 extent, as in a small plot
+func benchmarkCoordinates() []Coordinate {
+	random := rand.New(rand.NewSource(1))
+
+	coordinates := make([]Coordinate, benchmarkVoxels)
+
+	for i := range coordinates {
+		coordinates[i] = Coordinate{X: testOrigin.X + random.Intn(256), Y: testOrigin.Y + random.Intn(256), Z: testOrigin.Z + random.Intn(64)}
+	}
+
+	return coordinates
+}
+
+// Creates packed voxel storage
+func newPackedVoxels() VoxelStorage[int] {
+	return NewVoxelStorage[int](testOrigin)
+}
+
+// Creates map voxel storage
+func newMapVoxels() VoxelStorage[int] {
+	return MapVoxels[int]{}
+}
+
+// Creates storage holding the voxels
+func filledStorage(create func() VoxelStorage[int], coordinates []Coordinate) VoxelStorage[int] {
+	storage := create()
+
+	for _, coordinate := range coordinates {
+		storage.Add(coordinate)
+	}
+
+	return storage
+}
+
+// Adds every voxel to new storage
+func benchmarkAdd(b *testing.B, create func() VoxelStorage[int]) {
+	coordinates := benchmarkCoordinates()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		filledStorage(create, coordinates)
+	}
+}
+
+// Gets every voxel from full storage
+func benchmarkGet(b *testing.B, create func() VoxelStorage[int]) {
+	coordinates := benchmarkCoordinates()
+
+	storage := filledStorage(create, coordinates)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, coordinate := range coordinates {
+			storage.Get(coordinate)
+		}
+	}
+}
+
+// Visits every voxel of full storage
+func benchmarkRange(b *testing.B, create func() VoxelStorage[int]) {
+	storage := filledStorage(create, benchmarkCoordinates())
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		total := 0
+
+		storage.Range(func(coordinate Coordinate, value int) bool {
+			total += value + coordinate.Z
+			return true
+		})
+	}
+}
+
+// Merges the densities of two halves of the voxels, as chunk outputs are combined
+func benchmarkMerge(b *testing.B, create func() VoxelStorage[int]) {
+	coordinates := benchmarkCoordinates()
+
+	half := len(coordinates) / 2
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+
+		base, incoming := filledStorage(create, coordinates[:half]), filledStorage(create, coordinates[half:])
+
+		b.StartTimer()
+
+		incoming.Range(func(coordinate Coordinate, density int) bool {
+			baseDensity, _ := base.Get(coordinate)
+			base.Set(coordinate, baseDensity + density)
+			return true
+		})
+	}
+}
+
+func BenchmarkPackedVoxelsAdd(b *testing.B) {
+	benchmarkAdd(b, newPackedVoxels)
+}
+
+func BenchmarkMapVoxelsAdd(b *testing.B) {
+	benchmarkAdd(b, newMapVoxels)
+}
+
+func BenchmarkPackedVoxelsGet(b *testing.B) {
+	benchmarkGet(b, newPackedVoxels)
+}
+
+func BenchmarkMapVoxelsGet(b *testing.B) {
+	benchmarkGet(b, newMapVoxels)
+}
+
+func BenchmarkPackedVoxelsRange(b *testing.B) {
+	benchmarkRange(b, newPackedVoxels)
+}
+
+func BenchmarkMapVoxelsRange(b *testing.B) {
+	benchmarkRange(b, newMapVoxels)
+}
+
+func BenchmarkPackedVoxelsMerge(b *testing.B) {
+	benchmarkMerge(b, newPackedVoxels)
+}
+
+func BenchmarkMapVoxelsMerge(b *testing.B) {
+	benchmarkMerge(b, newMapVoxels)
+}
@@ -0,0 +1,274 @@
+package voxels
+
+import (
+	"context"
+	"math"
+
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
+	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
+)
+
+// A rectangle of columns over the XY plane
+type Region struct {
+
+	// Min number of voxels in the x direction
+	XMin int
+
+	// Min number of voxels in the y direction
+	YMin int
+
+	// Number of voxels in the X direction
+	XVoxels int
+
+	// Number of voxels in the Y direction
+	YVoxels int
+
+}
+
+// Whether the region contains a column
+func(region *Region) Contains(x int, y int) bool {
+	return x >= region.XMin && x < region.XMin + region.XVoxels && y >= region.YMin && y < region.YMin + region.YVoxels
+}
+
+// Gets the part of the region inside another region, with no voxels if they do not overlap
+func(region *Region) Intersect(other Region) Region {
+	xMin, yMin := maxInt(region.XMin, other.XMin), maxInt(region.YMin, other.YMin)
+
+	xMax := minInt(region.XMin + region.XVoxels, other.XMin + other.XVoxels)
+	yMax := minInt(region.YMin + region.YVoxels, other.YMin + other.YVoxels)
+
+	return Region{XMin: xMin, YMin: yMin, XVoxels: maxInt(xMax - xMin, 0), YVoxels: maxInt(yMax - yMin, 0)}
+}
+
+// Whether the region has no columns
+func(region *Region) Empty() bool {
+	return region.XVoxels <= 0 || region.YVoxels <= 0
+}
+
+// A tile of a dataset, processed on its own
+type Tile struct {
+
+	// Columns the tile outputs
+	Core Region
+
+	// Columns the tile processes, the core and a buffer around it, so that processing
+	// that depends on neighbouring columns is the same at the edge of the core as inside it
+	Buffered Region
+
+}
+
+// Divides the extent of a LAS file into tiles of the specified size with the specified buffer,
+// both in the units of the voxel size and rounded to whole voxels
+func(grid *VoxelGrid) Tiles(header *lidarioMod.LasHeader, tileSize float64, buffer float64) []Tile {
+	min, count := grid.Extent(header)
+
+	tileVoxels := maxInt(int(math.Round(tileSize / grid.VoxelSize)), 1)
+
+	bufferVoxels := maxInt(int(math.Ceil(buffer / grid.VoxelSize)), 0)
+
+	tiles := make([]Tile, 0)
+
+	for y := min.Y; y < min.Y + count.Y; y += tileVoxels {
+		for x := min.X; x < min.X + count.X; x += tileVoxels {
+			core := Region{XMin: x, YMin: y,
+				XVoxels: minInt(tileVoxels, min.X + count.X - x),
+				YVoxels: minInt(tileVoxels, min.Y + count.Y - y)}
+
+			buffered := Region{XMin: core.XMin - bufferVoxels, YMin: core.YMin - bufferVoxels,
+				XVoxels: core.XVoxels + 2 * bufferVoxels, YVoxels: core.YVoxels + 2 * bufferVoxels}
+
+			tiles = append(tiles, Tile{Core: core, Buffered: buffered})
+		}
+	}
+
+	return tiles
+}
+
+// Gets the columns covering the bounds of a LAS file
+func(grid *VoxelGrid) FileRegion(header *lidarioMod.LasHeader) Region {
+	min, count := grid.Extent(header)
+
+	return Region{XMin: min.X, YMin: min.Y, XVoxels: count.X, YVoxels: count.Y}
+}
+
+// Gets the columns covering an area of the XY plane
+func(grid *VoxelGrid) BoundsRegion(minX float64, minY float64, maxX float64, maxY float64) Region {
+	min := grid.PointToCoordinate(minX, minY, 0)
+
+	max := grid.PointToCoordinate(maxX, maxY, 0)
+
+	return Region{XMin: min.X, YMin: min.Y, XVoxels: max.X - min.X + 1, YVoxels: max.Y - min.Y + 1}
+}
+
+// Restricts a voxel set to the columns in a region
+type VoxelCropper struct {
+
+	// Columns to keep
+	Region Region
+
+}
+
+// Removes voxels outside the region
+func(cropper *VoxelCropper) Process(ctx context.Context, voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) (*VoxelSet, error) {
+	origin := Coordinate{X: voxelSet.XMin, Y: voxelSet.YMin, Z: voxelSet.ZMin}
+
+	cropped := NewVoxelStorage[Filled](origin)
+
+	total := voxelSet.Voxels.Len()
+
+	current := 0
+
+	status.Set("Cropping", 0.0)
+
+	var err error
+
+	voxelSet.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
+		if cropper.Region.Contains(voxel.X, voxel.Y) {
+			cropped.Add(voxel)
+		}
+
+		current += 1
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Cropping", float64(current) / float64(total))
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	region := cropper.Region.Intersect(Region{XMin: voxelSet.XMin, YMin: voxelSet.YMin, XVoxels: voxelSet.XVoxels, YVoxels: voxelSet.YVoxels})
+
+	output := *voxelSet
+
+	output.Voxels = cropped
+	output.XMin, output.YMin, output.XVoxels, output.YVoxels = region.XMin, region.YMin, region.XVoxels, region.YVoxels
+	output.XSize, output.YSize = float64(region.XVoxels) * voxelSet.Grid.VoxelSize, float64(region.YVoxels) * voxelSet.Grid.VoxelSize
+
+	if voxelSet.Attributes != nil {
+		output.Attributes = NewVoxelStorage[*VoxelAttributes](origin)
+
+		cropped.Range(func(voxel Coordinate, _ Filled) bool {
+			attributes, _ := voxelSet.Attributes.Get(voxel)
+			output.Attributes.Set(voxel, attributes)
+			return true
+		})
+	}
+
+	if voxelSet.ClassifiedGround != nil {
+		output.ClassifiedGround = NewColumnStorage[int](XYPair{X: origin.X, Y: origin.Y})
+
+		voxelSet.ClassifiedGround.Range(func(xy XYPair, height int) bool {
+			if cropper.Region.Contains(xy.X, xy.Y) {
+				output.ClassifiedGround.Set(xy, height)
+			}
+			return true
+		})
+	}
+
+	return &output, nil
+}
+
+// Restricts measurements to the columns in a region
+type MeasurementsCropper struct {
+
+	// Columns to keep
+	Region Region
+
+}
+
+// Removes measurements outside the region
+func(cropper *MeasurementsCropper) Process(ctx context.Context, measurements *Measurements, status *lasProcessing.PipelineStatus) (*Measurements, error) {
+	region := cropper.Region.Intersect(Region{XMin: measurements.XMin, YMin: measurements.YMin, XVoxels: measurements.XVoxels, YVoxels: measurements.YVoxels})
+
+	output := &Measurements{
+		CanopyBaseHeight: make(map[XYPair]int),
+		FuelStrataGap: make(map[XYPair]int),
+		CanopyHeight: make(map[XYPair]int),
+		UnderstoryHeight: make(map[XYPair]int),
+		XVoxels: region.XVoxels,
+		YVoxels: region.YVoxels,
+		XMin: region.XMin,
+		YMin: region.YMin,
+		Grid: measurements.Grid,
+		Normalized: measurements.Normalized}
+
+	total := len(measurements.CanopyHeight)
+
+	current := 0
+
+	status.Set("Cropping", 0.0)
+
+	for xy := range measurements.CanopyHeight {
+		if cropper.Region.Contains(xy.X, xy.Y) {
+			output.CanopyBaseHeight[xy] = measurements.CanopyBaseHeight[xy]
+			output.FuelStrataGap[xy] = measurements.FuelStrataGap[xy]
+			output.CanopyHeight[xy] = measurements.CanopyHeight[xy]
+			output.UnderstoryHeight[xy] = measurements.UnderstoryHeight[xy]
+		}
+
+		current += 1
+
+		if err := lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return nil, err
+		}
+		status.Set("Cropping", float64(current) / float64(total))
+	}
+
+	return output, nil
+}
+
+// Adds the measurements of other columns, extending the extent to cover them
+func(measurements *Measurements) Add(other *Measurements) {
+	for xy := range other.CanopyHeight {
+		measurements.CanopyBaseHeight[xy] = other.CanopyBaseHeight[xy]
+		measurements.FuelStrataGap[xy] = other.FuelStrataGap[xy]
+		measurements.CanopyHeight[xy] = other.CanopyHeight[xy]
+		measurements.UnderstoryHeight[xy] = other.UnderstoryHeight[xy]
+	}
+
+	measurements.Normalized = other.Normalized
+
+	if other.XVoxels == 0 || other.YVoxels == 0 {
+		return
+	}
+
+	if measurements.XVoxels == 0 || measurements.YVoxels == 0 {
+		measurements.XMin, measurements.YMin, measurements.XVoxels, measurements.YVoxels = other.XMin, other.YMin, other.XVoxels, other.YVoxels
+		return
+	}
+
+	xMin, yMin := minInt(measurements.XMin, other.XMin), minInt(measurements.YMin, other.YMin)
+	xMax := maxInt(measurements.XMin + measurements.XVoxels, other.XMin + other.XVoxels)
+	yMax := maxInt(measurements.YMin + measurements.YVoxels, other.YMin + other.YVoxels)
+
+	measurements.XMin, measurements.YMin, measurements.XVoxels, measurements.YVoxels = xMin, yMin, xMax - xMin, yMax - yMin
+}
+
+// Creates measurements with no columns, for adding the measurements of tiles to
+func EmptyMeasurements(grid VoxelGrid) *Measurements {
+	return &Measurements{
+		CanopyBaseHeight: make(map[XYPair]int),
+		FuelStrataGap: make(map[XYPair]int),
+		CanopyHeight: make(map[XYPair]int),
+		UnderstoryHeight: make(map[XYPair]int),
+		Grid: grid}
+}
+
+// Adds the counts of another gradient at each height
+func(gradient *HeightGradient) Add(other *HeightGradient) {
+	for height, count := range other.Gradient {
+		gradient.Gradient[height] += count
+	}
+
+	gradient.Grid = other.Grid
+	gradient.Normalized = other.Normalized
+}
+
+// Creates a gradient with no heights, for adding the gradients of tiles to
+func EmptyHeightGradient(grid VoxelGrid) *HeightGradient {
+	return &HeightGradient{Gradient: make(map[int]int), Grid: grid}
+}
@@ -1,86 +1,77 @@
 package voxels
 
 import (
-	"math"
-
+	"context"
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lidarioMod"
-
-	mapset "github.com/deckarep/golang-set/v2"
 )
 
 // Processes LAS files into VoxelSets
 type VoxelSetProcessor struct {
 	
-	// Voxel size to use with this processor
-	VoxelSize float64
+	// Grid to voxelize onto
+	VoxelGrid
+
+	// Points to voxelize, every point if there are no filters
+	PointFilters lasProcessing.PointFilters
 
 }
 
 // Processes a chunk of a LAS file into a VoxelSet
-func(processor *VoxelSetProcessor) Process(inputFile *lidarioMod.LasFile, chunk *lasProcessing.LASChunk, output chan<- *VoxelSet, status *float64) {
+func(processor *VoxelSetProcessor) Process(ctx context.Context, inputFile *lidarioMod.LasFile, chunk *lasProcessing.LASChunk, status *lasProcessing.ChunkProgress) (*VoxelSet, error) {
 	
-	*status = 0.0
-	
-	minX, minY, minZ := inputFile.Header.MinX, inputFile.Header.MinY, inputFile.Header.MinZ
+	status.Set(0.0)
 
-	minXVoxel, minYVoxel, minZVoxel := int(minX / processor.VoxelSize), int(minY / processor.VoxelSize), int(minZ / processor.VoxelSize)
+	min, _ := processor.Extent(&inputFile.Header)
 
-	voxels := &VoxelSet{Voxels: mapset.NewThreadUnsafeSet[Coordinate](), XMin: minXVoxel, YMin: minYVoxel, ZMin: minZVoxel}
+	voxels := &VoxelSet{Voxels: NewVoxelStorage[Filled](min)}
 	
-	rawBytes := chunk.ReadOnFile(inputFile)
+	rawBytes, err := chunk.ReadOnFile(inputFile)
+
+	if err != nil {
+		return nil, err
+	}
 
 	for i := chunk.Start; i < chunk.End; i++ {
+		if err := lasProcessing.CheckCancelled(ctx, i - chunk.Start); err != nil {
+			return nil, err
+		}
+
 		x, y, z := lasProcessing.ReadPointData(inputFile, chunk, rawBytes, i)
 		
-		coordinate := PointToCoordinate(x, minX, y, minY, z, minZ, processor.VoxelSize, false)
+		coordinate := processor.PointToCoordinate(x, y, z)
+
+		status.Set(float64(i - chunk.Start) / float64(chunk.End - chunk.Start))
 
-		*status = float64(i - chunk.Start) / float64(chunk.End - chunk.Start)
+		if !processor.PointFilters.Keep(inputFile, chunk, rawBytes, i) {
+			continue
+		}
 
 		voxels.Voxels.Add(coordinate)
 	}
 
-	*status = 1.0
+	status.Set(1.0)
 
-	output <- voxels
+	return voxels, nil
 }
 
 // Gets an empty VoxelSet
 func(processor *VoxelSetProcessor) EmptyOutput(inputFile *lidarioMod.LasFile) *VoxelSet {
 	
-	header := inputFile.Header
-
-	xSizeRaw, ySizeRaw, zSizeRaw := header.MaxX - header.MinX, header.MaxY - header.MinY, header.MaxZ - header.MinZ
-
-	xRemainder, yRemainder, zRemainder := math.Mod(xSizeRaw, processor.VoxelSize), math.Mod(ySizeRaw, processor.VoxelSize), math.Mod(zSizeRaw, processor.VoxelSize)
-
-	xVoxels, yVoxels, zVoxels := int(xSizeRaw / processor.VoxelSize), int(ySizeRaw / processor.VoxelSize), int(zSizeRaw / processor.VoxelSize)
-
-	if (xRemainder != 0) {
-		xVoxels += 1
-	}
-
-	if (yRemainder != 0) {
-		yVoxels += 1
-	}
-
-	if (zRemainder != 0) {
-		zVoxels += 1
-	}
-
-	xSize, ySize, zSize := float64(xVoxels) * processor.VoxelSize, float64(yVoxels) * processor.VoxelSize, float64(zVoxels) * processor.VoxelSize
-
-	minX, minY, minZ := inputFile.Header.MinX, inputFile.Header.MinY, inputFile.Header.MinZ
+	min, count := processor.Extent(&inputFile.Header)
 
-	minXVoxel, minYVoxel, minZVoxel := int(minX / processor.VoxelSize), int(minY / processor.VoxelSize), int(minZ / processor.VoxelSize)
+	xSize, ySize, zSize := float64(count.X) * processor.VoxelSize, float64(count.Y) * processor.VoxelSize, float64(count.Z) * processor.VoxelSize
 
-	voxels := mapset.NewThreadUnsafeSet[Coordinate]()
+	voxels := NewVoxelStorage[Filled](min)
 
-	return &VoxelSet{XSize: xSize, YSize: ySize, ZSize: zSize, XVoxels: xVoxels, YVoxels: yVoxels, ZVoxels: zVoxels, Voxels: voxels, XMin: minXVoxel, YMin: minYVoxel, ZMin: minZVoxel}
+	return &VoxelSet{XSize: xSize, YSize: ySize, ZSize: zSize, XVoxels: count.X, YVoxels: count.Y, ZVoxels: count.Z, Voxels: voxels, XMin: min.X, YMin: min.Y, ZMin: min.Z, Grid: processor.VoxelGrid}
 }
 
 // Combines two VoxelSets
 func(processor *VoxelSetProcessor) CombineOutput(base *VoxelSet, incoming *VoxelSet) *VoxelSet {
-	base.Voxels = base.Voxels.Union(incoming.Voxels)
+	incoming.Voxels.Range(func(coordinate Coordinate, _ Filled) bool {
+		base.Voxels.Add(coordinate)
+		return true
+	})
 	return base
 }
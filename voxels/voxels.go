@@ -1,12 +1,15 @@
 package voxels
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"math"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/Jacob4649/go-voxelize/go-voxelize/lasProcessing"
 	mapset "github.com/deckarep/golang-set/v2"
@@ -56,8 +59,20 @@ type VoxelSet struct {
 	// Min number of voxels in the z direction
 	ZMin int
 
+	// Grid the voxels are indexed on
+	Grid VoxelGrid
+
 	// Set of voxels in this VoxelSet
-	Voxels mapset.Set[Coordinate]
+	Voxels VoxelStorage[Filled]
+
+	// Point attributes of each voxel, nil if attributes are not tracked
+	Attributes VoxelStorage[*VoxelAttributes]
+
+	// Lowest voxel of any density containing ground class points in each column, nil if attributes are not tracked
+	ClassifiedGround ColumnStorage[int]
+
+	// Whether Z is the height above the ground rather than an elevation
+	Normalized bool
 }
 
 // A height gradient of voxels
@@ -66,6 +81,12 @@ type HeightGradient struct {
 	// the height gradient map
 	Gradient map[int]int
 
+	// Grid the heights are indexed on
+	Grid VoxelGrid
+
+	// Whether heights are above the ground rather than elevations
+	Normalized bool
+
 }
 
 // A column of voxels
@@ -143,7 +164,7 @@ type MeasurementFinder struct {
 }
 
 // finds measurements
-func(finder *MeasurementFinder) Process(voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) *Measurements {
+func(finder *MeasurementFinder) Process(ctx context.Context, voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) (*Measurements, error) {
 
 	// map xy to column of voxels
 	columns := make(map[XYPair]*Column)
@@ -151,13 +172,15 @@ func(finder *MeasurementFinder) Process(voxelSet *VoxelSet, status *lasProcessin
 	// height of the ground to set for all columns
 	groundHeight := math.MaxInt64
 
-	total := voxelSet.Voxels.Cardinality()
+	total := voxelSet.Voxels.Len()
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Columns", Progress: 0.0}
+	status.Set("Columns", 0.0)
+
+	var err error
 
-	for voxel := range voxelSet.Voxels.Iterator().C {
+	voxelSet.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
 		xy := XYPair{X: voxel.X, Y: voxel.Y}
 		column, contains := columns[xy]
 		if contains {
@@ -171,14 +194,29 @@ func(finder *MeasurementFinder) Process(voxelSet *VoxelSet, status *lasProcessin
 		}
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Columns", Progress: float64(current) / float64(total)}
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Columns", float64(current) / float64(total))
+		return true
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	*status = lasProcessing.PipelineStatus{Step: "Measuring", Progress: 0.0}
+	return measureColumns(ctx, columns, voxelSet, status)
+}
+
+// measures each column of a voxel set
+func measureColumns(ctx context.Context, columns map[XYPair]*Column, voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) (*Measurements, error) {
 
-	measurements := createMeasurements()
-	current = 0
-	total = len(columns)
+	status.Set("Measuring", 0.0)
+
+	measurements := createMeasurements(voxelSet)
+	current := 0
+	total := len(columns)
 
 	for coords, column := range columns {
 
@@ -186,15 +224,19 @@ func(finder *MeasurementFinder) Process(voxelSet *VoxelSet, status *lasProcessin
 		measurements.addColumn(column, coords)
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Measuring", Progress: float64(current) / float64(total)}
+
+		if err := lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return nil, err
+		}
+		status.Set("Measuring", float64(current) / float64(total))
 	}
 
-	return measurements
+	return measurements, nil
 }
 
 // A collection of measurements over the 2D ground plane.
 // Measurements are calculated as per https://doi.org/10.1016/j.foreco.2021.119037.
-// All measurements are in voxels.
+// All measurements are in voxels, and heights are voxel Z indices unless normalized.
 type Measurements struct {
 
 	// map of the canopy base height at different points
@@ -209,15 +251,56 @@ type Measurements struct {
 	// map of the understory height at different points
 	UnderstoryHeight map[XYPair]int
 
+	// Number of voxels in the X direction
+	XVoxels int
+
+	// Number of voxels in the Y direction
+	YVoxels int
+
+	// Min number of voxels in the x direction
+	XMin int
+
+	// Min number of voxels in the y direction
+	YMin int
+
+	// Grid the measured voxels are indexed on
+	Grid VoxelGrid
+
+	// Whether heights are above the ground rather than elevations
+	Normalized bool
+
 }
 
-// creates a new set of measurements
-func createMeasurements() *Measurements {
+// Gets the names of the measurements in the order they are written, heights being elevations
+// unless the measurements are normalized
+func(measurements *Measurements) names() []string {
+	if measurements.Normalized {
+		return []string{"understory_height", "canopy_base_height", "fuel_strata_gap", "canopy_height"}
+	}
+
+	return []string{"understory_elevation", "canopy_base_elevation", "fuel_strata_gap", "canopy_elevation"}
+}
+
+// Converts a height in voxels to the units of the CRS, as an elevation unless the measurements are normalized
+func(measurements *Measurements) worldHeight(height int) float64 {
+	_, _, z := measurements.Grid.VoxelCorner(Coordinate{Z: height})
+
+	if measurements.Normalized {
+		z -= measurements.Grid.Anchor.Z
+	}
+
+	return z
+}
+
+// creates a new set of measurements over the columns of a voxel set
+func createMeasurements(voxelSet *VoxelSet) *Measurements {
 	cbh := make(map[XYPair]int)
 	fsg := make(map[XYPair]int)
 	ch := make(map[XYPair]int)
 	uh := make(map[XYPair]int)
-	return &Measurements{CanopyBaseHeight: cbh, FuelStrataGap: fsg, CanopyHeight: ch, UnderstoryHeight: uh}
+	return &Measurements{CanopyBaseHeight: cbh, FuelStrataGap: fsg, CanopyHeight: ch, UnderstoryHeight: uh,
+		XVoxels: voxelSet.XVoxels, YVoxels: voxelSet.YVoxels, XMin: voxelSet.XMin, YMin: voxelSet.YMin, Grid: voxelSet.Grid,
+		Normalized: voxelSet.Normalized}
 }
 
 // adds the specified column to some measurements for the specified coordinates
@@ -235,18 +318,16 @@ func(measurements *Measurements) addColumn(column *Column, coords XYPair) {
 	measurements.FuelStrataGap[coords] = fsg
 }
 
-// Converts a point to a voxel Coordinate
+// Converts a point to a voxel Coordinate by truncation, use a VoxelGrid for floor indexing
 func PointToCoordinate(x float64, minX float64, y float64, minY float64, z float64, minZ float64, voxelSize float64, zeroCoords bool) Coordinate {
 	
-	var deltaX, deltaY, deltaZ float64
+	grid := VoxelGrid{VoxelSize: voxelSize, Indexing: TruncatedIndexing}
 
 	if (zeroCoords) {
-		deltaX, deltaY, deltaZ = x - minX, y - minY, z - minZ // not used currently
-	} else {
-		deltaX, deltaY, deltaZ = x, y, z
+		grid.Anchor = Anchor{X: minX, Y: minY, Z: minZ} // not used currently
 	}
 	
-	return Coordinate{X: int(deltaX / voxelSize), Y: int(deltaY / voxelSize), Z: int(deltaZ / voxelSize)}
+	return grid.PointToCoordinate(x, y, z)
 }
 
 // The minimum heights at different coordinates
@@ -298,7 +379,7 @@ func hsvToRGB(h, s, v float64) (r, g, b uint8) {
 }
 
 // writes minimum heights to an image
-func writeMinimumHeights(filename string, heights *MinimumHeights, status *lasProcessing.PipelineStatus, voxels *VoxelSet) {
+func writeMinimumHeights(ctx context.Context, filename string, heights *MinimumHeights, status *lasProcessing.PipelineStatus, voxels *VoxelSet) error {
 	
 	image := image.NewRGBA(image.Rect(0, 0, voxels.XVoxels, voxels.YVoxels))
 
@@ -317,36 +398,61 @@ func writeMinimumHeights(filename string, heights *MinimumHeights, status *lasPr
 		// i := 255 - uint8(float64(min) / float64(voxels.ZVoxels) * 255)
 		// color := color.RGBA{R: i, G: i, B: i, A: 255}
 		
-		image.SetRGBA(point.X - voxels.XMin, voxels.YVoxels - point.Y + voxels.YMin, color)
+		image.SetRGBA(point.X - voxels.XMin, voxels.YVoxels - 1 - (point.Y - voxels.YMin), color)
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Write min", Progress: float64(current) / float64(total)}
+
+		if err := lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return err
+		}
+		status.Set("Write min", float64(current) / float64(total))
 	}
 
 	outputFile, err := os.Create(filename)
 
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	defer outputFile.Close()
 
-	png.Encode(outputFile, image)
+	return png.Encode(outputFile, image)
+}
+
+// Writes heights to a PNG image over the extent of their voxels
+type MinimumsImageWriter struct {
+
+	// filename to output to
+	FileName string
+
+}
+
+// writes heights to an image, outputting the file name
+func(writer *MinimumsImageWriter) Process(ctx context.Context, heights *MinimumHeights, status *lasProcessing.PipelineStatus) (string, error) {
+	status.Set("Write min", 0.0)
+
+	if err := writeMinimumHeights(ctx, writer.FileName, heights, status, heights.Voxels); err != nil {
+		return "", err
+	}
+
+	return writer.FileName, nil
 }
 
 // finds minimum heights
-func(heightFinder *MinimumHeightFinder) Process(voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) *MinimumHeights {
+func(heightFinder *MinimumHeightFinder) Process(ctx context.Context, voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) (*MinimumHeights, error) {
 
 	// map xy to minimum z value
 	minHeights := make(map[XYPair]int)
 
-	total := voxelSet.Voxels.Cardinality()
+	total := voxelSet.Voxels.Len()
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Minimums", Progress: 0.0}
+	status.Set("Minimums", 0.0)
+
+	var err error
 
-	for voxel := range voxelSet.Voxels.Iterator().C {
+	voxelSet.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
 		xy := XYPair{X: voxel.X, Y: voxel.Y}
 		min, contains := minHeights[xy]
 		if contains {
@@ -358,18 +464,31 @@ func(heightFinder *MinimumHeightFinder) Process(voxelSet *VoxelSet, status *lasP
 		}
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Minimums", Progress: float64(current) / float64(total)}
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Minimums", float64(current) / float64(total))
+		return true
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	heights := &MinimumHeights{Voxels: voxelSet, Heights: minHeights}
 
-	*status = lasProcessing.PipelineStatus{Step: "Write min", Progress: 0.0}
+	status.Set("Write min", 0.0)
 
 	if heightFinder.OuptutMinimums {
-		writeMinimumHeights(heightFinder.OutputFile, heights, status, voxelSet)
+		err := writeMinimumHeights(ctx, heightFinder.OutputFile, heights, status, voxelSet)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return heights
+	return heights, nil
 }
 
 // Turns minimum heights back into plain voxels
@@ -378,8 +497,8 @@ type MinimumDegrouper struct {
 }
 
 // de groups minimum heights and voxels
-func(degrouper *MinimumDegrouper) Process(heights *MinimumHeights, status *lasProcessing.PipelineStatus) *VoxelSet {
-	return heights.Voxels
+func(degrouper *MinimumDegrouper) Process(ctx context.Context, heights *MinimumHeights, status *lasProcessing.PipelineStatus) (*VoxelSet, error) {
+	return heights.Voxels, nil
 }
 
 // Normalizes heights lazily
@@ -398,30 +517,85 @@ type XYPair struct {
 }
 
 // normalizes heights after the fact (z is up)
-func(normalizer *LazyNormalizer) Process(voxelSet *MinimumHeights, status *lasProcessing.PipelineStatus) *VoxelSet {
+func(normalizer *LazyNormalizer) Process(ctx context.Context, voxelSet *MinimumHeights, status *lasProcessing.PipelineStatus) (*VoxelSet, error) {
 
-	newVoxelSet := mapset.NewThreadUnsafeSet[Coordinate]()
+	// heights above the ground are no further below it than the extent is high
+	origin := Coordinate{X: voxelSet.Voxels.XMin, Y: voxelSet.Voxels.YMin, Z: -voxelSet.Voxels.ZVoxels}
 
-	total := voxelSet.Voxels.Voxels.Cardinality()
+	newVoxelSet := NewVoxelStorage[Filled](origin)
+
+	var newAttributes VoxelStorage[*VoxelAttributes]
+
+	if voxelSet.Voxels.Attributes != nil {
+		newAttributes = NewVoxelStorage[*VoxelAttributes](origin)
+	}
+
+	total := voxelSet.Voxels.Voxels.Len()
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Normalizing", Progress: 0.0}
+	status.Set("Normalizing", 0.0)
+
+	zMin, zMax := math.MaxInt, math.MinInt
+
+	var err error
 
-	for voxel := range voxelSet.Voxels.Voxels.Iterator().C {
+	voxelSet.Voxels.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
 		xy := XYPair{X: voxel.X, Y: voxel.Y}
 		min := voxelSet.Heights[xy]
+
+		var attributes *VoxelAttributes
+
+		if newAttributes != nil {
+			attributes, _ = voxelSet.Voxels.Attributes.Get(voxel)
+		}
 		
 		voxel.Z -= min
 		newVoxelSet.Add(voxel)
 
+		zMin, zMax = minInt(zMin, voxel.Z), maxInt(zMax, voxel.Z)
+
+		if newAttributes != nil {
+			newAttributes.Set(voxel, attributes)
+		}
+
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Normalizing", Progress: float64(current) / float64(total)}
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Normalizing", float64(current) / float64(total))
+		return true
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	voxelSet.Voxels.Voxels = newVoxelSet
+	// the input is left as it is, as other pipelines may share it
+	normalized := *voxelSet.Voxels
+
+	normalized.Voxels = newVoxelSet
+
+	normalized.Attributes = newAttributes
+
+	normalized.Normalized = true
+
+	// the vertical extent is now of heights above the ground
+	if total > 0 {
+		normalized.ZMin, normalized.ZVoxels = zMin, zMax - zMin + 1
+	}
+
+	if voxelSet.Voxels.ClassifiedGround != nil {
+		normalized.ClassifiedGround = NewColumnStorage[int](XYPair{X: origin.X, Y: origin.Y})
+
+		voxelSet.Voxels.ClassifiedGround.Range(func(xy XYPair, height int) bool {
+			normalized.ClassifiedGround.Set(xy, height - voxelSet.Heights[xy])
+			return true
+		})
+	}
 	
-	return voxelSet.Voxels
+	return &normalized, nil
 }
 
 // Converts voxels to a height gradient
@@ -430,18 +604,20 @@ type GradientProcessor struct {
 }
 
 // finds minimum heights
-func(gradientProcessor *GradientProcessor) Process(voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) *HeightGradient {
+func(gradientProcessor *GradientProcessor) Process(ctx context.Context, voxelSet *VoxelSet, status *lasProcessing.PipelineStatus) (*HeightGradient, error) {
 
 	// map height to minimum coxel count
 	gradient := make(map[int]int)
 
-	total := voxelSet.Voxels.Cardinality()
+	total := voxelSet.Voxels.Len()
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Gradient", Progress: 0.0}
+	status.Set("Gradient", 0.0)
+
+	var err error
 
-	for voxel := range voxelSet.Voxels.Iterator().C {
+	voxelSet.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
 		count, contains := gradient[voxel.Z]
 		
 		if contains {
@@ -451,10 +627,19 @@ func(gradientProcessor *GradientProcessor) Process(voxelSet *VoxelSet, status *l
 		}
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Gradient", Progress: float64(current) / float64(total)}
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Gradient", float64(current) / float64(total))
+		return true
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return &HeightGradient{Gradient: gradient}
+	return &HeightGradient{Gradient: gradient, Grid: voxelSet.Grid, Normalized: voxelSet.Normalized}, nil
 }
 
 
@@ -464,101 +649,301 @@ type VoxelFileWriter struct {
 	// Filename to write to
 	FileName string
 
+	// Whether to write voxel attributes, if they are tracked
+	Attributes bool
+
+	// Names of the tracked extra bytes attributes, written as their mean with the other attributes
+	ExtraAttributes []string
+
+	// Names of every tracked extra bytes attribute in the order they are tracked, when more are tracked than are
+	// written, nil if exactly the written attributes are tracked
+	TrackedExtraAttributes []string
+
+	// How to relate voxels to the CRS of the source
+	Georeferencing
+
+	// Whether to add to the end of an existing file instead of replacing it, for stitching tiles together
+	Append bool
+
 }
 
-// Writes a set of voxels to a file
-func(writer *VoxelFileWriter) Process(voxels *VoxelSet, status *lasProcessing.PipelineStatus) error {
-	file, err := os.Create(writer.FileName)
+// Writes a set of voxels to a file, outputting the name of the file
+func(writer *VoxelFileWriter) Process(ctx context.Context, voxels *VoxelSet, status *lasProcessing.PipelineStatus) (string, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	// only a new file needs a header
+	_, statErr := os.Stat(writer.FileName)
+
+	writeHeader := !writer.Append || statErr != nil
+
+	if writer.Append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(writer.FileName, flags, 0666)
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer file.Close()
 
-	file.WriteString("x,y,z\n")
+	writeAttributes := writer.Attributes && voxels.Attributes != nil
 
-	total := voxels.Voxels.Cardinality()
+	extras, err := writer.extraIndices()
+
+	if err != nil {
+		return "", err
+	}
+
+	if writeHeader && writeAttributes {
+		header := "x,y,z,points,mean_intensity,max_intensity,mean_red,mean_green,mean_blue,first_returns,last_returns,classifications"
+
+		for _, name := range writer.ExtraAttributes {
+			header += "," + extraColumnName(name)
+		}
+
+		_, err = file.WriteString(header + "\n")
+	} else if writeHeader {
+		_, err = file.WriteString("x,y,z\n")
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	total := voxels.Voxels.Len()
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Writing", Progress: 0.0}
+	status.Set("Writing", 0.0)
+
+	positions := createPositionFormatter(voxels.Grid)
+
+	voxels.Voxels.Range(func(voxel Coordinate, _ Filled) bool {
+		line := fmt.Sprint(voxel.X) + "," +fmt.Sprint(voxel.Y) + "," + fmt.Sprint(voxel.Z)
+
+		if writer.WorldCoordinates {
+			x, y, z := voxels.Grid.VoxelCentre(voxel)
+
+			if voxels.Normalized {
+				z -= voxels.Grid.Anchor.Z
+			}
+
+			line = positions.format(x) + "," + positions.format(y) + "," + positions.format(z)
+		}
+
+		if writeAttributes {
+			attributes, _ := voxels.Attributes.Get(voxel)
+			line += "," + attributeColumns(attributes, extras)
+		}
 
-	for voxel := range voxels.Voxels.Iterator().C {
-		_, err = file.WriteString(fmt.Sprint(voxel.X) + "," +fmt.Sprint(voxel.Y) + "," + fmt.Sprint(voxel.Z) + "\n")
+		_, err = file.WriteString(line + "\n")
 
 		if err != nil {
-			return err
+			return false
 		}
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Writing", Progress: float64(current) / float64(total)}
+
+		if err = lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return false
+		}
+		status.Set("Writing", float64(current) / float64(total))
+		return true
+	})
+
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	err = writer.writeSidecar(writer.FileName, voxels.Grid, Coordinate{X: voxels.XMin, Y: voxels.YMin, Z: voxels.ZMin},
+		Coordinate{X: voxels.XVoxels, Y: voxels.YVoxels, Z: voxels.ZVoxels}, 3, voxels.Normalized, writer.Append)
+
+	if err != nil {
+		return "", err
+	}
+
+	return writer.FileName, nil
+}
+
+// Finds the index among the tracked extra bytes attributes of each attribute to write
+func(writer *VoxelFileWriter) extraIndices() ([]int, error) {
+	indices := make([]int, len(writer.ExtraAttributes))
+
+	for i, name := range writer.ExtraAttributes {
+		indices[i] = i
+
+		if writer.TrackedExtraAttributes == nil {
+			continue
+		}
+
+		indices[i] = -1
+
+		for j, tracked := range writer.TrackedExtraAttributes {
+			if tracked == name {
+				indices[i] = j
+				break
+			}
+		}
+
+		if indices[i] < 0 {
+			return nil, fmt.Errorf("extra bytes attribute %q is not tracked", name)
+		}
+	}
+
+	return indices, nil
+}
+
+// Names the column of the mean of an extra bytes attribute, which may contain any characters
+func extraColumnName(name string) string {
+	replacer := strings.NewReplacer(",", "_", " ", "_", "\"", "_", "\n", "_")
+
+	return "mean_" + replacer.Replace(strings.ToLower(name))
+}
+
+// Formats the attributes of a voxel as CSV columns, with the mean of each extra bytes attribute at the specified
+// indices, empty if no point has a value
+func attributeColumns(attributes *VoxelAttributes, indices []int) string {
+	r, g, b := attributes.MeanRGB()
+
+	extras := ""
+
+	for _, i := range indices {
+		extras += ","
+
+		if mean, ok := attributes.MeanExtra(i); ok {
+			extras += strconv.FormatFloat(mean, 'f', 3, 64)
+		}
+	}
+
+	return fmt.Sprint(attributes.Points) + "," +
+		strconv.FormatFloat(attributes.MeanIntensity(), 'f', 2, 64) + "," +
+		fmt.Sprint(attributes.IntensityMax) + "," +
+		strconv.FormatFloat(r, 'f', 2, 64) + "," +
+		strconv.FormatFloat(g, 'f', 2, 64) + "," +
+		strconv.FormatFloat(b, 'f', 2, 64) + "," +
+		fmt.Sprint(attributes.FirstReturns) + "," +
+		fmt.Sprint(attributes.LastReturns) + "," +
+		attributes.ClassificationString() + extras
 }
 
 // Writes a gradient to a file
 type GradientFileWriter struct {
 	// The file name to write to
 	FileName string
+
+	// How to relate heights to the CRS of the source
+	Georeferencing
 }
 
-// Writes a set of voxels to a file
-func(writer *GradientFileWriter) Process(gradient *HeightGradient, status *lasProcessing.PipelineStatus) error {
+// Writes a gradient to a file, outputting the name of the file
+func(writer *GradientFileWriter) Process(ctx context.Context, gradient *HeightGradient, status *lasProcessing.PipelineStatus) (string, error) {
 	file, err := os.Create(writer.FileName)
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer file.Close()
 
-	file.WriteString("height,count\n")
+	_, err = file.WriteString("height,count\n")
+
+	if err != nil {
+		return "", err
+	}
 
 	total := len(gradient.Gradient)
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Writing", Progress: 0.0}
+	status.Set("Writing", 0.0)
+
+	positions := createPositionFormatter(gradient.Grid)
+
+	zMin, zMax := math.MaxInt, math.MinInt
 
 	for height, count := range gradient.Gradient {
-		_, err = file.WriteString(fmt.Sprint(height) + "," + fmt.Sprint(count) + "\n")
+		zMin, zMax = minInt(zMin, height), maxInt(zMax, height)
+
+		line := fmt.Sprint(height)
+
+		if writer.WorldCoordinates {
+			_, _, z := gradient.Grid.VoxelCentre(Coordinate{Z: height})
+
+			if gradient.Normalized {
+				z -= gradient.Grid.Anchor.Z
+			}
+
+			line = positions.format(z)
+		}
+
+		_, err = file.WriteString(line + "," + fmt.Sprint(count) + "\n")
 
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Writing", Progress: float64(current) / float64(total)}
+
+		if err := lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return "", err
+		}
+		status.Set("Writing", float64(current) / float64(total))
 	}
 
-	return nil
+	if len(gradient.Gradient) == 0 {
+		zMin, zMax = 0, -1
+	}
+
+	err = writer.writeSidecar(writer.FileName, gradient.Grid, Coordinate{Z: zMin}, Coordinate{Z: zMax - zMin + 1}, 1, gradient.Normalized, false)
+
+	if err != nil {
+		return "", err
+	}
+
+	return writer.FileName, nil
 }
 
 // writes measurements to a file
 type MeasurementsFileWriter struct {
 	// the name of the file to write to
 	FileName string
+
+	// How to relate columns and heights to the CRS of the source
+	Georeferencing
 }
 
-// Writes a set of measurements to a file
-func(writer *MeasurementsFileWriter) Process(measurements *Measurements, status *lasProcessing.PipelineStatus) error {
+// Writes a set of measurements to a file, outputting the name of the file
+func(writer *MeasurementsFileWriter) Process(ctx context.Context, measurements *Measurements, status *lasProcessing.PipelineStatus) (string, error) {
 	file, err := os.Create(writer.FileName)
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer file.Close()
 
-	file.WriteString("x,y,understory_height,canopy_base_height,fuel_strata_gap,canopy_height\n")
+	header := "x,y,understory_height,canopy_base_height,fuel_strata_gap,canopy_height"
+
+	// heights in the units of the CRS are elevations unless they are normalized
+	if writer.WorldCoordinates {
+		header = "x,y," + strings.Join(measurements.names(), ",")
+	}
+
+	_, err = file.WriteString(header + "\n")
+
+	if err != nil {
+		return "", err
+	}
 
 	total := len(measurements.CanopyBaseHeight)
 
 	current := 0
 
-	*status = lasProcessing.PipelineStatus{Step: "Writing", Progress: 0.0}
+	status.Set("Writing", 0.0)
+
+	positions := createPositionFormatter(measurements.Grid)
 
 	for coords := range measurements.CanopyBaseHeight {
 
@@ -569,20 +954,45 @@ func(writer *MeasurementsFileWriter) Process(measurements *Measurements, status
 		ch := measurements.CanopyHeight[coords]
 		fsg := measurements.FuelStrataGap[coords]
 
-		_, err = file.WriteString(fmt.Sprint(x) + "," + 
+		line := fmt.Sprint(x) + "," + 
 			fmt.Sprint(y) + "," +
 			fmt.Sprint(uh) + "," +
 			fmt.Sprint(cbh) + "," +
 			fmt.Sprint(fsg) + "," +
-			fmt.Sprint(ch) + "\n")
+			fmt.Sprint(ch)
+
+		if writer.WorldCoordinates {
+			// heights are converted from voxels to the units of the CRS, the gap is a length
+			worldX, worldY, _ := measurements.Grid.VoxelCentre(Coordinate{X: x, Y: y})
+
+			line = positions.format(worldX) + "," + 
+				positions.format(worldY) + "," +
+				positions.format(measurements.worldHeight(uh)) + "," +
+				positions.format(measurements.worldHeight(cbh)) + "," +
+				positions.format(float64(fsg) * measurements.Grid.VoxelSize) + "," +
+				positions.format(measurements.worldHeight(ch))
+		}
+
+		_, err = file.WriteString(line + "\n")
 
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		current += 1
-		*status = lasProcessing.PipelineStatus{Step: "Writing", Progress: float64(current) / float64(total)}
+
+		if err := lasProcessing.CheckCancelled(ctx, current); err != nil {
+			return "", err
+		}
+		status.Set("Writing", float64(current) / float64(total))
+	}
+
+	err = writer.writeSidecar(writer.FileName, measurements.Grid, Coordinate{X: measurements.XMin, Y: measurements.YMin},
+		Coordinate{X: measurements.XVoxels, Y: measurements.YVoxels}, 2, measurements.Normalized, false)
+
+	if err != nil {
+		return "", err
 	}
 
-	return nil
+	return writer.FileName, nil
 }
\ No newline at end of file